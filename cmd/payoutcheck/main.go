@@ -0,0 +1,246 @@
+// Command payoutcheck runs a payout config against a local spreadsheet
+// without Paperless or accounting.  It imports the file into an in-memory
+// DuckDB through the same path as the server's payout pipeline (read_xlsx,
+// the LibreOffice parser, read_csv, or Tika or Document AI tables for PDFs),
+// evaluates the export configs and prints every
+// intermediate table followed by the resulting PayoutInput and the result of
+// the platform's checks.
+//
+// Usage:
+//
+//	payoutcheck -platform swiggy -config payout_configs.json -file payout.xlsx
+//
+// Platforms with pdf_method "docai" read the Document AI settings from the
+// same environment variables as the server (GOOGLE_CLOUD_PROJECT,
+// GOOGLE_CLOUD_LOCATION, PAYOUT_PROCESSOR_ID or DOCUMENT_AI_PROCESSOR_ID and
+// GOOGLE_APPLICATION_CREDENTIALS).
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"text/tabwriter"
+
+	"paperless-document-processor/config"
+	"paperless-document-processor/pkg/dates"
+	"paperless-document-processor/pkg/docai"
+	"paperless-document-processor/pkg/libreoffice"
+	"paperless-document-processor/pkg/payout"
+	"paperless-document-processor/pkg/storage"
+	"paperless-document-processor/pkg/tika"
+)
+
+// checkDocID is the document_id the spreadsheet's rows are stored under.
+const checkDocID = 1
+
+func main() {
+	platform := flag.String("platform", "", "platform name as used in the payout config (default: detected by fingerprint)")
+	configPath := flag.String("config", "payout_configs.json", "payout config file")
+	file := flag.String("file", "", "payout report (.xlsx, .xls, .csv or .pdf) to import (required)")
+	loURL := flag.String("libreoffice-url", os.Getenv("LIBREOFFICE_URL"), "LibreOffice parser URL, for platforms with method \"libreoffice\"")
+	loPath := flag.String("libreoffice-path", "", "spreadsheet path as seen by the LibreOffice parser (default: the absolute -file path)")
+	tikaURL := flag.String("tika-url", getEnv("TIKA_URL", "http://localhost:9998"), "Tika URL, for PDF reports")
+	dateLayouts := flag.String("date-layouts", os.Getenv("DATE_LAYOUTS"), "semicolon-separated time layouts tried first for payout dates")
+	dateOrder := flag.String("date-order", getEnv("DATE_ORDER", string(dates.DayFirst)), "preferred reading of numeric dates, DMY or MDY")
+	maxRows := flag.Int("rows", 20, "rows to print per table (0 prints all)")
+	verbose := flag.Bool("v", false, "log the SQL and parser calls")
+	flag.Parse()
+
+	if *file == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	lvl := slog.LevelWarn
+	if *verbose {
+		lvl = slog.LevelDebug
+	}
+	slog.SetDefault(slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: lvl})))
+
+	order, err := config.ParseDateOrder(*dateOrder)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "payoutcheck: -date-order %v\n", err)
+		os.Exit(2)
+	}
+	dp := dates.Parser{Layouts: config.ParseDateLayouts(*dateLayouts), Order: order}
+
+	if err := run(os.Stdout, *platform, *configPath, *file, *loURL, *loPath, *tikaURL, *maxRows, dp); err != nil {
+		fmt.Fprintf(os.Stderr, "payoutcheck: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func run(w io.Writer, platform, configPath, file, loURL, loPath, tikaURL string, maxRows int, dp dates.Parser) error {
+	pc, err := config.LoadPayoutConfigs(configPath)
+	if err != nil {
+		return err
+	}
+	abs, err := filepath.Abs(file)
+	if err != nil {
+		return fmt.Errorf("failed to resolve %s: %w", file, err)
+	}
+	if _, err := os.Stat(abs); err != nil {
+		return err
+	}
+
+	if platform == "" {
+		matched, err := payout.Detect(pc.Platforms, filepath.Base(abs), abs)
+		if err != nil {
+			return err
+		}
+		if len(matched) != 1 {
+			return fmt.Errorf("fingerprints matched %d platforms %v, pass -platform", len(matched), matched)
+		}
+		platform = matched[0]
+		fmt.Fprintf(w, "== Platform: %s (detected by fingerprint)\n\n", platform)
+	}
+	option, ok := pc.Platforms[platform]
+	if !ok {
+		return fmt.Errorf("platform %q is not in %s", platform, configPath)
+	}
+	src := payout.Source{Path: abs, MediaPath: abs}
+	if loPath != "" {
+		src.MediaPath = loPath
+	}
+
+	var backends payout.Backends
+	if loURL != "" {
+		// An empty data path passes MediaPath through unchanged.
+		backends.LibreOffice = libreoffice.NewClient(loURL, "")
+	}
+	if tikaURL != "" {
+		backends.Tika = tika.NewClient(tikaURL)
+	}
+	if format, _ := payout.FormatOf(abs); format == payout.FormatPDF && option.UseDocAITables() {
+		dClient, err := docai.NewClient(context.Background(), os.Getenv("GOOGLE_CLOUD_PROJECT"), os.Getenv("GOOGLE_CLOUD_LOCATION"), os.Getenv("DOCUMENT_AI_PROCESSOR_ID"), os.Getenv("GOOGLE_APPLICATION_CREDENTIALS"))
+		if err != nil {
+			return err
+		}
+		defer dClient.Close()
+		backends.DocAI = dClient
+		backends.DocAIProcessorID = os.Getenv("PAYOUT_PROCESSOR_ID")
+	}
+
+	db, err := storage.InitDB("")
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	importErr := payout.Import(context.Background(), db, backends, checkDocID, platform, option, src)
+	// Print whatever was imported even when a later import config failed.
+	for _, table := range payout.Tables(platform, option) {
+		if err := printTable(w, db.Conn, table, maxRows); err != nil {
+			fmt.Fprintf(w, "== %s: %v\n\n", table, err)
+		}
+	}
+	if importErr != nil {
+		return fmt.Errorf("import failed: %w", importErr)
+	}
+
+	payoutInput, err := payout.Export(db, checkDocID, platform, option, dp)
+	if err != nil {
+		return fmt.Errorf("export failed: %w", err)
+	}
+	// The server resolves the outlet from the document too; only the
+	// spreadsheet's restaurant ID can be checked here.
+	if outlet, ok, err := payout.Outlet(db, checkDocID, option); err != nil {
+		fmt.Fprintf(w, "== Outlet: %v\n\n", err)
+	} else if ok {
+		payoutInput.OutletName = outlet
+		fmt.Fprintf(w, "== Outlet: %s\n\n", outlet)
+	}
+
+	out, err := json.MarshalIndent(payoutInput, "", "  ")
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(w, "== PayoutInput\n%s\n\n", out)
+
+	err = payout.Check(db, checkDocID, option, payoutInput)
+	var checkErr *payout.CheckError
+	switch {
+	case errors.As(err, &checkErr):
+		fmt.Fprintln(w, "== Checks")
+		for _, f := range checkErr.Failures {
+			fmt.Fprintf(w, "FAIL %s: %s\n", f.Name, f.Detail)
+		}
+		return fmt.Errorf("%d of %d checks failed", len(checkErr.Failures), len(option.Checks))
+	case err != nil:
+		return err
+	}
+	fmt.Fprintf(w, "== Checks: %d passed\n", len(option.Checks))
+	return nil
+}
+
+// printTable writes up to maxRows rows of an import table, with DuckDB's
+// positional column numbers (#1, #2, ...) in the header for use in export
+// expressions.
+func printTable(w io.Writer, conn *sql.DB, table string, maxRows int) error {
+	var count int
+	if err := conn.QueryRow(fmt.Sprintf("SELECT COUNT(1) FROM %s WHERE document_id = ?", table), checkDocID).Scan(&count); err != nil {
+		return err
+	}
+
+	query := fmt.Sprintf("SELECT * FROM %s WHERE document_id = ?", table)
+	if maxRows > 0 {
+		query += fmt.Sprintf(" LIMIT %d", maxRows)
+	}
+	rows, err := conn.Query(query, checkDocID)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	cols, err := rows.Columns()
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(w, "== %s (%d rows)\n", table, count)
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	for i, c := range cols {
+		fmt.Fprintf(tw, "#%d %s\t", i+1, c)
+	}
+	fmt.Fprintln(tw)
+
+	values := make([]interface{}, len(cols))
+	ptrs := make([]interface{}, len(cols))
+	for i := range values {
+		ptrs[i] = &values[i]
+	}
+	for rows.Next() {
+		if err := rows.Scan(ptrs...); err != nil {
+			return err
+		}
+		for _, v := range values {
+			if v == nil {
+				v = "NULL"
+			}
+			fmt.Fprintf(tw, "%v\t", v)
+		}
+		fmt.Fprintln(tw)
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+	if maxRows > 0 && count > maxRows {
+		fmt.Fprintf(w, "... %d more rows\n", count-maxRows)
+	}
+	fmt.Fprintln(w)
+	return rows.Err()
+}
+
+func getEnv(key, fallback string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return fallback
+}
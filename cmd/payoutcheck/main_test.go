@@ -0,0 +1,97 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"paperless-document-processor/pkg/dates"
+	"paperless-document-processor/pkg/storage"
+)
+
+const testOrdersCSV = `order_id,order_date,amount
+A1,2025-03-01,100.50
+A2,2025-03-02,200.00
+A3,2025-03-03,49.50
+`
+
+// writeFixture writes the orders report and a payout config whose single
+// check is check, and returns their paths.
+func writeFixture(t *testing.T, check string) (configPath, file string) {
+	t.Helper()
+	if db, err := storage.InitDB(""); err != nil {
+		t.Skipf("DuckDB unavailable: %v", err)
+	} else {
+		db.Close()
+	}
+
+	dir := t.TempDir()
+	file = filepath.Join(dir, "orders.csv")
+	if err := os.WriteFile(file, []byte(testOrdersCSV), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	configPath = filepath.Join(dir, "payout_configs.json")
+	cfg := fmt.Sprintf(`{
+		"outlets": {"default": "Test Kitchen"},
+		"platforms": {
+			"demo": {
+				"import_configs": [{"table_name": "demo_orders", "header": true}],
+				"export_configs": [{
+					"table_name": "demo_orders",
+					"reader_configs": [
+						{"column_name": "PeriodStart", "expression": "MIN(order_date)"},
+						{"column_name": "PeriodEnd", "expression": "MAX(order_date)"},
+						{"column_name": "TotalOrders", "expression": "COUNT(1)"},
+						{"column_name": "GrossSalesAmt", "expression": "SUM(amount)"}
+					]
+				}],
+				"checks": [{"name": "orders", "expression": %q}]
+			}
+		}
+	}`, check)
+	if err := os.WriteFile(configPath, []byte(cfg), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return configPath, file
+}
+
+func TestRun(t *testing.T) {
+	configPath, file := writeFixture(t, "TotalOrders = 3")
+
+	var out bytes.Buffer
+	if err := run(&out, "demo", configPath, file, "", "", "", 2, dates.Parser{Order: dates.DayFirst}); err != nil {
+		t.Fatalf("run: %v\n%s", err, out.String())
+	}
+	for _, want := range []string{
+		"== demo_orders (3 rows)",
+		"#2 order_id",
+		"A2",
+		"... 1 more rows",
+		`"period_start": "2025-03-01"`,
+		`"total_orders": 3`,
+		"== Checks: 1 passed",
+	} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("output lacks %q:\n%s", want, out.String())
+		}
+	}
+	if strings.Contains(out.String(), "A3") {
+		t.Errorf("output prints more rows than -rows allows:\n%s", out.String())
+	}
+}
+
+func TestRunFailedCheck(t *testing.T) {
+	configPath, file := writeFixture(t, "TotalOrders > 5")
+
+	var out bytes.Buffer
+	err := run(&out, "demo", configPath, file, "", "", "", 0, dates.Parser{Order: dates.DayFirst})
+	if err == nil || err.Error() != "1 of 1 checks failed" {
+		t.Errorf("run error = %v, want 1 of 1 checks failed", err)
+	}
+	if want := "FAIL orders: TotalOrders > 5 is not true (TotalOrders=3)"; !strings.Contains(out.String(), want) {
+		t.Errorf("output lacks %q:\n%s", want, out.String())
+	}
+}
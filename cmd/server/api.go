@@ -0,0 +1,84 @@
+package main
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"paperless-document-processor/pkg/storage"
+)
+
+// writeJSON writes v as a JSON response with the given status code.
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		slog.Warn("Failed to write JSON response", "error", err)
+	}
+}
+
+// handleListJobs serves GET /jobs with optional kind, state and limit query
+// parameters.
+func (s *Server) handleListJobs(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	filter := storage.JobFilter{
+		Kind:  storage.JobKind(q.Get("kind")),
+		State: storage.JobState(q.Get("state")),
+	}
+	if v := q.Get("limit"); v != "" {
+		limit, err := strconv.Atoi(v)
+		if err != nil || limit < 1 {
+			http.Error(w, "limit must be a positive integer", http.StatusBadRequest)
+			return
+		}
+		filter.Limit = limit
+	}
+
+	jobs, err := s.db.ListJobs(filter)
+	if err != nil {
+		slog.Error("Failed to list jobs", "error", err)
+		http.Error(w, "Failed to list jobs", http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusOK, jobs)
+}
+
+// handleGetJob serves GET /jobs/{id}.
+func (s *Server) handleGetJob(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid job ID", http.StatusBadRequest)
+		return
+	}
+
+	job, err := s.db.GetJob(id)
+	if err != nil {
+		slog.Error("Failed to get job", "job_id", id, "error", err)
+		http.Error(w, "Failed to get job", http.StatusInternalServerError)
+		return
+	}
+	if job == nil {
+		http.Error(w, "Job not found", http.StatusNotFound)
+		return
+	}
+	writeJSON(w, http.StatusOK, job)
+}
+
+// handleDocumentJobs serves GET /documents/{paperless_id}/jobs, the processing
+// history of a single Paperless document.
+func (s *Server) handleDocumentJobs(w http.ResponseWriter, r *http.Request) {
+	docID, err := strconv.Atoi(r.PathValue("paperless_id"))
+	if err != nil {
+		http.Error(w, "Invalid document ID", http.StatusBadRequest)
+		return
+	}
+
+	jobs, err := s.db.ListJobs(storage.JobFilter{PaperlessID: docID})
+	if err != nil {
+		slog.Error("Failed to list document jobs", "document_id", docID, "error", err)
+		http.Error(w, "Failed to list jobs", http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusOK, jobs)
+}
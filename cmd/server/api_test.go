@@ -0,0 +1,86 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"paperless-document-processor/pkg/storage"
+)
+
+// jobsMux routes the job status API like main does.
+func jobsMux(s *Server) *http.ServeMux {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /jobs", s.handleListJobs)
+	mux.HandleFunc("GET /jobs/{id}", s.handleGetJob)
+	mux.HandleFunc("GET /documents/{paperless_id}/jobs", s.handleDocumentJobs)
+	return mux
+}
+
+func TestJobsAPIRejectsBadParameters(t *testing.T) {
+	mux := jobsMux(&Server{})
+	for _, path := range []string{"/jobs?limit=0", "/jobs?limit=ten", "/jobs/abc", "/documents/abc/jobs"} {
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest("GET", path, nil))
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("GET %s: status %d, want 400", path, rec.Code)
+		}
+	}
+}
+
+func TestJobsAPI(t *testing.T) {
+	db, err := storage.InitDB("")
+	if err != nil {
+		t.Skipf("DuckDB unavailable: %v", err)
+	}
+	defer db.Close()
+
+	billID, _ := db.EnqueueJob(storage.JobKindBill, 7, "{}")
+	payoutID, _ := db.EnqueueJob(storage.JobKindPayout, 8, "{}")
+	if _, err := db.ClaimNextJob(); err != nil {
+		t.Fatal(err)
+	}
+	db.SetJobStep(billID, storage.JobStepDocAI)
+	db.FinishJob(billID, storage.JobStateFailed, "docai processing failed")
+
+	mux := jobsMux(&Server{db: db})
+	get := func(path string, v interface{}) int {
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest("GET", path, nil))
+		if rec.Code == http.StatusOK {
+			if err := json.Unmarshal(rec.Body.Bytes(), v); err != nil {
+				t.Errorf("GET %s: %v", path, err)
+			}
+		}
+		return rec.Code
+	}
+
+	var jobs []storage.Job
+	if code := get("/jobs", &jobs); code != http.StatusOK || len(jobs) != 2 || jobs[0].ID != payoutID {
+		t.Errorf("GET /jobs = %d %+v, want both jobs newest first", code, jobs)
+	}
+	if code := get("/jobs?kind=bill&state=failed", &jobs); code != http.StatusOK || len(jobs) != 1 || jobs[0].ID != billID {
+		t.Errorf("GET /jobs filtered = %d %+v, want the failed bill", code, jobs)
+	}
+	if code := get("/jobs?limit=1", &jobs); code != http.StatusOK || len(jobs) != 1 {
+		t.Errorf("GET /jobs?limit=1 = %d %+v", code, jobs)
+	}
+
+	var job storage.Job
+	code := get("/jobs/"+strconv.FormatInt(billID, 10), &job)
+	if code != http.StatusOK || job.State != storage.JobStateFailed || job.Step != storage.JobStepDocAI || job.Error != "docai processing failed" {
+		t.Errorf("GET /jobs/%d = %d %+v", billID, code, job)
+	}
+	if code := get("/jobs/999", &job); code != http.StatusNotFound {
+		t.Errorf("GET /jobs/999: status %d, want 404", code)
+	}
+
+	if code := get("/documents/8/jobs", &jobs); code != http.StatusOK || len(jobs) != 1 || jobs[0].ID != payoutID {
+		t.Errorf("GET /documents/8/jobs = %d %+v", code, jobs)
+	}
+	if code := get("/documents/9/jobs", &jobs); code != http.StatusOK || len(jobs) != 0 {
+		t.Errorf("GET /documents/9/jobs = %d %+v, want an empty list", code, jobs)
+	}
+}
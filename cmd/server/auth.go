@@ -0,0 +1,187 @@
+package main
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"paperless-document-processor/config"
+)
+
+// webhookAuth guards the webhook routes.  Each configured mode (IP
+// allowlist, bearer token, HMAC signature) is checked in that order and all
+// of them must pass.
+type webhookAuth struct {
+	token           string
+	hmacSecret      []byte
+	signatureHeader string
+	timestampHeader string
+	replayWindow    time.Duration
+	allowed         []*net.IPNet
+	now             func() time.Time
+}
+
+func newWebhookAuth(cfg *config.Config) (*webhookAuth, error) {
+	a := &webhookAuth{
+		token:           cfg.WebhookToken,
+		signatureHeader: cfg.WebhookSignatureHeader,
+		timestampHeader: cfg.WebhookTimestampHeader,
+		replayWindow:    cfg.WebhookReplayWindow,
+		now:             time.Now,
+	}
+	if cfg.WebhookHMACSecret != "" {
+		a.hmacSecret = []byte(cfg.WebhookHMACSecret)
+	}
+	for _, entry := range cfg.WebhookAllowedIPs {
+		network, err := parseIPOrCIDR(entry)
+		if err != nil {
+			return nil, fmt.Errorf("invalid WEBHOOK_ALLOWED_IPS entry %q: %w", entry, err)
+		}
+		a.allowed = append(a.allowed, network)
+	}
+	return a, nil
+}
+
+func parseIPOrCIDR(entry string) (*net.IPNet, error) {
+	if strings.Contains(entry, "/") {
+		_, network, err := net.ParseCIDR(entry)
+		return network, err
+	}
+	ip := net.ParseIP(entry)
+	if ip == nil {
+		return nil, fmt.Errorf("not an IP address or CIDR")
+	}
+	bits := 8 * net.IPv6len
+	if ip4 := ip.To4(); ip4 != nil {
+		ip, bits = ip4, 8*net.IPv4len
+	}
+	return &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)}, nil
+}
+
+// enabled reports whether any authentication mode is configured.
+func (a *webhookAuth) enabled() bool {
+	return a.token != "" || a.hmacSecret != nil || len(a.allowed) > 0
+}
+
+// modes lists the configured modes for the startup log.
+func (a *webhookAuth) modes() []string {
+	var modes []string
+	if len(a.allowed) > 0 {
+		modes = append(modes, "ip_allowlist")
+	}
+	if a.token != "" {
+		modes = append(modes, "bearer_token")
+	}
+	if a.hmacSecret != nil {
+		modes = append(modes, "hmac_signature")
+	}
+	return modes
+}
+
+// wrap returns next guarded by every configured mode.  Rejections are
+// answered with 403 for a disallowed address and 401 otherwise, without
+// revealing which check failed.
+func (a *webhookAuth) wrap(next http.HandlerFunc) http.HandlerFunc {
+	if !a.enabled() {
+		return next
+	}
+	return func(w http.ResponseWriter, r *http.Request) {
+		if len(a.allowed) > 0 && !a.ipAllowed(r.RemoteAddr) {
+			slog.Warn("Rejected webhook from disallowed address", "remote_addr", r.RemoteAddr, "path", r.URL.Path)
+			http.Error(w, "Forbidden", http.StatusForbidden)
+			return
+		}
+		if a.token != "" && !a.tokenValid(r.Header.Get("Authorization")) {
+			slog.Warn("Rejected webhook with missing or invalid bearer token", "remote_addr", r.RemoteAddr, "path", r.URL.Path)
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+		if a.hmacSecret != nil {
+			body, err := io.ReadAll(io.LimitReader(r.Body, maxWebhookBody))
+			if err != nil {
+				http.Error(w, "Failed to read request body", http.StatusBadRequest)
+				return
+			}
+			r.Body = io.NopCloser(bytes.NewReader(body))
+			if err := a.verifySignature(r.Method, r.RequestURI, r.Header.Get(a.timestampHeader), r.Header.Get(a.signatureHeader), body); err != nil {
+				slog.Warn("Rejected webhook with invalid signature", "remote_addr", r.RemoteAddr, "path", r.URL.Path, "error", err)
+				http.Error(w, "Unauthorized", http.StatusUnauthorized)
+				return
+			}
+		}
+		next(w, r)
+	}
+}
+
+// ipAllowed checks the connection's peer address.  X-Forwarded-For is
+// deliberately ignored as it is client-controlled.
+func (a *webhookAuth) ipAllowed(remoteAddr string) bool {
+	host, _, err := net.SplitHostPort(remoteAddr)
+	if err != nil {
+		host = remoteAddr
+	}
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return false
+	}
+	for _, network := range a.allowed {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+func (a *webhookAuth) tokenValid(header string) bool {
+	token, ok := strings.CutPrefix(header, "Bearer ")
+	if !ok {
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(strings.TrimSpace(token)), []byte(a.token)) == 1
+}
+
+// verifySignature checks sig, the hex HMAC-SHA256 (optionally prefixed with
+// "sha256=") of the request computed by signWebhook, and that timestamp
+// (Unix seconds) lies within the replay window of now.
+func (a *webhookAuth) verifySignature(method, requestURI, timestamp, sig string, body []byte) error {
+	if timestamp == "" || sig == "" {
+		return fmt.Errorf("missing %s or %s header", a.timestampHeader, a.signatureHeader)
+	}
+	ts, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid timestamp %q", timestamp)
+	}
+	if skew := a.now().Sub(time.Unix(ts, 0)).Abs(); skew > a.replayWindow {
+		return fmt.Errorf("timestamp outside replay window (skew %s)", skew.Round(time.Second))
+	}
+	got, err := hex.DecodeString(strings.TrimPrefix(sig, "sha256="))
+	if err != nil {
+		return fmt.Errorf("signature is not hex")
+	}
+	if !hmac.Equal(got, signWebhook(a.hmacSecret, method, requestURI, timestamp, body)) {
+		return fmt.Errorf("signature mismatch")
+	}
+	return nil
+}
+
+// signWebhook computes the signature senders must put in the signature
+// header (hex-encoded): the HMAC of the method, the request URI (path and
+// query), the timestamp and the body, joined by newlines.  Signing the method and the path with its query string stops a
+// captured request from being replayed against another route or with other
+// parameters.
+func signWebhook(secret []byte, method, requestURI, timestamp string, body []byte) []byte {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(method + "\n" + requestURI + "\n" + timestamp + "\n"))
+	mac.Write(body)
+	return mac.Sum(nil)
+}
@@ -0,0 +1,187 @@
+package main
+
+import (
+	"encoding/hex"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"paperless-document-processor/config"
+)
+
+const testBody = `{"doc_url": "http://paperless:8000/documents/73/details"}`
+
+// newTestAuth builds a webhookAuth from cfg with a fixed clock.
+func newTestAuth(t *testing.T, cfg config.Config, now time.Time) *webhookAuth {
+	t.Helper()
+	if cfg.WebhookSignatureHeader == "" {
+		cfg.WebhookSignatureHeader = "X-Webhook-Signature"
+	}
+	if cfg.WebhookTimestampHeader == "" {
+		cfg.WebhookTimestampHeader = "X-Webhook-Timestamp"
+	}
+	if cfg.WebhookReplayWindow == 0 {
+		cfg.WebhookReplayWindow = 5 * time.Minute
+	}
+	a, err := newWebhookAuth(&cfg)
+	if err != nil {
+		t.Fatalf("newWebhookAuth: %v", err)
+	}
+	a.now = func() time.Time { return now }
+	return a
+}
+
+// serve runs req through a.wrap and returns the status code and the body
+// the wrapped handler saw.
+func serve(a *webhookAuth, req *http.Request) (int, string) {
+	var seen string
+	h := a.wrap(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		seen = string(b)
+		w.WriteHeader(http.StatusAccepted)
+	})
+	rec := httptest.NewRecorder()
+	h(rec, req)
+	return rec.Code, seen
+}
+
+func TestWebhookAuth_DisabledPassesThrough(t *testing.T) {
+	a := newTestAuth(t, config.Config{}, time.Now())
+	if a.enabled() {
+		t.Fatal("expected auth to be disabled with empty config")
+	}
+	code, _ := serve(a, httptest.NewRequest(http.MethodPost, "/bills", strings.NewReader(testBody)))
+	if code != http.StatusAccepted {
+		t.Errorf("expected 202, got %d", code)
+	}
+}
+
+func TestWebhookAuth_BearerToken(t *testing.T) {
+	a := newTestAuth(t, config.Config{WebhookToken: "s3cret"}, time.Now())
+	cases := []struct {
+		header string
+		want   int
+	}{
+		{"Bearer s3cret", http.StatusAccepted},
+		{"", http.StatusUnauthorized},
+		{"Bearer wrong", http.StatusUnauthorized},
+		{"s3cret", http.StatusUnauthorized},
+		{"Basic s3cret", http.StatusUnauthorized},
+	}
+	for _, tc := range cases {
+		req := httptest.NewRequest(http.MethodPost, "/bills", strings.NewReader(testBody))
+		if tc.header != "" {
+			req.Header.Set("Authorization", tc.header)
+		}
+		if code, _ := serve(a, req); code != tc.want {
+			t.Errorf("Authorization %q: got %d, want %d", tc.header, code, tc.want)
+		}
+	}
+}
+
+func TestWebhookAuth_HMACSignature(t *testing.T) {
+	now := time.Unix(1_700_000_000, 0)
+	secret := "hmac-key"
+	a := newTestAuth(t, config.Config{WebhookHMACSecret: secret}, now)
+
+	signRequest := func(method, uri string, ts int64, body string) string {
+		return hex.EncodeToString(signWebhook([]byte(secret), method, uri, strconv.FormatInt(ts, 10), []byte(body)))
+	}
+	sign := func(ts int64, body string) string {
+		return signRequest(http.MethodPost, "/bills", ts, body)
+	}
+
+	cases := []struct {
+		name string
+		ts   string
+		sig  string
+		want int
+	}{
+		{"valid", strconv.FormatInt(now.Unix(), 10), sign(now.Unix(), testBody), http.StatusAccepted},
+		{"valid with prefix", strconv.FormatInt(now.Unix(), 10), "sha256=" + sign(now.Unix(), testBody), http.StatusAccepted},
+		{"small skew", strconv.FormatInt(now.Unix()-60, 10), sign(now.Unix()-60, testBody), http.StatusAccepted},
+		{"replayed", strconv.FormatInt(now.Add(-10*time.Minute).Unix(), 10), sign(now.Add(-10*time.Minute).Unix(), testBody), http.StatusUnauthorized},
+		{"future", strconv.FormatInt(now.Add(10*time.Minute).Unix(), 10), sign(now.Add(10*time.Minute).Unix(), testBody), http.StatusUnauthorized},
+		{"tampered body", strconv.FormatInt(now.Unix(), 10), sign(now.Unix(), `{"doc_url": "x/documents/1/"}`), http.StatusUnauthorized},
+		{"timestamp not signed", strconv.FormatInt(now.Unix()-1, 10), sign(now.Unix(), testBody), http.StatusUnauthorized},
+		{"other route", strconv.FormatInt(now.Unix(), 10), signRequest(http.MethodPost, "/payouts", now.Unix(), testBody), http.StatusUnauthorized},
+		{"other query", strconv.FormatInt(now.Unix(), 10), signRequest(http.MethodPost, "/bills?dry_run=true", now.Unix(), testBody), http.StatusUnauthorized},
+		{"other method", strconv.FormatInt(now.Unix(), 10), signRequest(http.MethodPut, "/bills", now.Unix(), testBody), http.StatusUnauthorized},
+		{"missing signature", strconv.FormatInt(now.Unix(), 10), "", http.StatusUnauthorized},
+		{"missing timestamp", "", sign(now.Unix(), testBody), http.StatusUnauthorized},
+		{"not hex", strconv.FormatInt(now.Unix(), 10), "zz", http.StatusUnauthorized},
+	}
+	for _, tc := range cases {
+		req := httptest.NewRequest(http.MethodPost, "/bills", strings.NewReader(testBody))
+		if tc.ts != "" {
+			req.Header.Set("X-Webhook-Timestamp", tc.ts)
+		}
+		if tc.sig != "" {
+			req.Header.Set("X-Webhook-Signature", tc.sig)
+		}
+		code, seen := serve(a, req)
+		if code != tc.want {
+			t.Errorf("%s: got %d, want %d", tc.name, code, tc.want)
+		}
+		if code == http.StatusAccepted && seen != testBody {
+			t.Errorf("%s: handler saw body %q, want original body", tc.name, seen)
+		}
+	}
+}
+
+func TestWebhookAuth_IPAllowlist(t *testing.T) {
+	a := newTestAuth(t, config.Config{WebhookAllowedIPs: []string{"10.0.0.0/24", "192.168.1.5", "::1"}}, time.Now())
+	cases := []struct {
+		remote string
+		want   int
+	}{
+		{"10.0.0.17:5123", http.StatusAccepted},
+		{"192.168.1.5:80", http.StatusAccepted},
+		{"[::1]:4000", http.StatusAccepted},
+		{"10.0.1.1:5123", http.StatusForbidden},
+		{"192.168.1.6:80", http.StatusForbidden},
+		{"garbage", http.StatusForbidden},
+	}
+	for _, tc := range cases {
+		req := httptest.NewRequest(http.MethodPost, "/bills", strings.NewReader(testBody))
+		req.RemoteAddr = tc.remote
+		req.Header.Set("X-Forwarded-For", "10.0.0.1")
+		if code, _ := serve(a, req); code != tc.want {
+			t.Errorf("remote %s: got %d, want %d", tc.remote, code, tc.want)
+		}
+	}
+}
+
+func TestWebhookAuth_AllModesRequired(t *testing.T) {
+	a := newTestAuth(t, config.Config{WebhookToken: "tok", WebhookAllowedIPs: []string{"10.0.0.1"}}, time.Now())
+
+	req := httptest.NewRequest(http.MethodPost, "/bills", strings.NewReader(testBody))
+	req.RemoteAddr = "10.0.0.1:1234"
+	if code, _ := serve(a, req); code != http.StatusUnauthorized {
+		t.Errorf("allowed IP without token: got %d, want 401", code)
+	}
+
+	req = httptest.NewRequest(http.MethodPost, "/bills", strings.NewReader(testBody))
+	req.RemoteAddr = "10.0.0.2:1234"
+	req.Header.Set("Authorization", "Bearer tok")
+	if code, _ := serve(a, req); code != http.StatusForbidden {
+		t.Errorf("token from disallowed IP: got %d, want 403", code)
+	}
+
+	req = httptest.NewRequest(http.MethodPost, "/bills", strings.NewReader(testBody))
+	req.RemoteAddr = "10.0.0.1:1234"
+	req.Header.Set("Authorization", "Bearer tok")
+	if code, _ := serve(a, req); code != http.StatusAccepted {
+		t.Errorf("token from allowed IP: got %d, want 202", code)
+	}
+}
+
+func TestNewWebhookAuth_InvalidAllowlist(t *testing.T) {
+	if _, err := newWebhookAuth(&config.Config{WebhookAllowedIPs: []string{"not-an-ip"}}); err == nil {
+		t.Error("expected error for invalid allowlist entry")
+	}
+}
@@ -0,0 +1,273 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"paperless-document-processor/config"
+	"paperless-document-processor/pkg/paperless"
+)
+
+// The Paperless tags, custom fields and correspondents are cached in memory
+// and reloaded periodically and on POST /admin/refresh, so objects created in
+// Paperless after startup are picked up without a restart.  Payout platform
+// configs are keyed by tag ID and are re-resolved whenever the tags reload.
+// All cache reads and writes go through s.cacheMu.
+
+// refreshResult reports the cache sizes after a refresh.
+type refreshResult struct {
+	Tags           int      `json:"tags"`
+	CustomFields   int      `json:"custom_fields"`
+	Correspondents int      `json:"correspondents"`
+	Platforms      []string `json:"platforms"`
+	Unresolved     []string `json:"unresolved_platforms,omitempty"`
+	Errors         []string `json:"errors,omitempty"`
+}
+
+// loadCustomFields replaces the custom field cache with the fields currently
+// defined in Paperless.
+func (s *Server) loadCustomFields() error {
+	fields, err := s.paperlessClient.GetCustomFields(s.ctx)
+	if err != nil {
+		return err
+	}
+	byName := make(map[string]paperless.CustomField, len(fields))
+	for _, f := range fields {
+		byName[f.Name] = f
+	}
+	s.cacheMu.Lock()
+	s.customFields = byName
+	s.cacheMu.Unlock()
+	return nil
+}
+
+// loadTags replaces the tag cache and re-resolves the payout platforms
+// against the new tag IDs.
+func (s *Server) loadTags() error {
+	tags, err := s.paperlessClient.GetTags(s.ctx)
+	if err != nil {
+		return err
+	}
+	byName := make(map[string]int, len(tags))
+	for _, t := range tags {
+		byName[t.Name] = t.ID
+	}
+	s.cacheMu.Lock()
+	s.tagIDs = byName
+	s.resolvePlatformsLocked()
+	s.cacheMu.Unlock()
+	return nil
+}
+
+// loadCorrespondents replaces the correspondent cache.
+func (s *Server) loadCorrespondents() error {
+	corrs, err := s.paperlessClient.GetCorrespondents(s.ctx)
+	if err != nil {
+		return err
+	}
+	byName := make(map[string]paperless.Correspondent, len(corrs))
+	for _, c := range corrs {
+		byName[strings.ToLower(c.Name)] = c
+	}
+	s.cacheMu.Lock()
+	s.correspondents = byName
+	s.cacheMu.Unlock()
+	return nil
+}
+
+// setPayoutConfigs replaces the payout platform configs (keyed by tag name)
+// and outlet rules, and resolves the platforms against the cached tags,
+// logging every platform.
+func (s *Server) setPayoutConfigs(pc config.PayoutConfigs) {
+	s.cacheMu.Lock()
+	defer s.cacheMu.Unlock()
+	s.payoutPlatforms = pc.Platforms
+	s.outlets = pc.Outlets
+	s.platformIDs = nil
+	s.resolvePlatformsLocked()
+}
+
+// resolvePlatformsLocked rebuilds duckDBConfigs from payoutPlatforms and the
+// current tag IDs.  Only changes are logged, so periodic refreshes stay
+// quiet.  Nothing is resolved until the tags have loaded.  The caller must
+// hold s.cacheMu for writing.
+func (s *Server) resolvePlatformsLocked() {
+	if s.tagIDs == nil {
+		return
+	}
+	previous := s.platformIDs
+	resolved := make(map[string]int, len(s.payoutPlatforms))
+	configs := make(map[int]config.PlatformConfig, len(s.payoutPlatforms))
+	for platform, options := range s.payoutPlatforms {
+		id, ok := s.tagIDs[platform]
+		if !ok {
+			if _, was := previous[platform]; was || previous == nil {
+				slog.Warn("Platform in config not found in Paperless tags", "platform", platform)
+			}
+			continue
+		}
+		resolved[platform] = id
+		configs[id] = options
+		if old, was := previous[platform]; !was || old != id {
+			slog.Info("Configured platform via JSON", "platform", platform, "tag_id", id)
+		}
+	}
+	s.platformIDs = resolved
+	s.duckDBConfigs = configs
+}
+
+// refreshCaches reloads every Paperless cache.  A failed load keeps the
+// previous contents of that cache and is reported in the result.
+func (s *Server) refreshCaches() refreshResult {
+	var res refreshResult
+	for _, load := range []struct {
+		name string
+		fn   func() error
+	}{
+		{"tags", s.loadTags},
+		{"custom fields", s.loadCustomFields},
+		{"correspondents", s.loadCorrespondents},
+	} {
+		if err := load.fn(); err != nil {
+			slog.Warn("Failed to refresh Paperless cache", "cache", load.name, "error", err)
+			res.Errors = append(res.Errors, fmt.Sprintf("%s: %v", load.name, err))
+		}
+	}
+
+	s.cacheMu.RLock()
+	res.Tags = len(s.tagIDs)
+	res.CustomFields = len(s.customFields)
+	res.Correspondents = len(s.correspondents)
+	for platform := range s.payoutPlatforms {
+		if _, ok := s.platformIDs[platform]; ok {
+			res.Platforms = append(res.Platforms, platform)
+		} else {
+			res.Unresolved = append(res.Unresolved, platform)
+		}
+	}
+	s.cacheMu.RUnlock()
+	sort.Strings(res.Platforms)
+	sort.Strings(res.Unresolved)
+
+	slog.Debug("Refreshed Paperless caches", "tags", res.Tags, "custom_fields", res.CustomFields, "correspondents", res.Correspondents, "platforms", res.Platforms)
+	return res
+}
+
+// startCacheRefresher reloads the caches every interval.
+func (s *Server) startCacheRefresher(interval time.Duration) {
+	slog.Info("Starting Paperless cache refresh", "interval", interval)
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for range ticker.C {
+			s.refreshCaches()
+		}
+	}()
+}
+
+// handleRefresh reloads the caches on demand.
+func (s *Server) handleRefresh(w http.ResponseWriter, r *http.Request) {
+	res := s.refreshCaches()
+	status := http.StatusOK
+	if len(res.Errors) > 0 {
+		status = http.StatusBadGateway
+	}
+	writeJSON(w, status, res)
+}
+
+// customField returns the cached custom field with the given name.
+func (s *Server) customField(name string) (paperless.CustomField, bool) {
+	s.cacheMu.RLock()
+	defer s.cacheMu.RUnlock()
+	f, ok := s.customFields[name]
+	return f, ok
+}
+
+// tagID looks up a tag by name, falling back to a case-insensitive match.
+func (s *Server) tagID(name string) (int, bool) {
+	s.cacheMu.RLock()
+	defer s.cacheMu.RUnlock()
+	if id, ok := s.tagIDs[name]; ok {
+		return id, true
+	}
+	for tagName, id := range s.tagIDs {
+		if strings.EqualFold(tagName, name) {
+			return id, true
+		}
+	}
+	return 0, false
+}
+
+// tagNames returns the names of the given tag IDs that are in the cache.
+func (s *Server) tagNames(ids []int) []string {
+	s.cacheMu.RLock()
+	defer s.cacheMu.RUnlock()
+	var names []string
+	for name, id := range s.tagIDs {
+		for _, want := range ids {
+			if id == want {
+				names = append(names, name)
+			}
+		}
+	}
+	return names
+}
+
+// payoutPlatform returns the first configured payout platform among a
+// document's tags.
+func (s *Server) payoutPlatform(tags []int) (string, config.PlatformConfig, bool) {
+	s.cacheMu.RLock()
+	defer s.cacheMu.RUnlock()
+	for _, tagID := range tags {
+		options, ok := s.duckDBConfigs[tagID]
+		if !ok {
+			continue
+		}
+		for platform, id := range s.platformIDs {
+			if id == tagID {
+				return platform, options, true
+			}
+		}
+	}
+	return "", config.PlatformConfig{}, false
+}
+
+// payoutConfigs returns the payout platform configs keyed by platform name.
+// A reload replaces the map rather than modifying it, so callers may keep
+// reading it after the lock is released.
+func (s *Server) payoutConfigs() map[string]config.PlatformConfig {
+	s.cacheMu.RLock()
+	defer s.cacheMu.RUnlock()
+	return s.payoutPlatforms
+}
+
+// findCorrespondent looks a correspondent up in the cache, then in Paperless
+// in case it was created since the last refresh.  It returns nil when there
+// is none.
+func (s *Server) findCorrespondent(name string) (*paperless.Correspondent, error) {
+	s.cacheMu.RLock()
+	c, ok := s.correspondents[strings.ToLower(name)]
+	s.cacheMu.RUnlock()
+	if ok {
+		return &c, nil
+	}
+	found, err := s.paperlessClient.GetCorrespondent(s.ctx, name)
+	if err != nil || found == nil {
+		return nil, err
+	}
+	s.cacheCorrespondent(*found)
+	return found, nil
+}
+
+func (s *Server) cacheCorrespondent(c paperless.Correspondent) {
+	s.cacheMu.Lock()
+	defer s.cacheMu.Unlock()
+	if s.correspondents == nil {
+		s.correspondents = make(map[string]paperless.Correspondent)
+	}
+	s.correspondents[strings.ToLower(c.Name)] = c
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"paperless-document-processor/config"
+	"paperless-document-processor/pkg/paperless"
+)
+
+func TestRefreshCachesResolvesNewPlatformTags(t *testing.T) {
+	var mu sync.Mutex
+	tags := []paperless.Tag{{ID: 3, Name: "swiggy"}}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		switch r.URL.Path {
+		case "/api/tags/":
+			json.NewEncoder(w).Encode(paperless.PaginatedResponse[paperless.Tag]{Results: tags})
+		case "/api/custom_fields/":
+			json.NewEncoder(w).Encode(paperless.PaginatedResponse[paperless.CustomField]{Results: []paperless.CustomField{{ID: 1, Name: "Total"}}})
+		case "/api/correspondents/":
+			json.NewEncoder(w).Encode(paperless.PaginatedResponse[paperless.Correspondent]{Results: []paperless.Correspondent{{ID: 4, Name: "Acme Foods"}}})
+		default:
+			t.Errorf("Unexpected request: %s %s", r.Method, r.URL.Path)
+		}
+	}))
+	defer server.Close()
+
+	s := &Server{ctx: context.Background(), paperlessClient: paperless.NewClient(server.URL, "tok")}
+	s.setPayoutConfigs(config.PayoutConfigs{Platforms: map[string]config.PlatformConfig{
+		"swiggy": {ImportConfigs: []config.ImportConfig{{}}},
+		"zomato": {ImportConfigs: []config.ImportConfig{{}}},
+	}})
+
+	res := s.refreshCaches()
+	if len(res.Errors) != 0 || len(res.Platforms) != 1 || len(res.Unresolved) != 1 || res.Unresolved[0] != "zomato" {
+		t.Fatalf("unexpected first refresh: %+v", res)
+	}
+	if _, _, ok := s.payoutPlatform([]int{9}); ok {
+		t.Errorf("zomato should not resolve before its tag exists")
+	}
+
+	mu.Lock()
+	tags = append(tags, paperless.Tag{ID: 9, Name: "zomato"})
+	mu.Unlock()
+
+	rec := httptest.NewRecorder()
+	s.handleRefresh(rec, httptest.NewRequest("POST", "/admin/refresh", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body)
+	}
+	var body refreshResult
+	json.NewDecoder(rec.Body).Decode(&body)
+	if body.Tags != 2 || body.CustomFields != 1 || body.Correspondents != 1 || len(body.Platforms) != 2 {
+		t.Errorf("unexpected refresh response: %+v", body)
+	}
+	if platform, _, ok := s.payoutPlatform([]int{1, 9}); !ok || platform != "zomato" {
+		t.Errorf("expected zomato to resolve after refresh, got %q %v", platform, ok)
+	}
+	if c, err := s.findCorrespondent("ACME FOODS"); err != nil || c == nil || c.ID != 4 {
+		t.Errorf("expected cached correspondent, got %+v (err %v)", c, err)
+	}
+}
+
+func TestHandleRefreshReportsFailures(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "down", http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	s := &Server{ctx: context.Background(), paperlessClient: paperless.NewClient(server.URL, "tok"), tagIDs: map[string]int{"swiggy": 3}}
+
+	rec := httptest.NewRecorder()
+	s.handleRefresh(rec, httptest.NewRequest("POST", "/admin/refresh", nil))
+	if rec.Code != http.StatusBadGateway {
+		t.Fatalf("expected 502, got %d", rec.Code)
+	}
+	if id, ok := s.tagID("SWIGGY"); !ok || id != 3 {
+		t.Errorf("a failed refresh must keep the previous tags, got %d %v", id, ok)
+	}
+}
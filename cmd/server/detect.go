@@ -0,0 +1,58 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"path"
+	"strings"
+
+	"paperless-document-processor/config"
+	"paperless-document-processor/pkg/paperless"
+	"paperless-document-processor/pkg/payout"
+)
+
+// detectPayoutPlatform identifies a payout report without a platform tag by
+// the platforms' fingerprints, and adds the platform's tag to the document
+// so the Paperless UI and later runs see it like a tagged upload.  It fails
+// unless exactly one platform's fingerprint matches.
+func (s *Server) detectPayoutPlatform(doc *paperless.Document, meta *paperless.Metadata, filePath string, dry *dryRunResult) (string, config.PlatformConfig, error) {
+	platforms := s.payoutConfigs()
+	filename := doc.OriginalFileName
+	if filename == "" {
+		filename = meta.OriginalFileName
+	}
+	if filename == "" {
+		filename = path.Base(meta.MediaFilename)
+	}
+
+	matched, err := payout.Detect(platforms, filename, filePath)
+	if err != nil {
+		slog.Error("Failed to fingerprint payout report", "document_id", doc.ID, "error", err)
+		return "", config.PlatformConfig{}, fmt.Errorf("failed to fingerprint payout report: %w", err)
+	}
+	switch len(matched) {
+	case 0:
+		return "", config.PlatformConfig{}, fmt.Errorf("%q has no platform tag and matches no platform fingerprint", filename)
+	case 1:
+	default:
+		return "", config.PlatformConfig{}, fmt.Errorf("%q matches the fingerprints of several platforms: %s", filename, strings.Join(matched, ", "))
+	}
+
+	platform := matched[0]
+	slog.Info("Detected payout platform by fingerprint", "document_id", doc.ID, "platform", platform, "filename", filename)
+
+	tagID, ok := s.tagID(platform)
+	switch {
+	case !ok:
+		slog.Warn("Detected payout platform has no Paperless tag, document left untagged", "document_id", doc.ID, "platform", platform)
+	case dry != nil:
+		dry.notef("platform %s detected by fingerprint; the document would be tagged %q", platform, platform)
+	default:
+		// The tag only records the detection; the payout goes ahead
+		// without it.
+		if err := s.paperlessClient.ModifyTags(s.ctx, doc.ID, []int{tagID}, nil); err != nil {
+			slog.Warn("Failed to tag document with detected payout platform", "document_id", doc.ID, "platform", platform, "error", err)
+		}
+	}
+	return platform, platforms[platform], nil
+}
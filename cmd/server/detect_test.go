@@ -0,0 +1,65 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"paperless-document-processor/config"
+	"paperless-document-processor/pkg/paperless"
+)
+
+func TestDetectPayoutPlatform(t *testing.T) {
+	var tagged []any
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "POST" || r.URL.Path != "/api/documents/bulk_edit/" {
+			t.Errorf("Unexpected request: %s %s", r.Method, r.URL.Path)
+			return
+		}
+		var body struct {
+			Parameters map[string][]any `json:"parameters"`
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+		tagged = body.Parameters["add_tags"]
+	}))
+	defer server.Close()
+
+	s := &Server{
+		ctx:             context.Background(),
+		paperlessClient: paperless.NewClient(server.URL, "tok"),
+		tagIDs:          map[string]int{"Swiggy-Dineout": 4},
+		payoutPlatforms: map[string]config.PlatformConfig{
+			"swiggy-dineout": {Fingerprint: &config.Fingerprint{Filename: `(?i)dineout`}},
+			"zomato":         {Fingerprint: &config.Fingerprint{Filename: `(?i)zomato`}},
+		},
+	}
+	meta := &paperless.Metadata{MediaFilename: "0001.csv"}
+
+	doc := &paperless.Document{ID: 9, OriginalFileName: "dineout_settlement.csv"}
+	platform, _, err := s.detectPayoutPlatform(doc, meta, "/app/media/documents/originals/0001.csv", nil)
+	if err != nil || platform != "swiggy-dineout" {
+		t.Fatalf("got (%q, %v)", platform, err)
+	}
+	if len(tagged) != 1 || tagged[0] != float64(4) {
+		t.Errorf("document tagged with %v, want [4]", tagged)
+	}
+
+	dry := &dryRunResult{}
+	tagged = nil
+	if _, _, err := s.detectPayoutPlatform(doc, meta, "", dry); err != nil || tagged != nil || len(dry.Notes) != 1 {
+		t.Errorf("dry run: err %v, tagged %v, notes %v", err, tagged, dry.Notes)
+	}
+
+	doc.OriginalFileName = "zomato_dineout.csv"
+	if _, _, err := s.detectPayoutPlatform(doc, meta, "", nil); err == nil || !strings.Contains(err.Error(), "several platforms") {
+		t.Errorf("expected an ambiguity error, got %v", err)
+	}
+
+	doc.OriginalFileName = "statement.csv"
+	if _, _, err := s.detectPayoutPlatform(doc, meta, "", nil); err == nil || !strings.Contains(err.Error(), "matches no platform fingerprint") {
+		t.Errorf("expected a no-match error, got %v", err)
+	}
+}
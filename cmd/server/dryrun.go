@@ -0,0 +1,47 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+
+	"paperless-document-processor/pkg/accounting"
+	"paperless-document-processor/pkg/paperless"
+	"paperless-document-processor/pkg/storage"
+)
+
+// dryRunResult collects what a pipeline would have written.  When a process
+// function is given a non-nil result it performs every read (Paperless,
+// DocAI, spreadsheet imports into a scratch database) but records the
+// Paperless update and accounting inputs here instead of sending them.
+type dryRunResult struct {
+	DocumentID     int                           `json:"document_id"`
+	Kind           storage.JobKind               `json:"kind"`
+	DocumentUpdate *paperless.DocumentUpdate     `json:"document_update,omitempty"`
+	BillInput      *accounting.BillInput         `json:"bill_input,omitempty"`
+	PayoutInput    *accounting.PayoutInput       `json:"payout_input,omitempty"`
+	Transactions   []accounting.TransactionInput `json:"transactions,omitempty"`
+	// Notes explain writes that were skipped or would have side effects,
+	// such as a correspondent or vendor that would be created.
+	Notes []string `json:"notes,omitempty"`
+	Error string   `json:"error,omitempty"`
+}
+
+func (d *dryRunResult) notef(format string, args ...any) {
+	d.Notes = append(d.Notes, fmt.Sprintf(format, args...))
+}
+
+// runDryRun executes process synchronously against a fresh dryRunResult and
+// writes the result as the response.  A pipeline error is reported with a 422
+// alongside whatever was collected before it failed.
+func (s *Server) runDryRun(w http.ResponseWriter, kind storage.JobKind, docID int, process func(dry *dryRunResult) error) {
+	slog.Info("Running dry run", "document_id", docID, "kind", kind)
+	dry := &dryRunResult{DocumentID: docID, Kind: kind}
+	if err := process(dry); err != nil {
+		slog.Warn("Dry run failed", "document_id", docID, "kind", kind, "error", err)
+		dry.Error = err.Error()
+		writeJSON(w, http.StatusUnprocessableEntity, dry)
+		return
+	}
+	writeJSON(w, http.StatusOK, dry)
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+
+	"paperless-document-processor/pkg/docai"
+	"paperless-document-processor/pkg/paperless"
+)
+
+// mapCustomFields builds the custom field values for a document from the
+// configured entity mappings, coercing each to its field's data type.
+// Mappings whose field is unknown or whose value cannot be coerced are
+// skipped with a warning; the first mapping to produce a value for a field
+// wins.
+func (s *Server) mapCustomFields(docID int, extracted *docai.ExtractedData, dry *dryRunResult) []paperless.CustomFieldInstance {
+	var cfs []paperless.CustomFieldInstance
+	seen := make(map[int]bool)
+	dp := s.cfg.DateParser()
+
+	for _, m := range s.fieldMappings.Mappings {
+		field, found := s.customField(m.Field)
+		if !found || seen[field.ID] {
+			continue
+		}
+
+		raw, ok, err := m.Value(extracted.Entities, extracted.Normalized)
+		if err != nil {
+			slog.Warn("Custom field transform failed", "document_id", docID, "field", m.Field, "entity", m.Entity, "error", err)
+			continue
+		}
+		if !ok {
+			continue
+		}
+
+		currency := s.fieldMappings.CurrencyFor(m, extracted.Entities, extracted.Normalized)
+		value, err := field.Coerce(raw, currency, s.fieldMappings.DefaultCurrency, dp)
+		if err != nil {
+			slog.Warn("Skipping custom field: value does not fit its data type", "document_id", docID, "field", m.Field, "data_type", field.DataType, "value", raw, "error", err)
+			if dry != nil {
+				dry.notef("custom field %q skipped: %v", m.Field, err)
+			}
+			continue
+		}
+
+		if field.DataType == paperless.DataTypeDate {
+			if amb := dp.Ambiguity(raw); amb != nil {
+				slog.Warn("Ambiguous custom field date read in DATE_ORDER", "document_id", docID, "field", m.Field, "date", raw, "read_as", value)
+				if dry != nil {
+					dry.notef("custom field %q: %v", m.Field, amb)
+				}
+			}
+		}
+
+		seen[field.ID] = true
+		cfs = append(cfs, paperless.CustomFieldInstance{Field: field.ID, Value: value})
+	}
+	return cfs
+}
+
+// fieldReport records, for the startup log, which mappings can be applied.
+type fieldReport struct {
+	Active     []string // "entity -> field"
+	Created    []string // fields created in Paperless during startup
+	Unresolved []string // "field: reason"
+}
+
+// syncCustomFields checks every mapped field against the custom field cache.
+// With create set, fields missing from Paperless are created with their
+// mapping's data type and the cache is reloaded.  The resulting report is
+// logged and returned.
+func (s *Server) syncCustomFields(create bool) fieldReport {
+	var report fieldReport
+	failed := make(map[string]error)
+
+	if create {
+		created := make(map[string]bool)
+		for _, m := range s.fieldMappings.Mappings {
+			if _, found := s.customField(m.Field); found || created[m.Field] || failed[m.Field] != nil {
+				continue
+			}
+			field, err := s.paperlessClient.CreateCustomField(s.ctx, m.Field, m.FieldDataType())
+			if err != nil {
+				slog.Error("Failed to create custom field", "field", m.Field, "data_type", m.FieldDataType(), "error", err)
+				failed[m.Field] = err
+				continue
+			}
+			created[m.Field] = true
+			s.cacheMu.Lock()
+			s.customFields[field.Name] = *field
+			s.cacheMu.Unlock()
+			report.Created = append(report.Created, m.Field)
+		}
+		if len(report.Created) > 0 {
+			if err := s.loadCustomFields(); err != nil {
+				// The created fields are already cached from the create responses.
+				slog.Warn("Failed to reload custom fields after creating missing ones", "error", err)
+			}
+		}
+	}
+
+	for _, m := range s.fieldMappings.Mappings {
+		field, found := s.customField(m.Field)
+		switch {
+		case failed[m.Field] != nil:
+			report.Unresolved = append(report.Unresolved, fmt.Sprintf("%s: create failed: %v", m.Field, failed[m.Field]))
+		case !found:
+			report.Unresolved = append(report.Unresolved, fmt.Sprintf("%s: not defined in Paperless", m.Field))
+		default:
+			report.Active = append(report.Active, fmt.Sprintf("%s -> %s", m.Entity, m.Field))
+			if m.DataType != "" && m.DataType != field.DataType {
+				slog.Warn("Custom field data type differs from mapping; values are coerced to the field's type",
+					"field", m.Field, "mapping_data_type", m.DataType, "field_data_type", field.DataType)
+			}
+		}
+	}
+
+	slog.Info("Custom field mappings", "active", len(report.Active), "created", len(report.Created), "unresolved", len(report.Unresolved))
+	for _, a := range report.Active {
+		slog.Info("Custom field mapping active", "mapping", a)
+	}
+	for _, u := range report.Unresolved {
+		slog.Warn("Custom field mapping unresolved", "mapping", u)
+	}
+	return report
+}
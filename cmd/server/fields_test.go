@@ -0,0 +1,91 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"paperless-document-processor/config"
+	"paperless-document-processor/pkg/docai"
+	"paperless-document-processor/pkg/fieldmap"
+	"paperless-document-processor/pkg/paperless"
+)
+
+func TestSyncCustomFields(t *testing.T) {
+	fields := []paperless.CustomField{{ID: 1, Name: "Total", DataType: "monetary"}}
+	var created []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == "GET" && r.URL.Path == "/api/custom_fields/":
+			json.NewEncoder(w).Encode(paperless.PaginatedResponse[paperless.CustomField]{Results: fields})
+		case r.Method == "POST" && r.URL.Path == "/api/custom_fields/":
+			var body map[string]string
+			json.NewDecoder(r.Body).Decode(&body)
+			if body["name"] == "Broken" {
+				http.Error(w, `{"name": ["invalid"]}`, http.StatusBadRequest)
+				return
+			}
+			f := paperless.CustomField{ID: 10 + len(fields), Name: body["name"], DataType: body["data_type"]}
+			fields = append(fields, f)
+			created = append(created, body["name"]+":"+body["data_type"])
+			json.NewEncoder(w).Encode(f)
+		default:
+			t.Errorf("Unexpected request: %s %s", r.Method, r.URL.Path)
+		}
+	}))
+	defer server.Close()
+
+	s := &Server{
+		ctx:             context.Background(),
+		paperlessClient: paperless.NewClient(server.URL, "tok"),
+		fieldMappings: fieldmap.Config{Mappings: []fieldmap.Mapping{
+			{Entity: "total_amount", Field: "Total", DataType: "monetary"},
+			{Entity: "invoice_date", Field: "Invoice Date", DataType: "date"},
+			{Entity: "invoice_id", Field: "Invoice Number"},
+			{Entity: "invoice_id", Field: "Invoice Number"},
+			{Entity: "po", Field: "Broken"},
+		}},
+	}
+	if err := s.loadCustomFields(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	report := s.syncCustomFields(false)
+	if len(report.Active) != 1 || len(report.Unresolved) != 4 || len(report.Created) != 0 {
+		t.Errorf("unexpected report without create: %+v", report)
+	}
+	if len(created) != 0 {
+		t.Fatalf("fields created without auto-create: %v", created)
+	}
+
+	report = s.syncCustomFields(true)
+	if strings.Join(created, ",") != "Invoice Date:date,Invoice Number:string" {
+		t.Errorf("unexpected created fields: %v", created)
+	}
+	if len(report.Active) != 4 || len(report.Unresolved) != 1 || !strings.HasPrefix(report.Unresolved[0], "Broken: create failed") {
+		t.Errorf("unexpected report with create: %+v", report)
+	}
+	if f, ok := s.customFields["Invoice Date"]; !ok || f.DataType != "date" {
+		t.Errorf("expected created field in cache, got %+v", s.customFields)
+	}
+}
+
+func TestMapCustomFieldsKeepsPrintedCurrency(t *testing.T) {
+	s := &Server{
+		cfg:          &config.Config{},
+		customFields: map[string]paperless.CustomField{"Total": {ID: 1, Name: "Total", DataType: paperless.DataTypeMonetary}},
+		fieldMappings: fieldmap.Config{DefaultCurrency: "INR", Mappings: []fieldmap.Mapping{
+			{Entity: "total_amount", Field: "Total", DataType: "monetary"},
+		}},
+	}
+	for raw, want := range map[string]string{"$1,250.00": "USD1250.00", "1,250.00": "INR1250.00"} {
+		extracted := &docai.ExtractedData{Entities: map[string]string{"total_amount": raw}}
+		cfs := s.mapCustomFields(1, extracted, nil)
+		if len(cfs) != 1 || cfs[0].Value != want {
+			t.Errorf("total %q mapped to %+v, want %s", raw, cfs, want)
+		}
+	}
+}
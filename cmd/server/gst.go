@@ -0,0 +1,45 @@
+package main
+
+import (
+	"log/slog"
+
+	"paperless-document-processor/pkg/accounting"
+	"paperless-document-processor/pkg/docai"
+	"paperless-document-processor/pkg/gst"
+)
+
+// billGST reads the GST breakdown of a bill.  Failed checks are logged and
+// returned so they can be noted on the bill; they do not stop it being
+// created.  The breakdown is nil for invoices without any GST detail.
+func (s *Server) billGST(docID int, extracted *docai.ExtractedData, dry *dryRunResult) (*accounting.GSTBreakdown, []string) {
+	b := gst.Extract(extracted, s.cfg.GSTINs)
+	for _, p := range b.Problems {
+		slog.Warn("GST check failed", "document_id", docID, "problem", p)
+		if dry != nil {
+			dry.notef("GST check failed: %s", p)
+		}
+	}
+	if b.Empty() {
+		return nil, b.Problems
+	}
+	return gstBreakdown(b), b.Problems
+}
+
+// gstBreakdown converts a breakdown for the accounting bill.
+func gstBreakdown(b gst.Breakdown) *accounting.GSTBreakdown {
+	out := &accounting.GSTBreakdown{
+		SupplierGSTIN: b.SupplierGSTIN,
+		BuyerGSTIN:    b.BuyerGSTIN,
+		PlaceOfSupply: b.PlaceOfSupply,
+		TaxableAmount: int(b.TaxableAmount.Minor),
+		HSNCodes:      b.HSNCodes,
+	}
+	for _, t := range b.Taxes {
+		out.Taxes = append(out.Taxes, accounting.GSTTax{
+			Type:   t.Type,
+			Rate:   t.Rate,
+			Amount: int(t.Amount.Minor),
+		})
+	}
+	return out
+}
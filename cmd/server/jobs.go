@@ -0,0 +1,174 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"paperless-document-processor/pkg/retry"
+	"paperless-document-processor/pkg/storage"
+)
+
+// jobPollInterval bounds how long an idle worker waits before checking the
+// queue again when no wake-up signal arrives.
+const jobPollInterval = 5 * time.Second
+
+type enqueueResponse struct {
+	JobID int64            `json:"job_id"`
+	State storage.JobState `json:"state"`
+}
+
+// enqueueJob persists a job for docID and writes the job ID back to the caller.
+func (s *Server) enqueueJob(w http.ResponseWriter, kind storage.JobKind, docID int, req interface{}) {
+	jobID, err := s.submitJob(kind, docID, req)
+	if err != nil {
+		slog.Error("Failed to enqueue job", "kind", kind, "document_id", docID, "error", err)
+		http.Error(w, "Failed to enqueue job", http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, http.StatusAccepted, enqueueResponse{JobID: jobID, State: storage.JobStateQueued})
+}
+
+// submitJob persists a job for docID with req as its payload and wakes a
+// worker.
+func (s *Server) submitJob(kind storage.JobKind, docID int, req interface{}) (int64, error) {
+	payload, err := json.Marshal(req)
+	if err != nil {
+		return 0, fmt.Errorf("failed to encode job payload: %w", err)
+	}
+
+	jobID, err := s.db.EnqueueJob(kind, docID, string(payload))
+	if err != nil {
+		return 0, err
+	}
+	slog.Info("Job enqueued", "job_id", jobID, "kind", kind, "document_id", docID)
+	s.wakeWorkers()
+	return jobID, nil
+}
+
+// wakeWorkers nudges an idle worker without blocking when all are busy.
+func (s *Server) wakeWorkers() {
+	select {
+	case s.jobWake <- struct{}{}:
+	default:
+	}
+}
+
+// startWorkers requeues jobs interrupted by a previous shutdown and starts n
+// workers that drain the queue.
+func (s *Server) startWorkers(n int) {
+	requeued, err := s.db.RequeueInterruptedJobs()
+	if err != nil {
+		slog.Error("Failed to requeue interrupted jobs", "error", err)
+	} else if requeued > 0 {
+		slog.Info("Requeued jobs interrupted by previous shutdown", "count", requeued)
+	}
+
+	for i := 0; i < n; i++ {
+		go s.runWorker(i)
+	}
+	slog.Info("Started job workers", "count", n)
+}
+
+func (s *Server) runWorker(workerID int) {
+	ticker := time.NewTicker(jobPollInterval)
+	defer ticker.Stop()
+
+	for s.ctx.Err() == nil {
+		job, err := s.db.ClaimNextJob()
+		if err != nil {
+			slog.Error("Failed to claim job", "worker", workerID, "error", err)
+		}
+		if job == nil {
+			select {
+			case <-s.jobWake:
+			case <-ticker.C:
+			case <-s.ctx.Done():
+			}
+			continue
+		}
+
+		slog.Info("Job started", "worker", workerID, "job_id", job.ID, "kind", job.Kind, "document_id", job.PaperlessID, "attempt", job.Attempts)
+		var sum jobSummary
+		err = s.runJob(job, &sum)
+		if err != nil && s.ctx.Err() != nil {
+			// Left running, the job is requeued by the next startup.
+			slog.Warn("Job interrupted by shutdown", "worker", workerID, "job_id", job.ID, "kind", job.Kind, "document_id", job.PaperlessID, "error", err)
+			return
+		}
+		state, errText := jobOutcome(err)
+		if err != nil {
+			slog.Error("Job failed", "worker", workerID, "job_id", job.ID, "kind", job.Kind, "document_id", job.PaperlessID, "state", state, "error", err)
+		} else {
+			slog.Info("Job succeeded", "worker", workerID, "job_id", job.ID, "kind", job.Kind, "document_id", job.PaperlessID)
+		}
+		if err := s.db.FinishJob(job.ID, state, errText); err != nil {
+			slog.Error("Failed to record job result", "job_id", job.ID, "error", err)
+		}
+		s.reportOutcome(job, state, &sum, err)
+	}
+}
+
+// jobOutcome maps a pipeline result to the job's terminal state and error
+// text.  Client calls already retried transient errors, so one that still
+// surfaces here means the retry budget is spent: the job is dead-lettered
+// rather than failed so it can be resubmitted once the dependency recovers.
+func jobOutcome(err error) (storage.JobState, string) {
+	switch {
+	case err == nil:
+		return storage.JobStateSucceeded, ""
+	case retry.IsRetryable(err):
+		return storage.JobStateDeadLetter, err.Error()
+	default:
+		return storage.JobStateFailed, err.Error()
+	}
+}
+
+// setJobStep records the pipeline stage of jobID.  Failures are logged only:
+// step tracking must never abort a pipeline.
+// Dry runs pass jobID 0 and are not tracked.
+func (s *Server) setJobStep(jobID int64, step storage.JobStep) {
+	if jobID == 0 {
+		return
+	}
+	if err := s.db.SetJobStep(jobID, step); err != nil {
+		slog.Warn("Failed to record job step", "job_id", jobID, "step", step, "error", err)
+	}
+}
+
+// runJob decodes the job payload and dispatches it to the matching pipeline,
+// which records what it created in sum.  A panic inside a pipeline is
+// converted into a job failure so the worker keeps running.
+func (s *Server) runJob(job *storage.Job, sum *jobSummary) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v", r)
+		}
+	}()
+
+	switch job.Kind {
+	case storage.JobKindBill:
+		var req BillRequest
+		if err := json.Unmarshal([]byte(job.Payload), &req); err != nil {
+			return fmt.Errorf("failed to decode bill job payload: %w", err)
+		}
+		return s.processBill(job.ID, job.PaperlessID, req, sum, nil)
+	case storage.JobKindPayout:
+		var req PayoutRequest
+		if err := json.Unmarshal([]byte(job.Payload), &req); err != nil {
+			return fmt.Errorf("failed to decode payout job payload: %w", err)
+		}
+		return s.processPayout(job.ID, job.PaperlessID, req, sum, nil)
+	case storage.JobKindBankStatement:
+		var req BankStatementRequest
+		if err := json.Unmarshal([]byte(job.Payload), &req); err != nil {
+			return fmt.Errorf("failed to decode bank statement job payload: %w", err)
+		}
+		return s.processBankStatement(job.ID, job.PaperlessID, req, sum, nil)
+	default:
+		return fmt.Errorf("unknown job kind %q", job.Kind)
+	}
+}
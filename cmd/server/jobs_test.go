@@ -0,0 +1,32 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"paperless-document-processor/pkg/retry"
+	"paperless-document-processor/pkg/storage"
+)
+
+func TestJobOutcome(t *testing.T) {
+	invalid := &retry.StatusError{StatusCode: 422, Err: errors.New("status 422: invalid bill")}
+	unavailable := fmt.Errorf("failed to create bill: %w", &retry.StatusError{StatusCode: 503, Err: errors.New("status 503")})
+	cases := []struct {
+		name    string
+		err     error
+		state   storage.JobState
+		errText string
+	}{
+		{"success", nil, storage.JobStateSucceeded, ""},
+		{"permanent error", errors.New("no valid amount"), storage.JobStateFailed, "no valid amount"},
+		{"client error", invalid, storage.JobStateFailed, "status 422: invalid bill"},
+		{"retries exhausted", unavailable, storage.JobStateDeadLetter, "failed to create bill: status 503"},
+	}
+	for _, tc := range cases {
+		state, errText := jobOutcome(tc.err)
+		if state != tc.state || errText != tc.errText {
+			t.Errorf("%s: got (%s, %q), want (%s, %q)", tc.name, state, errText, tc.state, tc.errText)
+		}
+	}
+}
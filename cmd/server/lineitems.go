@@ -0,0 +1,66 @@
+package main
+
+import (
+	"paperless-document-processor/pkg/accounting"
+	"paperless-document-processor/pkg/docai"
+	"paperless-document-processor/pkg/money"
+	"paperless-document-processor/pkg/storage"
+)
+
+// lineItems converts the extracted line items for storage, numbering them
+// from 1.  A missing amount is computed from quantity and unit price.
+// Items with neither a description nor an amount are dropped.
+func lineItems(extracted []docai.LineItem) []storage.LineItem {
+	var items []storage.LineItem
+	for _, li := range extracted {
+		item := storage.LineItem{
+			Description: li.Description,
+			ProductCode: li.ProductCode,
+			Unit:        li.Unit,
+		}
+		item.Quantity, _ = money.ParseNumber(li.Quantity)
+		item.UnitPrice, _ = money.Parse(li.UnitPrice)
+		if amount, err := money.Parse(li.Amount); err == nil {
+			item.Amount = amount
+		} else {
+			item.Amount = item.UnitPrice.Mul(item.Quantity)
+		}
+		if item.Description == "" && item.Amount.IsZero() {
+			continue
+		}
+		item.LineNo = len(items) + 1
+		items = append(items, item)
+	}
+	return items
+}
+
+// billLineItems converts stored line items for the accounting bill.
+func billLineItems(items []storage.LineItem) []accounting.BillLineItem {
+	var out []accounting.BillLineItem
+	for _, item := range items {
+		out = append(out, accounting.BillLineItem{
+			Description: item.Description,
+			ProductCode: item.ProductCode,
+			Quantity:    item.Quantity,
+			Unit:        item.Unit,
+			UnitPrice:   int(item.UnitPrice.Minor),
+			Amount:      int(item.Amount.Minor),
+		})
+	}
+	return out
+}
+
+// billCurrency returns the currency of a bill: DefaultCurrency unless its
+// total or one of its line items is in another currency.
+func billCurrency(extracted *docai.ExtractedData, items []storage.LineItem) string {
+	amounts := []money.Money{extracted.TotalAmount}
+	for _, item := range items {
+		amounts = append(amounts, item.UnitPrice, item.Amount)
+	}
+	for _, m := range amounts {
+		if cur := m.CurrencyCode(); cur != money.DefaultCurrency {
+			return cur
+		}
+	}
+	return money.DefaultCurrency
+}
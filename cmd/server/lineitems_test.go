@@ -0,0 +1,52 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+
+	"paperless-document-processor/pkg/accounting"
+	"paperless-document-processor/pkg/docai"
+	"paperless-document-processor/pkg/money"
+	"paperless-document-processor/pkg/storage"
+)
+
+func TestLineItems(t *testing.T) {
+	items := lineItems([]docai.LineItem{
+		{Description: "Basmati Rice", Quantity: "2", UnitPrice: "₹1,800.50", ProductCode: "1006"},
+		{},
+		{Description: "Delivery", Amount: "Rs. 150"},
+	})
+	want := []storage.LineItem{
+		{LineNo: 1, Description: "Basmati Rice", ProductCode: "1006", Quantity: 2, UnitPrice: money.New(180050, "INR"), Amount: money.New(360100, "INR")},
+		{LineNo: 2, Description: "Delivery", Amount: money.New(15000, "INR")},
+	}
+	if !reflect.DeepEqual(items, want) {
+		t.Fatalf("lineItems = %+v, want %+v", items, want)
+	}
+
+	bill := billLineItems(items)
+	wantBill := []accounting.BillLineItem{
+		{Description: "Basmati Rice", ProductCode: "1006", Quantity: 2, UnitPrice: 180050, Amount: 360100},
+		{Description: "Delivery", Amount: 15000},
+	}
+	if !reflect.DeepEqual(bill, wantBill) {
+		t.Errorf("billLineItems = %+v, want %+v", bill, wantBill)
+	}
+}
+
+func TestBillCurrency(t *testing.T) {
+	inr := &docai.ExtractedData{TotalAmount: money.MustParse("₹1,000")}
+	if got := billCurrency(inr, nil); got != "INR" {
+		t.Errorf("rupee bill: got %s", got)
+	}
+	if got := billCurrency(&docai.ExtractedData{TotalAmount: money.MustParse("1000")}, nil); got != "INR" {
+		t.Errorf("bill without a currency: got %s", got)
+	}
+	if got := billCurrency(&docai.ExtractedData{TotalAmount: money.MustParse("USD 12")}, nil); got != "USD" {
+		t.Errorf("dollar total: got %s", got)
+	}
+	items := []storage.LineItem{{Description: "Licence", Amount: money.MustParse("$12")}}
+	if got := billCurrency(inr, items); got != "USD" {
+		t.Errorf("dollar line item: got %s", got)
+	}
+}
@@ -3,20 +3,26 @@ package main
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log/slog"
 	"net/http"
 	"os"
-	"strconv"
+	"os/signal"
 	"strings"
+	"sync"
+	"syscall"
 	"time"
 
 	"paperless-document-processor/config"
 	"paperless-document-processor/pkg/accounting"
+	"paperless-document-processor/pkg/dates"
 	"paperless-document-processor/pkg/docai"
-	"paperless-document-processor/pkg/excel"
+	"paperless-document-processor/pkg/fieldmap"
 	"paperless-document-processor/pkg/libreoffice"
+	"paperless-document-processor/pkg/money"
 	"paperless-document-processor/pkg/paperless"
+	"paperless-document-processor/pkg/payout"
 	"paperless-document-processor/pkg/storage"
 	"paperless-document-processor/pkg/tika"
 
@@ -24,28 +30,49 @@ import (
 )
 
 type Server struct {
-	cfg                  *config.Config
-	db                   *storage.DB
-	paperlessClient      *paperless.Client
-	docAIClient          *docai.Client
-	accountingClient     *accounting.Client    // nil if not configured
-	tikaClient           *tika.Client          // nil if not configured
-	libreOfficeClient    *libreoffice.Client   // nil if not configured
-	customFields         map[string]int        // Name -> ID
-	tagIDs               map[string]int        // Name -> ID (e.g., "Swiggy" -> 3)
-	duckDBConfigs        map[int]config.PlatformConfig
+	ctx               context.Context // cancelled on shutdown
+	cfg               *config.Config
+	db                *storage.DB
+	paperlessClient   *paperless.Client
+	docAIClient       *docai.Client
+	accountingClient  *accounting.Client  // nil if not configured
+	tikaClient        *tika.Client        // nil if not configured
+	libreOfficeClient *libreoffice.Client // nil if not configured
+	fieldMappings     fieldmap.Config
+	jobWake           chan struct{} // signals idle workers that a job was enqueued
+
+	// Paperless caches, refreshed while running (see cache.go).
+	cacheMu         sync.RWMutex
+	customFields    map[string]paperless.CustomField   // Name -> field
+	tagIDs          map[string]int                     // Name -> ID (e.g., "Swiggy" -> 3)
+	correspondents  map[string]paperless.Correspondent // lower-cased name -> correspondent
+	payoutPlatforms map[string]config.PlatformConfig   // platform tag name -> config, from the JSON file
+	platformIDs     map[string]int                     // platform tag name -> resolved tag ID
+	outlets         config.OutletConfig
+	duckDBConfigs   map[int]config.PlatformConfig
 }
 
 type BillRequest struct {
 	DocURL string `json:"doc_url"`
+	// UpdateAccounting updates the bill created by an earlier run instead of
+	// creating a new one.  Set by the reprocess endpoint.
+	UpdateAccounting bool `json:"update_accounting,omitempty"`
+	// DryRun runs the pipeline synchronously and returns what would be
+	// written instead of writing it.
+	DryRun bool `json:"dry_run,omitempty"`
 }
 
 type PayoutRequest struct {
 	DocURL string `json:"doc_url"`
+	// UpdateAccounting updates the payout created by an earlier run instead
+	// of creating a new one.  Set by the reprocess endpoint.
+	UpdateAccounting bool `json:"update_accounting,omitempty"`
+	DryRun           bool `json:"dry_run,omitempty"`
 }
 
 type BankStatementRequest struct {
 	DocURL string `json:"doc_url"`
+	DryRun bool   `json:"dry_run,omitempty"`
 }
 
 func main() {
@@ -82,19 +109,26 @@ func main() {
 
 	// 3. Init Clients
 	pClient := paperless.NewClient(cfg.PaperlessURL, cfg.PaperlessToken)
+	pClient.SetRetryPolicy(cfg.PaperlessRetry)
+
+	// ctx is cancelled on SIGINT or SIGTERM: in-flight client calls and
+	// their retries stop and the HTTP server shuts down.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
 
-	ctx := context.Background()
 	dClient, err := docai.NewClient(ctx, cfg.GoogleProjectID, cfg.GoogleLocation, cfg.DocumentAIProcessorID, cfg.GoogleCredentialsPath)
 	if err != nil {
 		slog.Error("Failed to init DocAI client", "error", err)
 		os.Exit(1)
 	}
 	defer dClient.Close()
+	dClient.SetRetryPolicy(cfg.DocAIRetry)
 
 	// Init Accounting client (optional)
 	var acClient *accounting.Client
 	if cfg.AccountingURL != "" {
 		acClient = accounting.NewClient(cfg.AccountingURL, cfg.AccountingUser, cfg.AccountingPass)
+		acClient.SetRetryPolicy(cfg.AccountingRetry)
 		slog.Info("Accounting integration enabled", "url", cfg.AccountingURL)
 	} else {
 		slog.Info("Accounting integration disabled (ACCOUNTING_URL not set)")
@@ -104,12 +138,20 @@ func main() {
 	var loClient *libreoffice.Client
 	if cfg.LibreOfficeURL != "" {
 		loClient = libreoffice.NewClient(cfg.LibreOfficeURL, cfg.LibreOfficeDataPath)
+		loClient.SetRetryPolicy(cfg.LibreOfficeRetry)
 		slog.Info("LibreOffice parser integration configured", "url", cfg.LibreOfficeURL, "data_path", cfg.LibreOfficeDataPath)
 	} else {
 		slog.Info("LibreOffice parser integration disabled (LIBREOFFICE_URL not set)")
 	}
 
+	fieldMappings, err := fieldmap.Load(cfg.CustomFieldMappingPath)
+	if err != nil {
+		slog.Error("Failed to load custom field mapping", "path", cfg.CustomFieldMappingPath, "error", err)
+		os.Exit(1)
+	}
+
 	srv := &Server{
+		ctx:               ctx,
 		cfg:               cfg,
 		db:                db,
 		paperlessClient:   pClient,
@@ -117,136 +159,136 @@ func main() {
 		accountingClient:  acClient,
 		tikaClient:        tika.NewClient(cfg.TikaURL),
 		libreOfficeClient: loClient,
-		customFields:      make(map[string]int),
-		tagIDs:            make(map[string]int),
-		duckDBConfigs:     make(map[int]config.PlatformConfig),
+		fieldMappings:     fieldMappings,
+		jobWake:           make(chan struct{}, 1),
+		customFields:      make(map[string]paperless.CustomField),
 	}
 
 	// 4. Fetch Custom Fields (Retry policy could be added)
 	slog.Info("Fetching custom fields from Paperless...")
-	fields, err := pClient.GetCustomFields()
-	if err != nil {
+	if err := srv.loadCustomFields(); err != nil {
 		slog.Warn("Failed to fetch custom fields. Custom field updates will be skipped.", "error", err)
 	} else {
-		for _, f := range fields {
-			srv.customFields[f.Name] = f.ID
-		}
 		slog.Info("Loaded custom fields", "count", len(srv.customFields))
+		// Creating fields without knowing which exist would duplicate them,
+		// so this only runs after a successful fetch.
+		srv.syncCustomFields(cfg.CustomFieldsAutoCreate)
+	}
+
+	// 5. Load payout configs, then fetch tags and correspondents.  The
+	// configs are keyed by platform tag name and resolved to tag IDs on every
+	// tag refresh.
+	if cfg.PayoutConfigPath != "" {
+		slog.Info("Loading payout configurations from file", "path", cfg.PayoutConfigPath)
+		pc, err := config.LoadPayoutConfigs(cfg.PayoutConfigPath)
+		if err != nil {
+			slog.Error("Failed to load payout config file", "path", cfg.PayoutConfigPath, "error", err)
+			os.Exit(1)
+		}
+		srv.setPayoutConfigs(pc)
+		srv.watchPayoutConfigs(cfg.PayoutConfigPath, cfg.PayoutConfigWatchInterval)
+	} else {
+		slog.Info("No PAYOUT_EXCEL_DUCKDB_CONFIG_PATH set, no payout platforms configured")
 	}
 
-	// 5. Fetch Tags and Setup DuckDB Configs
 	slog.Info("Fetching tags from Paperless...")
-	tags, err := pClient.GetTags()
-	if err != nil {
-		slog.Warn("Failed to fetch tags. Dynamic DuckDB config will be limited.", "error", err)
+	if err := srv.loadTags(); err != nil {
+		slog.Warn("Failed to fetch tags. Dynamic DuckDB config will be limited until the next refresh.", "error", err)
 	} else {
-		for _, t := range tags {
-			srv.tagIDs[t.Name] = t.ID
-		}
 		slog.Info("Loaded tags", "count", len(srv.tagIDs))
+	}
+	if err := srv.loadCorrespondents(); err != nil {
+		slog.Warn("Failed to fetch correspondents; they will be looked up per document", "error", err)
+	} else {
+		slog.Info("Loaded correspondents", "count", len(srv.correspondents))
+	}
+	if cfg.CacheRefreshInterval > 0 {
+		srv.startCacheRefresher(cfg.CacheRefreshInterval)
+	}
 
-		// Load external payout configs if path is set
-		if cfg.PayoutConfigPath != "" {
-			slog.Info("Loading payout configurations from file", "path", cfg.PayoutConfigPath)
-			data, err := os.ReadFile(cfg.PayoutConfigPath)
-			if err != nil {
-				slog.Error("Failed to read payout config file", "path", cfg.PayoutConfigPath, "error", err)
-			} else {
-				var pConfigs config.PayoutConfigs
-				if err := json.Unmarshal(data, &pConfigs); err != nil {
-					slog.Error("Failed to parse payout config JSON", "error", err)
-				} else {
-					for platform, options := range pConfigs.Platforms {
-						if id, ok := srv.tagIDs[platform]; ok {
-							srv.duckDBConfigs[id] = options
-							slog.Info("Configured platform via JSON", "platform", platform, "tag_id", id)
-						} else {
-							slog.Warn("Platform in config not found in Paperless tags", "platform", platform)
-						}
-					}
-				}
-			}
-		} else {
-			slog.Info("No PAYOUT_EXCEL_DUCKDB_CONFIG_PATH set, using defaults")
-			// Fallback to hardcoded defaults for Swiggy/Zomato
-			// if id, ok := srv.tagIDs["swiggy"]; ok {
-			// 	srv.duckDBConfigs[id] = map[string]interface{}{
-			// 		"header":        true,
-			// 		"sheet":         "Order Level",
-			// 		"range":         "A3:AR",
-			// 		"stop_at_empty": true,
-			// 	}
-			// }
-			// if id, ok := srv.tagIDs["zomato"]; ok {
-			// 	srv.duckDBConfigs[id] = map[string]interface{}{
-			// 		"header":      true,
-			// 		"sheet":       "Payout Breakup",
-			// 		"all_varchar": true,
-			// 	}
-			// }
-		}
+	// 6. Start job workers (drains jobs left over from a previous run)
+	srv.startWorkers(cfg.WorkerCount)
+	if cfg.PollInterval > 0 {
+		srv.startPoller(cfg.PollInterval)
+	}
+
+	// 7. Start Server
+	auth, err := newWebhookAuth(cfg)
+	if err != nil {
+		slog.Error("Invalid webhook auth config", "error", err)
+		os.Exit(1)
 	}
+	if auth.enabled() {
+		slog.Info("Webhook authentication enabled", "modes", auth.modes())
+	} else {
+		slog.Warn("Webhook authentication disabled; set WEBHOOK_TOKEN, WEBHOOK_HMAC_SECRET or WEBHOOK_ALLOWED_IPS")
+	}
+	http.HandleFunc("POST /bills", auth.wrap(srv.handleBills))
+	http.HandleFunc("POST /payouts", auth.wrap(srv.handlePayouts))
+	http.HandleFunc("POST /bank-statements", auth.wrap(srv.handleBankStatements))
+	http.HandleFunc("GET /jobs", srv.handleListJobs)
+	http.HandleFunc("GET /jobs/{id}", srv.handleGetJob)
+	http.HandleFunc("GET /documents/{paperless_id}/jobs", srv.handleDocumentJobs)
+	http.HandleFunc("POST /documents/{id}/reprocess", auth.wrap(srv.handleReprocess))
+	http.HandleFunc("GET /payment-terms", srv.handleListPaymentTerms)
+	http.HandleFunc("PUT /payment-terms", auth.wrap(srv.handleSetPaymentTerms))
+	http.HandleFunc("DELETE /payment-terms", auth.wrap(srv.handleDeletePaymentTerms))
+	http.HandleFunc("POST /admin/refresh", auth.wrap(srv.handleRefresh))
+	http.HandleFunc("POST /admin/payout-configs/validate", auth.wrap(srv.handleValidatePayoutConfigs))
+	httpServer := &http.Server{Addr: ":" + cfg.Port}
+	go func() {
+		<-ctx.Done()
+		slog.Info("Shutting down; running jobs will be requeued on restart")
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		if err := httpServer.Shutdown(shutdownCtx); err != nil {
+			slog.Warn("HTTP server shutdown failed", "error", err)
+		}
+	}()
 
-	// 6. Start Server
-	http.HandleFunc("POST /bills", srv.handleBills)
-	http.HandleFunc("POST /payouts", srv.handlePayouts)
-	http.HandleFunc("POST /bank-statements", srv.handleBankStatements)
 	slog.Info("Starting server", "port", cfg.Port)
-	if err := http.ListenAndServe(":"+cfg.Port, nil); err != nil {
+	if err := httpServer.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
 		slog.Error("Server failed", "error", err)
 		os.Exit(1)
 	}
 }
 
 func (s *Server) handleBills(w http.ResponseWriter, r *http.Request) {
-
-	var req BillRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		slog.Error("Failed to decode bill request", "error", err)
-		http.Error(w, "Invalid JSON body", http.StatusBadRequest)
+	p, ok := s.decodeWebhook(w, r, "bill")
+	if !ok {
 		return
 	}
+	docID := p.DocumentID
+	req := BillRequest{DocURL: p.DocURL, UpdateAccounting: p.UpdateAccounting, DryRun: p.DryRun}
 
-	// Extract ID from URL (e.g. http://webserver:8000/documents/73/)
-	trimmed := strings.TrimSuffix(req.DocURL, "/")
-	parts := strings.Split(trimmed, "/")
-	if len(parts) == 0 {
-		http.Error(w, "Invalid doc_url format", http.StatusBadRequest)
+	if req.DryRun {
+		s.runDryRun(w, storage.JobKindBill, docID, func(dry *dryRunResult) error {
+			return s.processBill(0, docID, req, &jobSummary{}, dry)
+		})
 		return
 	}
-	idStr := parts[len(parts)-1]
-
-	docID, err := strconv.Atoi(idStr)
-	if err != nil {
-		slog.Error("Invalid document ID format from url", "url", req.DocURL, "id_part", idStr, "error", err)
-		http.Error(w, "Invalid document ID in URL", http.StatusBadRequest)
-		return
-	}
-
-	slog.Info("Received bill request", "doc_url", req.DocURL, "document_id", docID)
-
-	// Run processing asynchronously
-	go s.processBill(docID, req)
-
-	w.WriteHeader(http.StatusOK)
-	w.Write([]byte("Processing started"))
+	s.enqueueJob(w, storage.JobKindBill, docID, req)
 }
 
-func (s *Server) processBill(docID int, req BillRequest) {
+// processBill runs the bill pipeline, recording what it created in sum.  When
+// dry is non-nil nothing is written to the database, accounting or
+// Paperless; the would-be writes are recorded in dry instead.
+func (s *Server) processBill(jobID int64, docID int, req BillRequest, sum *jobSummary, dry *dryRunResult) error {
 	slog.Info("Starting processing", "document_id", docID)
 
 	// 1. Get Metadata
-	doc, err := s.paperlessClient.GetDocument(docID)
+	s.setJobStep(jobID, storage.JobStepDownload)
+	doc, err := s.paperlessClient.GetDocument(s.ctx, docID)
 	if err != nil {
 		slog.Error("Error getting document", "document_id", docID, "error", err)
-		return
+		return fmt.Errorf("failed to get document: %w", err)
 	}
 
 	// 2. Download Content
-	content, err := s.paperlessClient.DownloadDocument(docID, false)
+	content, err := s.paperlessClient.DownloadDocument(s.ctx, docID, false)
 	if err != nil {
 		slog.Error("Error downloading content", "document_id", docID, "error", err)
-		return
+		return fmt.Errorf("failed to download document: %w", err)
 	}
 
 	// 3. Process with DocAI
@@ -255,10 +297,11 @@ func (s *Server) processBill(docID int, req BillRequest) {
 	slog.Info("Detected MIME type", "document_id", docID, "mimetype", mimeType, "extension", mtype.Extension())
 
 	slog.Info("Sending to Document AI", "document_id", docID, "mime_type", mimeType)
-	aiDoc, err := s.docAIClient.ProcessDocument(context.Background(), "", content, mimeType)
+	s.setJobStep(jobID, storage.JobStepDocAI)
+	aiDoc, err := s.docAIClient.ProcessDocument(s.ctx, "", content, mimeType)
 	if err != nil {
 		slog.Error("DocAI error", "document_id", docID, "error", err)
-		return
+		return fmt.Errorf("docai processing failed: %w", err)
 	}
 
 	extracted := s.docAIClient.ExtractData(aiDoc)
@@ -270,26 +313,43 @@ func (s *Server) processBill(docID int, req BillRequest) {
 	// For "raw_ocr_data", we can marshal aiDoc to JSON.
 	rawJSON, _ := json.Marshal(aiDoc.Entities)
 
-	totalAmount, _ := strconv.ParseFloat(extracted.TotalAmount, 64) // weak parsing, clean up usually needed (remove currency symbols)
+	// The date is stored as printed when it cannot be normalized, so it
+	// can still be reviewed.
+	docDate := extracted.ExampleDate
+	if iso, err := s.cfg.DateParser().Normalize(docDate); err == nil {
+		docDate = iso
+	}
 
+	s.setJobStep(jobID, storage.JobStepDBSave)
 	dbDoc := &storage.ProcessedDocument{
 		PaperlessID:   docID,
 		Filename:      doc.OriginalFileName,
 		Supplier:      extracted.Supplier,
-		Date:          extracted.ExampleDate,
-		TotalAmount:   totalAmount,
+		Date:          docDate,
+		TotalAmount:   extracted.TotalAmount,
 		RawOCRData:    string(rawJSON),
 		ExtractedText: extracted.Text,
 	}
 
-	if err := s.db.SaveDocument(dbDoc); err != nil {
-		slog.Error("DB Save error", "document_id", docID, "error", err)
-		// Continue anyway? Yes.
+	items := lineItems(extracted.LineItems)
+	if dry == nil {
+		if err := s.db.SaveDocument(dbDoc); err != nil {
+			slog.Error("DB Save error", "document_id", docID, "error", err)
+			// Continue anyway? Yes.
+		}
+		if err := s.db.SaveLineItems(docID, items); err != nil {
+			slog.Error("Failed to save bill line items", "document_id", docID, "error", err)
+		}
 	}
 
-	// 4b. Create Bill in Accounting (optional)
+	// 4b. Create Bill in Accounting (optional). A failure here is reported
+	// on the job but does not prevent the Paperless update below.
+	var accountingErr error
 	if s.accountingClient != nil {
-		s.createLocalBill(docID, extracted, doc, req)
+		s.setJobStep(jobID, storage.JobStepAccounting)
+		accountingErr = s.createLocalBill(docID, extracted, items, doc, req, sum, dry)
+	} else if dry != nil {
+		dry.notef("accounting integration disabled; no bill would be created")
 	}
 
 	// 5. Update Paperless
@@ -301,7 +361,16 @@ func (s *Server) processBill(docID int, req BillRequest) {
 	}
 
 	// Update Correspondent
-	if extracted.Supplier != "" {
+	if extracted.Supplier != "" && dry != nil {
+		corr, err := s.findCorrespondent(extracted.Supplier)
+		if err != nil {
+			slog.Warn("Correspondent error", "document_id", docID, "error", err)
+		} else if corr == nil {
+			dry.notef("correspondent %q would be created", extracted.Supplier)
+		} else {
+			updates.Correspondent = &corr.ID
+		}
+	} else if extracted.Supplier != "" {
 		corr, err := s.getOrCreateCorrespondent(extracted.Supplier)
 		if err != nil {
 			slog.Warn("Correspondent error", "document_id", docID, "error", err)
@@ -318,58 +387,36 @@ func (s *Server) processBill(docID int, req BillRequest) {
 	// }
 
 	// Update Custom Fields
-	var cfs []paperless.CustomFieldInstance
-
-	if val, ok := extracted.Entities["invoice_date"]; ok && val != "" {
-		if id, found := s.customFields["Invoice Date"]; found {
-			cfs = append(cfs, paperless.CustomFieldInstance{Field: id, Value: val}) // Paperless expects YYYY-MM-DD usually
-		}
-	}
-	if _, ok := extracted.Entities["total_amount"]; ok && extracted.TotalAmount != "" {
-		if id, found := s.customFields["Total"]; found {
-			cfs = append(cfs, paperless.CustomFieldInstance{Field: id, Value: extracted.TotalAmount})
-		}
-		if id, found := s.customFields["Amount"]; found {
-			cfs = append(cfs, paperless.CustomFieldInstance{Field: id, Value: extracted.TotalAmount})
-		}
+	cfs := s.mapCustomFields(docID, extracted, dry)
+	if len(cfs) > 0 {
+		updates.CustomFields = cfs
 	}
-	// Generic loop for others if configured
-	for k, v := range extracted.Entities {
-		// Map "invoice_id" -> "Invoice Number"
-		// This mapping logic should ideally be configurable or strict.
-		// For now, let's map normalized keys to likely names.
-		targetName := ""
-		switch k {
-		case "invoice_id":
-			targetName = "Invoice Number"
-		case "currency":
-			targetName = "Currency"
-		case "net_amount":
-			targetName = "Net Amount"
-		}
 
-		if targetName != "" {
-			if id, found := s.customFields[targetName]; found {
-				cfs = append(cfs, paperless.CustomFieldInstance{Field: id, Value: v})
-			}
+	if dry != nil {
+		dry.DocumentUpdate = &updates
+		if accountingErr != nil {
+			return fmt.Errorf("failed to build accounting bill: %w", accountingErr)
 		}
+		return nil
 	}
 
-	if len(cfs) > 0 {
-		updates.CustomFields = cfs
+	s.setJobStep(jobID, storage.JobStepPaperlessUpdate)
+	if err := s.paperlessClient.UpdateDocument(s.ctx, docID, updates); err != nil {
+		slog.Error("Update error", "document_id", docID, "error", err)
+		return fmt.Errorf("failed to update paperless document: %w", err)
 	}
 
-	if err := s.paperlessClient.UpdateDocument(docID, updates); err != nil {
-		slog.Error("Update error", "document_id", docID, "error", err)
-		return
+	if accountingErr != nil {
+		return fmt.Errorf("failed to create accounting bill: %w", accountingErr)
 	}
 
 	slog.Info("Successfully processed and updated", "document_id", docID)
+	return nil
 }
 
 func (s *Server) getOrCreateCorrespondent(name string) (*paperless.Correspondent, error) {
 	// 1. Try finding
-	existing, err := s.paperlessClient.GetCorrespondent(name)
+	existing, err := s.findCorrespondent(name)
 	if err != nil {
 		return nil, err
 	}
@@ -378,42 +425,88 @@ func (s *Server) getOrCreateCorrespondent(name string) (*paperless.Correspondent
 	}
 
 	// 2. Create
-	return s.paperlessClient.CreateCorrespondent(name)
+	created, err := s.paperlessClient.CreateCorrespondent(s.ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	s.cacheCorrespondent(*created)
+	return created, nil
 }
 
-func (s *Server) createLocalBill(docID int, extracted *docai.ExtractedData, doc *paperless.Document, req BillRequest) {
+func (s *Server) createLocalBill(docID int, extracted *docai.ExtractedData, items []storage.LineItem, doc *paperless.Document, req BillRequest, sum *jobSummary, dry *dryRunResult) error {
 	slog.Info("Creating local accounting bill", "document_id", docID, "supplier", extracted.Supplier)
 
+	// The accounting service books bills in paise and has no currency, so
+	// a bill in another currency would be posted as that many rupees.
+	if cur := billCurrency(extracted, items); cur != money.DefaultCurrency {
+		slog.Warn("Skipping accounting bill: not in rupees", "document_id", docID, "currency", cur, "amount", extracted.TotalAmount)
+		if dry != nil {
+			dry.notef("bill is in %s, not %s; accounting bill would be skipped", cur, money.DefaultCurrency)
+		}
+		sum.Skipped = fmt.Sprintf("bill is in %s, not %s, accounting bill not created", cur, money.DefaultCurrency)
+		return nil
+	}
+
 	// Resolve vendor contact
 	contactName := extracted.Supplier
 	if contactName == "" {
 		contactName = "Unknown Vendor"
 	}
 
-	contactID, err := s.accountingClient.GetOrCreateVendor(contactName)
+	var contactID int
+	var err error
+	if dry != nil {
+		var found bool
+		contactID, found, err = s.accountingClient.FindVendor(s.ctx, contactName)
+		if err == nil && !found {
+			dry.notef("vendor %q would be created", contactName)
+		}
+	} else {
+		contactID, err = s.accountingClient.GetOrCreateVendor(s.ctx, contactName)
+	}
 	if err != nil {
 		slog.Error("Accounting contact error", "document_id", docID, "error", err)
-		return
-	}
-
-	// Parse dates
-	issuedAt := extracted.ExampleDate
-	if issuedAt == "" {
-		issuedAt = time.Now().Format("2006-01-02")
+		return err
+	}
+
+	// Issue date.  A missing or unreadable date is left blank for review
+	// rather than guessed; an ambiguous one like 03/04/2025 is read in
+	// DATE_ORDER and noted.
+	dp := s.cfg.DateParser()
+	var issued time.Time
+	var issuedAt, dateProblem, dateNote string
+	if extracted.ExampleDate == "" {
+		dateProblem = "no invoice date found"
+	} else if t, err := dp.Parse(extracted.ExampleDate); err != nil {
+		dateProblem = err.Error()
+	} else {
+		issued, issuedAt = t, t.Format(dates.ISO)
+		if amb := dp.Ambiguity(extracted.ExampleDate); amb != nil {
+			dateNote = amb.Error()
+			slog.Warn("Ambiguous bill date read in DATE_ORDER", "document_id", docID, "date", extracted.ExampleDate, "read_as", issuedAt)
+			if dry != nil {
+				dry.notef("bill date: %s", dateNote)
+			}
+		}
 	}
-
-	// Due date = issued + 30 days
-	dueAt := issuedAt
-	if t, err := time.Parse("2006-01-02", issuedAt); err == nil {
-		dueAt = t.AddDate(0, 0, 30).Format("2006-01-02")
+	if dateProblem != "" {
+		slog.Warn("Bill date left blank", "document_id", docID, "date", extracted.ExampleDate, "reason", dateProblem)
+		if dry != nil {
+			dry.notef("bill date left blank: %s", dateProblem)
+		}
 	}
+	dueAt, dueSource, dueProblems := s.billDueDate(docID, doc, extracted, issued, dry)
 
 	// Build amount in paise
-	amountFloat, _ := strconv.ParseFloat(extracted.TotalAmount, 64)
-	amountPaise := int(amountFloat * 100)
+	amountPaise := int(extracted.TotalAmount.Minor)
 	if amountPaise <= 0 {
-		slog.Warn("Skipping accounting bill: no valid amount", "document_id", docID, "raw_amount", extracted.TotalAmount)
-		return
+		rawAmount := extracted.Entities["total_amount"]
+		slog.Warn("Skipping accounting bill: no valid amount", "document_id", docID, "raw_amount", rawAmount)
+		if dry != nil {
+			dry.notef("no valid amount in %q; accounting bill would be skipped", rawAmount)
+		}
+		sum.Skipped = fmt.Sprintf("no valid amount in %q, accounting bill not created", rawAmount)
+		return nil
 	}
 
 	// Invoice / document number
@@ -422,6 +515,24 @@ func (s *Server) createLocalBill(docID int, extracted *docai.ExtractedData, doc
 		docNumber = val
 	}
 
+	notes := fmt.Sprintf("Auto-created from Paperless document #%d (%s)", docID, doc.OriginalFileName)
+	if dateProblem != "" {
+		notes += "\nDate check failed: " + dateProblem
+	}
+	if dateNote != "" {
+		notes += "\nDate check: " + dateNote
+	}
+	if dueSource != "" {
+		notes += "\nDue date from " + dueSource
+	}
+	for _, p := range dueProblems {
+		notes += "\nDue date check failed: " + p
+	}
+	gstDetail, gstProblems := s.billGST(docID, extracted, dry)
+	for _, p := range gstProblems {
+		notes += "\nGST check failed: " + p
+	}
+
 	billInput := accounting.BillInput{
 		ContactID:  &contactID,
 		BillNumber: docNumber,
@@ -430,16 +541,50 @@ func (s *Server) createLocalBill(docID int, extracted *docai.ExtractedData, doc
 		Amount:     amountPaise,
 		Status:     "draft",
 		FileURL:    req.DocURL,
-		Notes:      fmt.Sprintf("Auto-created from Paperless document #%d (%s)", docID, doc.OriginalFileName),
+		Notes:      notes,
+		LineItems:  billLineItems(items),
+		GST:        gstDetail,
+	}
+
+	if dry != nil {
+		dry.BillInput = &billInput
+		if req.UpdateAccounting {
+			if existingID, found, err := s.db.GetAccountingRecord(docID, storage.AccountingRecordBill); err == nil && found {
+				dry.notef("accounting bill %d would be updated", existingID)
+			}
+		}
+		return nil
 	}
 
-	billID, err := s.accountingClient.CreateBill(billInput)
+	if req.UpdateAccounting {
+		existingID, found, err := s.db.GetAccountingRecord(docID, storage.AccountingRecordBill)
+		if err != nil {
+			return err
+		}
+		if found {
+			if err := s.accountingClient.UpdateBill(s.ctx, existingID, billInput); err != nil {
+				slog.Error("Accounting bill update failed", "document_id", docID, "accounting_bill_id", existingID, "error", err)
+				return err
+			}
+			slog.Info("Local accounting bill updated", "document_id", docID, "accounting_bill_id", existingID)
+			sum.AccountingBillID, sum.Updated = existingID, true
+			return nil
+		}
+		slog.Info("No earlier accounting bill recorded, creating a new one", "document_id", docID)
+	}
+
+	billID, err := s.accountingClient.CreateBill(s.ctx, billInput)
 	if err != nil {
 		slog.Error("Accounting bill creation failed", "document_id", docID, "error", err)
-		return
+		return err
+	}
+	if err := s.db.SaveAccountingRecord(docID, storage.AccountingRecordBill, billID); err != nil {
+		slog.Warn("Failed to record accounting bill ID", "document_id", docID, "accounting_bill_id", billID, "error", err)
 	}
 
 	slog.Info("Local accounting bill created", "document_id", docID, "accounting_bill_id", billID)
+	sum.AccountingBillID = billID
+	return nil
 }
 
 func (s *Server) handlePayouts(w http.ResponseWriter, r *http.Request) {
@@ -448,196 +593,171 @@ func (s *Server) handlePayouts(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	var req PayoutRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		slog.Error("Failed to decode payout request", "error", err)
-		http.Error(w, "Invalid JSON body", http.StatusBadRequest)
+	p, ok := s.decodeWebhook(w, r, "payout")
+	if !ok {
 		return
 	}
+	docID := p.DocumentID
+	req := PayoutRequest{DocURL: p.DocURL, UpdateAccounting: p.UpdateAccounting, DryRun: p.DryRun}
 
-	// Extract ID from URL
-	trimmed := strings.TrimSuffix(req.DocURL, "/")
-	parts := strings.Split(trimmed, "/")
-	if len(parts) == 0 {
-		http.Error(w, "Invalid doc_url format", http.StatusBadRequest)
-		return
-	}
-	idStr := parts[len(parts)-1]
-	docID, err := strconv.Atoi(idStr)
-	if err != nil {
-		http.Error(w, "Invalid document ID in URL", http.StatusBadRequest)
+	if req.DryRun {
+		s.runDryRun(w, storage.JobKindPayout, docID, func(dry *dryRunResult) error {
+			return s.processPayout(0, docID, req, &jobSummary{}, dry)
+		})
 		return
 	}
-
-	slog.Info("Received payout request", "doc_url", req.DocURL, "document_id", docID)
-
-	go s.processPayout(docID, req)
-
-	w.WriteHeader(http.StatusOK)
-	w.Write([]byte("Payout processing started"))
+	s.enqueueJob(w, storage.JobKindPayout, docID, req)
 }
 
-func (s *Server) processPayout(docID int, req PayoutRequest) {
+// processPayout runs the payout pipeline, recording what it created in sum.
+// When dry is non-nil the spreadsheet is imported into a scratch in-memory
+// database and the resulting PayoutInput is recorded in dry instead of being
+// sent.
+func (s *Server) processPayout(jobID int64, docID int, req PayoutRequest, sum *jobSummary, dry *dryRunResult) error {
 	slog.Info("Starting payout processing", "document_id", docID)
 
-	// 1. if the document already processed, return no need to process again
-	if processed, err := s.db.IsDocumentProcessed(docID); err == nil && processed {
+	db := s.db
+	if dry != nil {
+		scratch, err := storage.InitDB("")
+		if err != nil {
+			return fmt.Errorf("failed to open dry run database: %w", err)
+		}
+		defer scratch.Close()
+		db = scratch
+	} else if processed, err := s.db.IsDocumentProcessed(docID); err == nil && processed {
+		// 1. if the document already processed, return no need to process again
 		slog.Warn("Document already processed, skipping it", "document_id", docID)
-		return
+		sum.Skipped = "document already processed"
+		return nil
 	}
 
 	// 1. Get Document (for tags)
-	doc, err := s.paperlessClient.GetDocument(docID)
+	s.setJobStep(jobID, storage.JobStepDownload)
+	doc, err := s.paperlessClient.GetDocument(s.ctx, docID)
 	if err != nil {
 		slog.Error("Error getting payout document", "document_id", docID, "error", err)
-		return
+		return fmt.Errorf("failed to get document: %w", err)
 	}
 
 	// 2. Get Metadata (for filename)
-	meta, err := s.paperlessClient.GetMetadata(docID)
+	meta, err := s.paperlessClient.GetMetadata(s.ctx, docID)
 	if err != nil {
 		slog.Error("Error getting payout metadata", "document_id", docID, "error", err)
-		return
-	}
-
-	// 3. Determine DuckDB Options based on Tags
-	var option config.PlatformConfig
-	var platform string
-	for name, id := range s.tagIDs {
-		for _, tagID := range doc.Tags {
-			if id == tagID {
-				if cfg, ok := s.duckDBConfigs[tagID]; ok {
-					option = cfg
-					platform = name
-					break
-				}
-			}
-		}
-		if platform != "" {
-			break
-		}
+		return fmt.Errorf("failed to get document metadata: %w", err)
 	}
 
 	// Try to get file path from mounted media volume for DuckDB ProcessPlatformExcel
 	filename := "documents/originals/" + meta.MediaFilename
 	filePath := fmt.Sprintf("/app/media/%s", filename)
 
-	if (strings.HasSuffix(strings.ToLower(filename), ".xlsx") || strings.HasSuffix(strings.ToLower(filename), ".xls")) && platform != "" {
-		if option.UseLibreOffice() {
-			if s.libreOfficeClient == nil || s.cfg == nil || s.cfg.LibreOfficeURL == "" {
-				slog.Error("LibreOffice import method requested but LIBREOFFICE_URL is not configured", "document_id", docID)
-				return
-			}
-			slog.Info("Excel file detected in payout, storing via LibreOffice parser", "path", filename, "platform", platform)
-
-			// resultRowCounts tracks the number of data rows loaded for each
-			// import config so that subsequent configs with RelativeRange can
-			// compute their start row correctly.
-			resultRowCounts := make([]int, len(option.ImportConfigs))
-
-			for i, importConfig := range option.ImportConfigs {
-				// Resolve relative range if configured.
-				if importConfig.RelativeRange.RelativeConfigIndex > 0 {
-					refIdx := importConfig.RelativeRange.RelativeConfigIndex
-					if refIdx >= i {
-						slog.Error("LibreOffice relative range: RelativeConfigIndex must reference an earlier config", "document_id", docID, "config_index", i, "relative_config_index", refIdx)
-						return
-					}
-					relativeOption := option.ImportConfigs[refIdx]
-					refRowCount := resultRowCounts[refIdx]
-					// Mirror the DuckDB GetRangeEnd row-count adjustment:
-					// decrement only when the referenced config explicitly sets
-					// header=false.
-					if relativeOption.Header != nil && !*relativeOption.Header {
-						refRowCount--
-					}
-					// If the referenced config had a footer row, that row is
-					// physically present in the spreadsheet even though it was
-					// stripped from the loaded data.  Add it back so the computed
-					// start row accounts for the full physical extent of the table.
-					if relativeOption.Footer != nil && *relativeOption.Footer {
-						refRowCount++
-					}
-					if refRowCount < 0 {
-						refRowCount = 0
-					}
-					refRange, err := excel.NewRange(relativeOption.Range)
-					if err != nil {
-						slog.Error("LibreOffice relative range: failed to parse referenced range", "document_id", docID, "range", relativeOption.Range, "error", err)
-						return
-					}
-					// Build the new range using the referenced config's columns
-					// but with a computed start row.
-					refRange.Start.Row = refRange.Start.Row + refRowCount + importConfig.RelativeRange.RowsOffset
-					importConfig.Range = refRange.String()
-					slog.Debug("LibreOffice relative range resolved", "config_index", i, "computed_range", importConfig.Range)
-				}
+	// 3. Determine DuckDB Options based on Tags, or the report's content
+	platform, option, ok := s.payoutPlatform(doc.Tags)
+	if !ok {
+		platform, option, err = s.detectPayoutPlatform(doc, meta, filePath, dry)
+		if err != nil {
+			return err
+		}
+	}
 
-				hasHeader := importConfig.Header == nil || *importConfig.Header
-				stopAtEmpty := importConfig.StopAtEmpty != nil && *importConfig.StopAtEmpty
-				result, err := s.libreOfficeClient.Parse(filename, importConfig.Sheet, importConfig.Range, hasHeader, stopAtEmpty)
-				if err != nil {
-					slog.Error("LibreOffice parse failed", "document_id", docID, "sheet", importConfig.Sheet, "error", err)
-					return
-				}
+	format, ok := payout.FormatOf(filename)
+	if !ok {
+		slog.Error("Unsupported payout report format", "document_id", docID, "filename", filename, "platform", platform)
+		return fmt.Errorf("unsupported payout report %q: expected .xlsx, .xls, .csv or .pdf", meta.OriginalFileName)
+	}
 
-				// Drop the last row when the import config declares a footer
-				// row (i.e. a totals/summary row appended by Excel).
-				if importConfig.Footer != nil && *importConfig.Footer && len(result.Rows) > 0 {
-					slog.Debug("LibreOffice footer row dropped", "table", importConfig.GetTableName(platform), "rows_before", len(result.Rows))
-					result.Rows = result.Rows[:len(result.Rows)-1]
-				}
+	// 4. Import the report and evaluate the export configs
+	s.setJobStep(jobID, storage.JobStepImport)
+	src := payout.Source{Path: filePath, MediaPath: filename}
+	backends := payout.Backends{
+		LibreOffice:      s.libreOfficeClient,
+		Tika:             s.tikaClient,
+		DocAI:            s.docAIClient,
+		DocAIProcessorID: s.cfg.PayoutProcessorID,
+	}
+	if err := payout.Import(s.ctx, db, backends, docID, platform, option, src); err != nil {
+		return err
+	}
 
-				resultRowCounts[i] = len(result.Rows)
+	payoutInput, err := payout.Export(db, docID, platform, option, s.cfg.DateParser())
+	if err != nil {
+		return err
+	}
 
-				tableName := importConfig.GetTableName(platform)
-				if err := s.db.LoadRowsIntoTable(docID, tableName, result); err != nil {
-					slog.Error("Failed to load LibreOffice rows into table", "document_id", docID, "table", tableName, "error", err)
-					return
-				}
-			}
-		} else {
-			slog.Info("Excel file detected in payout, storing via DuckDB", "path", filePath, "platform", platform, "options", option)
+	outlet, err := s.resolveOutlet(db, doc, option)
+	if err != nil {
+		slog.Error("Failed to resolve payout outlet", "document_id", docID, "error", err)
+		return err
+	}
+	payoutInput.OutletName = outlet
 
-			if err := s.db.ProcessPlatformExcel(docID, filePath, platform, option); err != nil {
-				slog.Error("DuckDB ProcessPlatformExcel failed", "document_id", docID, "error", err)
-				return
-			}
-		}
+	slog.Debug("Extracted payout data from DB", "document_id", docID, "payout_input", payoutInput.String())
+	if dry != nil {
+		dry.PayoutInput = &payoutInput
+	}
 
-		payoutInput, err := s.db.GetPlatformExcelRows(docID, platform, option)
-		if err != nil {
-			slog.Error("Failed to get excel rows", "document_id", docID, "error", err)
-			return
-		}
+	// Failed checks block the accounting post; the error lists the
+	// offending numbers for the failure note.
+	s.setJobStep(jobID, storage.JobStepChecks)
+	if err := payout.Check(db, docID, option, payoutInput); err != nil {
+		slog.Error("Payout checks failed", "document_id", docID, "platform", platform, "error", err)
+		return err
+	}
+
+	if dry != nil {
+		return nil
+	}
 
-		payoutInput.Platform = accounting.Platform(platform)
-		payoutInput.OutletName = "Noodle House"
+	// 5. Send to Accounting
+	s.setJobStep(jobID, storage.JobStepAccounting)
+	payoutID, updated, err := s.sendPayout(docID, payoutInput, req.UpdateAccounting)
+	if err != nil {
+		return err
+	}
+	sum.PayoutID, sum.Updated = payoutID, updated
 
-		// swiggy sends the amount as negative, so adding it
-		payoutInput.FinalPayoutAmt += payoutInput.MarketingAdsAmt
+	// 6. Save to processed documents
+	s.setJobStep(jobID, storage.JobStepDBSave)
+	processed := storage.ProcessedDocument{
+		PaperlessID: docID,
+		Filename:    filename,
+	}
+	if err := s.db.SaveDocument(&processed); err != nil {
+		return fmt.Errorf("payout %d created but document was not marked processed: %w", payoutID, err)
+	}
 
-		slog.Debug("Extracted payout data from DB", "document_id", docID, "payout_input", payoutInput.String())
+	slog.Info("Local accounting payout created", "document_id", docID, "payout_id", payoutID, "format", format)
+	return nil
+}
 
-		// 5. Send to Accounting
-		payoutID, err := s.accountingClient.CreatePayout(payoutInput)
+// sendPayout creates the payout in accounting, or updates the payout recorded
+// for docID when update is set and one exists.  It returns the payout ID and
+// whether an existing payout was updated.
+func (s *Server) sendPayout(docID int, payoutInput accounting.PayoutInput, update bool) (int, bool, error) {
+	if update {
+		existingID, found, err := s.db.GetAccountingRecord(docID, storage.AccountingRecordPayout)
 		if err != nil {
-			slog.Error("Accounting payout creation failed", "document_id", docID, "error", err)
-			return
+			return 0, false, err
 		}
-
-		// 6. Save to processed documents
-		doc := storage.ProcessedDocument{
-			PaperlessID: docID,
-			Filename:    filename,
+		if found {
+			if err := s.accountingClient.UpdatePayout(s.ctx, existingID, payoutInput); err != nil {
+				slog.Error("Accounting payout update failed", "document_id", docID, "payout_id", existingID, "error", err)
+				return 0, false, fmt.Errorf("failed to update accounting payout %d: %w", existingID, err)
+			}
+			slog.Info("Local accounting payout updated", "document_id", docID, "payout_id", existingID)
+			return existingID, true, nil
 		}
-		err = s.db.SaveDocument(&doc)
+		slog.Info("No earlier accounting payout recorded, creating a new one", "document_id", docID)
+	}
 
-		slog.Info("Local accounting payout created from Excel", "document_id", docID, "payout_id", payoutID)
-	} else {
-		// Payout with generic document (TIKA or DocAI)
-		// ... existing implementation if any ...
+	payoutID, err := s.accountingClient.CreatePayout(s.ctx, payoutInput)
+	if err != nil {
+		slog.Error("Accounting payout creation failed", "document_id", docID, "error", err)
+		return 0, false, fmt.Errorf("failed to create accounting payout: %w", err)
+	}
+	if err := s.db.SaveAccountingRecord(docID, storage.AccountingRecordPayout, payoutID); err != nil {
+		slog.Warn("Failed to record accounting payout ID", "document_id", docID, "payout_id", payoutID, "error", err)
 	}
+	return payoutID, false, nil
 }
 
 func (s *Server) handleBankStatements(w http.ResponseWriter, r *http.Request) {
@@ -646,53 +766,49 @@ func (s *Server) handleBankStatements(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	var req BankStatementRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		slog.Error("Failed to decode bank statement request", "error", err)
-		http.Error(w, "Invalid JSON body", http.StatusBadRequest)
+	p, ok := s.decodeWebhook(w, r, "bank statement")
+	if !ok {
 		return
 	}
-
-	// Extract ID from URL
-	trimmed := strings.TrimSuffix(req.DocURL, "/")
-	parts := strings.Split(trimmed, "/")
-	if len(parts) == 0 {
-		http.Error(w, "Invalid doc_url format", http.StatusBadRequest)
+	if p.UpdateAccounting {
+		http.Error(w, "update_accounting is only supported for bill and payout", http.StatusBadRequest)
 		return
 	}
-	idStr := parts[len(parts)-1]
-	docID, err := strconv.Atoi(idStr)
-	if err != nil {
-		http.Error(w, "Invalid document ID in URL", http.StatusBadRequest)
+	docID := p.DocumentID
+	req := BankStatementRequest{DocURL: p.DocURL, DryRun: p.DryRun}
+
+	if req.DryRun {
+		s.runDryRun(w, storage.JobKindBankStatement, docID, func(dry *dryRunResult) error {
+			return s.processBankStatement(0, docID, req, &jobSummary{}, dry)
+		})
 		return
 	}
-
-	slog.Info("Received bank statement request", "doc_url", req.DocURL, "document_id", docID)
-
-	go s.processBankStatement(docID, req)
-
-	w.WriteHeader(http.StatusOK)
-	w.Write([]byte("Bank statement processing started"))
+	s.enqueueJob(w, storage.JobKindBankStatement, docID, req)
 }
 
-func (s *Server) processBankStatement(docID int, req BankStatementRequest) {
+// processBankStatement runs the bank statement pipeline, recording what it
+// created in sum.  When dry is non-nil the transactions and Paperless update
+// are recorded in dry instead of being written.
+func (s *Server) processBankStatement(jobID int64, docID int, req BankStatementRequest, sum *jobSummary, dry *dryRunResult) error {
 	slog.Info("Starting bank statement processing", "document_id", docID)
 
 	// 1. Get Metadata & Content
-	content, err := s.paperlessClient.DownloadDocument(docID, false)
+	s.setJobStep(jobID, storage.JobStepDownload)
+	content, err := s.paperlessClient.DownloadDocument(s.ctx, docID, false)
 	if err != nil {
 		slog.Error("Error downloading bank statement", "document_id", docID, "error", err)
-		return
+		return fmt.Errorf("failed to download document: %w", err)
 	}
 
 	mtype := mimetype.Detect(content)
 	mimeType := mtype.String()
 
 	// 3. Process with DocAI (using BankStatementProcessorID)
-	aiDoc, err := s.docAIClient.ProcessDocument(context.Background(), s.cfg.BankStatementProcessorID, content, mimeType)
+	s.setJobStep(jobID, storage.JobStepDocAI)
+	aiDoc, err := s.docAIClient.ProcessDocument(s.ctx, s.cfg.BankStatementProcessorID, content, mimeType)
 	if err != nil {
 		slog.Error("DocAI bank statement error", "document_id", docID, "error", err)
-		return
+		return fmt.Errorf("docai processing failed: %w", err)
 	}
 
 	// 3a. Save to processed documents
@@ -714,10 +830,13 @@ func (s *Server) processBankStatement(docID int, req BankStatementRequest) {
 		ExtractedText: aiDoc.Text,
 	}
 
-	err = s.db.SaveDocument(doc)
-	if err != nil {
-		slog.Error("Failed to save document", "document_id", docID, "error", err)
-		return
+	if dry == nil {
+		s.setJobStep(jobID, storage.JobStepDBSave)
+		err = s.db.SaveDocument(doc)
+		if err != nil {
+			slog.Error("Failed to save document", "document_id", docID, "error", err)
+			return err
+		}
 	}
 
 	// 4. Extract Transactions
@@ -726,6 +845,7 @@ func (s *Server) processBankStatement(docID int, req BankStatementRequest) {
 
 	// 5. Send to Accounting
 	if s.accountingClient != nil && len(transactions) > 0 {
+		s.setJobStep(jobID, storage.JobStepAccounting)
 		// Resolve bank name from DocAI top-level entities (type = "bank_name")
 		bankName := "Bank"
 		for _, entity := range aiDoc.Entities {
@@ -742,13 +862,32 @@ func (s *Server) processBankStatement(docID int, req BankStatementRequest) {
 		}
 		slog.Info("Resolved bank name from DocAI", "bank_name", bankName)
 
-		bankAccountID, err := s.accountingClient.GetOrCreateBankAccount(bankName)
+		var bankAccountID int
+		if dry != nil {
+			var found bool
+			bankAccountID, found, err = s.accountingClient.FindBankAccount(s.ctx, bankName)
+			if err == nil && !found {
+				dry.notef("bank account %q would be created", bankName)
+			}
+		} else {
+			bankAccountID, err = s.accountingClient.GetOrCreateBankAccount(s.ctx, bankName)
+		}
 		if err != nil {
 			slog.Error("Failed to get/create bank account", "document_id", docID, "bank_name", bankName, "error", err)
 			// Continue without accounting — don't abort
+			sum.Skipped = fmt.Sprintf("bank account %q unavailable, no transactions created: %v", bankName, err)
 		} else {
 			for _, txMap := range transactions {
-				amount, _ := strconv.ParseFloat(txMap["amount"], 64)
+				amount, err := money.Parse(txMap["amount"])
+				if err != nil {
+					slog.Warn("Skipping transaction with unreadable amount", "document_id", docID, "amount", txMap["amount"], "error", err)
+					if dry != nil {
+						dry.notef("transaction with amount %q would be skipped", txMap["amount"])
+					} else {
+						sum.TransactionsFailed++
+					}
+					continue
+				}
 
 				// Map debit → expense, credit → income (accounting service expects income/expense)
 				txType := "expense"
@@ -756,9 +895,22 @@ func (s *Server) processBankStatement(docID int, req BankStatementRequest) {
 					txType = "income"
 				}
 
-				date := txMap["date"]
-				if date == "" {
-					date = time.Now().Format("2006-01-02")
+				dp := s.cfg.DateParser()
+				date, err := dp.Normalize(txMap["date"])
+				if err != nil {
+					slog.Warn("Skipping transaction with unreadable date", "document_id", docID, "date", txMap["date"], "amount", amount, "error", err)
+					if dry != nil {
+						dry.notef("transaction of %s would be skipped: %v", amount, err)
+					} else {
+						sum.TransactionsFailed++
+					}
+					continue
+				}
+				if amb := dp.Ambiguity(txMap["date"]); amb != nil {
+					slog.Warn("Ambiguous transaction date read in DATE_ORDER", "document_id", docID, "date", txMap["date"], "read_as", date, "amount", amount)
+					if dry != nil {
+						dry.notef("transaction of %s: %v", amount, amb)
+					}
 				}
 				desc := txMap["description"]
 
@@ -770,33 +922,36 @@ func (s *Server) processBankStatement(docID int, req BankStatementRequest) {
 					Description:     &desc,
 				}
 
-				txID, err := s.accountingClient.CreateTransaction(txnInput)
+				if dry != nil {
+					dry.Transactions = append(dry.Transactions, txnInput)
+					continue
+				}
+
+				txID, err := s.accountingClient.CreateTransaction(s.ctx, txnInput)
 				if err != nil {
 					slog.Error("Failed to create transaction", "document_id", docID, "error", err, "date", date, "amount", amount, "type", txType)
+					sum.TransactionsFailed++
 					continue
 				}
 				slog.Info("Transaction created", "document_id", docID, "transaction_id", txID, "type", txType, "amount", amount)
+				sum.Transactions++
 			}
 		}
 	}
 
 	// 6. Update Paperless
+	s.setJobStep(jobID, storage.JobStepPaperlessUpdate)
 	updates := paperless.DocumentUpdate{
 		Content: &aiDoc.Text,
 	}
-	if err := s.paperlessClient.UpdateDocument(docID, updates); err != nil {
+	if dry != nil {
+		dry.DocumentUpdate = &updates
+		return nil
+	}
+	if err := s.paperlessClient.UpdateDocument(s.ctx, docID, updates); err != nil {
 		slog.Warn("Failed to update paperless document content", "document_id", docID, "error", err)
 	}
 
 	slog.Info("Finished processing bank statement", "document_id", docID)
-}
-
-func (s *Server) parseAmount(val string) int {
-	// Clean currency symbols, commas, and Tika wrappers like [$₹]
-	val = strings.ReplaceAll(val, "₹", "")
-	val = strings.ReplaceAll(val, "[$₹]", "")
-	val = strings.ReplaceAll(val, ",", "")
-	val = strings.TrimSpace(val)
-	amtFloat, _ := strconv.ParseFloat(val, 64)
-	return int(amtFloat * 100)
+	return nil
 }
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"paperless-document-processor/config"
+	"paperless-document-processor/pkg/paperless"
+	"paperless-document-processor/pkg/storage"
+)
+
+// maxNoteError bounds how much of an error message is copied into a note;
+// accounting and Paperless errors can embed whole response bodies.
+const maxNoteError = 1000
+
+// jobSummary records what a pipeline run created, for the success note.
+type jobSummary struct {
+	AccountingBillID   int
+	PayoutID           int
+	Updated            bool // the bill or payout already existed and was updated
+	Transactions       int
+	TransactionsFailed int
+	Skipped            string // why a run finished without creating anything
+}
+
+// successNote describes a finished job for the bookkeeper.
+func successNote(job *storage.Job, sum *jobSummary) string {
+	verb := "created"
+	if sum.Updated {
+		verb = "updated"
+	}
+	var parts []string
+	if sum.AccountingBillID != 0 {
+		parts = append(parts, fmt.Sprintf("accounting bill #%d %s", sum.AccountingBillID, verb))
+	}
+	if sum.PayoutID != 0 {
+		parts = append(parts, fmt.Sprintf("payout #%d %s", sum.PayoutID, verb))
+	}
+	if job.Kind == storage.JobKindBankStatement && sum.Skipped == "" {
+		tx := fmt.Sprintf("%d transactions created", sum.Transactions)
+		if sum.TransactionsFailed > 0 {
+			tx += fmt.Sprintf(", %d failed", sum.TransactionsFailed)
+		}
+		parts = append(parts, tx)
+	}
+	if sum.Skipped != "" {
+		parts = append(parts, sum.Skipped)
+	}
+	if len(parts) == 0 {
+		parts = append(parts, "document updated")
+	}
+	return fmt.Sprintf("Processed as %s (job %d): %s.", strings.ReplaceAll(string(job.Kind), "_", " "), job.ID, strings.Join(parts, "; "))
+}
+
+// failureNote reports the failing step and error of a job.
+func failureNote(job *storage.Job, state storage.JobState, step storage.JobStep, err error) string {
+	msg := err.Error()
+	if r := []rune(msg); len(r) > maxNoteError {
+		msg = string(r[:maxNoteError]) + "…"
+	}
+	if step == "" {
+		step = "start"
+	}
+	status := "failed"
+	if state == storage.JobStateDeadLetter {
+		status = "failed after retries (dead letter)"
+	}
+	return fmt.Sprintf("Processing as %s %s (job %d, attempt %d) at step %s: %s",
+		strings.ReplaceAll(string(job.Kind), "_", " "), status, job.ID, job.Attempts, step, msg)
+}
+
+// reportOutcome makes a finished job visible in Paperless: it posts a note
+// (when enabled) and applies the kind's configured tag transition.  Failures
+// here are logged only; the job result is already recorded.
+func (s *Server) reportOutcome(job *storage.Job, state storage.JobState, sum *jobSummary, jobErr error) {
+	if s.cfg.PaperlessNotes {
+		var note string
+		if jobErr == nil {
+			note = successNote(job, sum)
+		} else {
+			// The step was recorded by the pipeline after the job was
+			// claimed, so re-read it.
+			step := job.Step
+			if current, err := s.db.GetJob(job.ID); err == nil && current != nil {
+				step = current.Step
+			}
+			note = failureNote(job, state, step, jobErr)
+		}
+		if err := s.paperlessClient.AddNote(s.ctx, job.PaperlessID, note); err != nil {
+			slog.Error("Failed to add job note", "job_id", job.ID, "document_id", job.PaperlessID, "error", err)
+		}
+	}
+
+	transitions := s.tagTransitions(job.Kind)
+	transition := transitions.Failure
+	if state == storage.JobStateSucceeded {
+		transition = transitions.Success
+	}
+	if transition.Empty() {
+		return
+	}
+
+	add, err := s.resolveTagIDs(transition.Add)
+	if err != nil {
+		slog.Warn("Some tags to add are unknown", "job_id", job.ID, "document_id", job.PaperlessID, "error", err)
+	}
+	remove, err := s.resolveTagIDs(transition.Remove)
+	if err != nil {
+		slog.Warn("Some tags to remove are unknown", "job_id", job.ID, "document_id", job.PaperlessID, "error", err)
+	}
+	if len(add) == 0 && len(remove) == 0 {
+		return
+	}
+
+	update := paperless.DocumentUpdate{AddTags: add, RemoveTags: remove}
+	if err := s.paperlessClient.UpdateDocument(s.ctx, job.PaperlessID, update); err != nil {
+		slog.Error("Failed to apply tag transition", "job_id", job.ID, "document_id", job.PaperlessID, "state", state, "error", err)
+		return
+	}
+	slog.Info("Applied tag transition", "job_id", job.ID, "document_id", job.PaperlessID, "state", state, "add", transition.Add, "remove", transition.Remove)
+}
+
+func (s *Server) tagTransitions(kind storage.JobKind) config.TagTransitions {
+	switch kind {
+	case storage.JobKindBill:
+		return s.cfg.BillTags
+	case storage.JobKindPayout:
+		return s.cfg.PayoutTags
+	case storage.JobKindBankStatement:
+		return s.cfg.BankStatementTags
+	}
+	return config.TagTransitions{}
+}
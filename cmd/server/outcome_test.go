@@ -0,0 +1,55 @@
+package main
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"paperless-document-processor/pkg/storage"
+)
+
+func TestSuccessNote(t *testing.T) {
+	cases := []struct {
+		name string
+		kind storage.JobKind
+		sum  jobSummary
+		want string
+	}{
+		{"bill", storage.JobKindBill, jobSummary{AccountingBillID: 45}, "Processed as bill (job 7): accounting bill #45 created."},
+		{"bill updated", storage.JobKindBill, jobSummary{AccountingBillID: 45, Updated: true}, "Processed as bill (job 7): accounting bill #45 updated."},
+		{"bill without accounting", storage.JobKindBill, jobSummary{}, "Processed as bill (job 7): document updated."},
+		{"payout", storage.JobKindPayout, jobSummary{PayoutID: 12}, "Processed as payout (job 7): payout #12 created."},
+		{"payout skipped", storage.JobKindPayout, jobSummary{Skipped: "document already processed"}, "Processed as payout (job 7): document already processed."},
+		{"bank statement", storage.JobKindBankStatement, jobSummary{Transactions: 14, TransactionsFailed: 2}, "Processed as bank statement (job 7): 14 transactions created, 2 failed."},
+	}
+	for _, tc := range cases {
+		got := successNote(&storage.Job{ID: 7, Kind: tc.kind}, &tc.sum)
+		if got != tc.want {
+			t.Errorf("%s: got %q, want %q", tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestFailureNote(t *testing.T) {
+	job := &storage.Job{ID: 9, Kind: storage.JobKindBill, Attempts: 1}
+	got := failureNote(job, storage.JobStateFailed, storage.JobStepAccounting, errors.New("failed to create accounting bill: status 422"))
+	want := "Processing as bill failed (job 9, attempt 1) at step accounting: failed to create accounting bill: status 422"
+	if got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+
+	got = failureNote(job, storage.JobStateDeadLetter, storage.JobStepDocAI, errors.New(strings.Repeat("x", 2*maxNoteError)))
+	if !strings.Contains(got, "dead letter") || !strings.Contains(got, "at step docai") {
+		t.Errorf("unexpected dead letter note: %q", got[:100])
+	}
+	if len(got) > maxNoteError+200 {
+		t.Errorf("expected long error to be truncated, note is %d bytes", len(got))
+	}
+
+	// Multi-byte errors are cut between runes, not inside one.
+	got = failureNote(job, storage.JobStateFailed, storage.JobStepImport, errors.New(strings.Repeat("₹", 2*maxNoteError)))
+	if !utf8.ValidString(got) || !strings.HasSuffix(got, strings.Repeat("₹", maxNoteError)+"…") {
+		t.Errorf("expected the note to end in %d whole runes and an ellipsis", maxNoteError)
+	}
+}
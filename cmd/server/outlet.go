@@ -0,0 +1,99 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"paperless-document-processor/config"
+	"paperless-document-processor/pkg/paperless"
+	"paperless-document-processor/pkg/payout"
+	"paperless-document-processor/pkg/storage"
+)
+
+// legacyOutlet is the outlet every payout was booked to before the payout
+// config had an outlets section.  Configs without one keep using it, with a
+// warning per payout so the fallback is not silent.
+const legacyOutlet = "Noodle House"
+
+// resolveOutlet picks the outlet a payout is booked to, trying the payout
+// config's outlet sources in order and falling back to its default.
+func (s *Server) resolveOutlet(db *storage.DB, doc *paperless.Document, option config.PlatformConfig) (string, error) {
+	s.cacheMu.RLock()
+	outlets := s.outlets
+	s.cacheMu.RUnlock()
+
+	if len(outlets.Sources) == 0 && outlets.Default == "" {
+		slog.Warn("Payout config has no outlets section, booking the payout to the legacy outlet", "document_id", doc.ID, "outlet", legacyOutlet)
+		return legacyOutlet, nil
+	}
+
+	for _, src := range outlets.Sources {
+		outlet, err := s.outletFromSource(db, doc, option, outlets, src)
+		if err != nil {
+			return "", fmt.Errorf("outlet source %s: %w", src.Type, err)
+		}
+		if outlet != "" {
+			slog.Info("Resolved payout outlet", "document_id", doc.ID, "source", src.Type, "outlet", outlet)
+			return outlet, nil
+		}
+	}
+	if outlets.Default != "" {
+		slog.Warn("No outlet source matched, booking the payout to the default outlet", "document_id", doc.ID, "outlet", outlets.Default)
+		return outlets.Default, nil
+	}
+	return "", fmt.Errorf("no outlet resolved for document %d; set outlets.sources or outlets.default in the payout config", doc.ID)
+}
+
+// outletFromSource returns the outlet a single source yields, or "" when the
+// document carries nothing for it.
+func (s *Server) outletFromSource(db *storage.DB, doc *paperless.Document, option config.PlatformConfig, outlets config.OutletConfig, src config.OutletSource) (string, error) {
+	var value string
+	switch src.Type {
+	case config.OutletSourceTag:
+		var matches []string
+		for _, name := range s.tagNames(doc.Tags) {
+			if len(name) > len(src.TagPrefix) && strings.EqualFold(name[:len(src.TagPrefix)], src.TagPrefix) {
+				matches = append(matches, name[len(src.TagPrefix):])
+			}
+		}
+		if len(matches) > 1 {
+			return "", fmt.Errorf("document has several outlet tags: %s", strings.Join(matches, ", "))
+		}
+		if len(matches) == 1 {
+			value = matches[0]
+		}
+
+	case config.OutletSourceCustomField:
+		field, ok := s.customField(src.Field)
+		if !ok {
+			return "", fmt.Errorf("custom field %q not found in Paperless", src.Field)
+		}
+		for _, cf := range doc.CustomFields {
+			if cf.Field == field.ID && cf.Value != nil {
+				value = fmt.Sprint(cf.Value)
+			}
+		}
+
+	case config.OutletSourceStoragePath:
+		if doc.StoragePath == nil {
+			return "", nil
+		}
+		sp, err := s.paperlessClient.GetStoragePath(s.ctx, *doc.StoragePath)
+		if err != nil {
+			return "", fmt.Errorf("failed to get storage path: %w", err)
+		}
+		value = sp.Name
+
+	case config.OutletSourceExport:
+		// Restaurant IDs are mapped by the platform's own table.
+		outlet, _, err := payout.Outlet(db, doc.ID, option)
+		return outlet, err
+	}
+
+	value = strings.TrimSpace(value)
+	if mapped, ok := outlets.Names[value]; ok {
+		return mapped, nil
+	}
+	return value, nil
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"paperless-document-processor/config"
+	"paperless-document-processor/pkg/paperless"
+)
+
+func TestResolveOutlet(t *testing.T) {
+	s := &Server{
+		tagIDs:       map[string]int{"swiggy": 1, "Outlet:Indiranagar": 2, "outlet:Koramangala": 3},
+		customFields: map[string]paperless.CustomField{"Outlet": {ID: 7, Name: "Outlet"}},
+		outlets: config.OutletConfig{
+			Sources: []config.OutletSource{
+				{Type: config.OutletSourceTag, TagPrefix: "outlet:"},
+				{Type: config.OutletSourceCustomField, Field: "Outlet"},
+			},
+			Names:   map[string]string{"Indiranagar": "Noodle House Indiranagar"},
+			Default: "Noodle House",
+		},
+	}
+
+	cases := []struct {
+		name string
+		doc  paperless.Document
+		want string
+	}{
+		{"tag mapped through names", paperless.Document{Tags: []int{1, 2}}, "Noodle House Indiranagar"},
+		{"tag used as is", paperless.Document{Tags: []int{3}}, "Koramangala"},
+		{"custom field", paperless.Document{Tags: []int{1}, CustomFields: []paperless.CustomFieldInstance{{Field: 7, Value: "HSR"}}}, "HSR"},
+		{"default", paperless.Document{Tags: []int{1}}, "Noodle House"},
+	}
+	for _, tc := range cases {
+		got, err := s.resolveOutlet(nil, &tc.doc, config.PlatformConfig{})
+		if err != nil || got != tc.want {
+			t.Errorf("%s: got (%q, %v), want %q", tc.name, got, err, tc.want)
+		}
+	}
+
+	if _, err := s.resolveOutlet(nil, &paperless.Document{Tags: []int{2, 3}}, config.PlatformConfig{}); err == nil || !strings.Contains(err.Error(), "several outlet tags") {
+		t.Errorf("expected an error for two outlet tags, got %v", err)
+	}
+
+	s.outlets.Default = ""
+	if _, err := s.resolveOutlet(nil, &paperless.Document{ID: 5, Tags: []int{1}}, config.PlatformConfig{}); err == nil {
+		t.Errorf("expected an error when nothing resolves and there is no default")
+	}
+
+	// Configs written before the outlets section keep the old outlet.
+	s.outlets = config.OutletConfig{}
+	if got, err := s.resolveOutlet(nil, &paperless.Document{ID: 6, Tags: []int{1}}, config.PlatformConfig{}); err != nil || got != legacyOutlet {
+		t.Errorf("without an outlets section: got (%q, %v), want %q", got, err, legacyOutlet)
+	}
+}
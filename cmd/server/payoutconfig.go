@@ -0,0 +1,119 @@
+package main
+
+import (
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"os/signal"
+	"sort"
+	"syscall"
+	"time"
+
+	"paperless-document-processor/config"
+)
+
+// maxPayoutConfigBody bounds the candidate config accepted by the validate
+// endpoint.
+const maxPayoutConfigBody = 1 << 20
+
+// reloadPayoutConfigs re-reads the payout config file.  An invalid file is
+// logged and the running configuration is kept.
+func (s *Server) reloadPayoutConfigs(path string) error {
+	pc, err := config.LoadPayoutConfigs(path)
+	if err != nil {
+		slog.Error("Payout config reload rejected, keeping the current configuration", "path", path, "error", err)
+		return err
+	}
+	s.setPayoutConfigs(pc)
+	slog.Info("Reloaded payout configuration", "path", path, "platforms", len(pc.Platforms))
+	return nil
+}
+
+// watchPayoutConfigs reloads the payout config on SIGHUP and, when interval
+// is positive, whenever the file's modification time changes.
+func (s *Server) watchPayoutConfigs(path string, interval time.Duration) {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+
+	var tick <-chan time.Time
+	if interval > 0 {
+		tick = time.NewTicker(interval).C
+	}
+	slog.Info("Watching payout configuration", "path", path, "interval", interval)
+
+	go func() {
+		modTime := fileModTime(path)
+		for {
+			select {
+			case <-hup:
+				slog.Info("SIGHUP received, reloading payout configuration", "path", path)
+				s.reloadPayoutConfigs(path)
+				modTime = fileModTime(path)
+			case <-tick:
+				// A rejected file is not retried until it changes again.
+				mt := fileModTime(path)
+				if mt.IsZero() || mt.Equal(modTime) {
+					continue
+				}
+				modTime = mt
+				s.reloadPayoutConfigs(path)
+			}
+		}
+	}()
+}
+
+// fileModTime returns the modification time of path, or the zero time if it
+// cannot be read.
+func fileModTime(path string) time.Time {
+	info, err := os.Stat(path)
+	if err != nil {
+		return time.Time{}
+	}
+	return info.ModTime()
+}
+
+// payoutConfigValidation is the response of the validate endpoint.
+type payoutConfigValidation struct {
+	Valid     bool     `json:"valid"`
+	Platforms []string `json:"platforms,omitempty"`
+	// Unresolved lists platforms with no Paperless tag of the same name;
+	// they are valid but match no documents until the tag exists.
+	Unresolved []string `json:"unresolved_platforms,omitempty"`
+	Errors     []string `json:"errors,omitempty"`
+}
+
+// handleValidatePayoutConfigs checks a candidate payout config sent as the
+// request body without applying it.
+func (s *Server) handleValidatePayoutConfigs(w http.ResponseWriter, r *http.Request) {
+	data, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxPayoutConfigBody))
+	if err != nil {
+		http.Error(w, "Failed to read request body", http.StatusBadRequest)
+		return
+	}
+
+	pc, err := config.ParsePayoutConfigs(data)
+	if err != nil {
+		res := payoutConfigValidation{}
+		var cfgErr *config.PayoutConfigError
+		if errors.As(err, &cfgErr) {
+			res.Errors = cfgErr.Problems
+		} else {
+			res.Errors = []string{err.Error()}
+		}
+		writeJSON(w, http.StatusUnprocessableEntity, res)
+		return
+	}
+
+	res := payoutConfigValidation{Valid: true}
+	for platform := range pc.Platforms {
+		res.Platforms = append(res.Platforms, platform)
+		if _, ok := s.tagID(platform); !ok {
+			res.Unresolved = append(res.Unresolved, platform)
+		}
+	}
+	sort.Strings(res.Platforms)
+	sort.Strings(res.Unresolved)
+	writeJSON(w, http.StatusOK, res)
+}
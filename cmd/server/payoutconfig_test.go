@@ -0,0 +1,40 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestHandleValidatePayoutConfigs(t *testing.T) {
+	s := &Server{tagIDs: map[string]int{"Swiggy": 3}}
+
+	valid := `{"platforms": {
+		"swiggy": {"import_configs": [{"table_name": "t", "range": "A1:B"}], "export_configs": [{"table_name": "t", "reader_configs": [{"column_name": "TotalOrders", "expression": "COUNT(1)"}]}]},
+		"zomato": {"import_configs": [{"sheet": "Payout Breakup"}]}
+	}}`
+	rec := httptest.NewRecorder()
+	s.handleValidatePayoutConfigs(rec, httptest.NewRequest("POST", "/admin/payout-configs/validate", strings.NewReader(valid)))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body)
+	}
+	var res payoutConfigValidation
+	json.NewDecoder(rec.Body).Decode(&res)
+	if !res.Valid || len(res.Platforms) != 2 || len(res.Unresolved) != 1 || res.Unresolved[0] != "zomato" {
+		t.Errorf("unexpected validation result: %+v", res)
+	}
+
+	invalid := `{"platforms": {"swiggy": {"import_configs": [{"range": "A1"}, {"relative_range": {"relative_config_index": 1}}]}}}`
+	rec = httptest.NewRecorder()
+	s.handleValidatePayoutConfigs(rec, httptest.NewRequest("POST", "/admin/payout-configs/validate", strings.NewReader(invalid)))
+	if rec.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("expected 422, got %d: %s", rec.Code, rec.Body)
+	}
+	res = payoutConfigValidation{}
+	json.NewDecoder(rec.Body).Decode(&res)
+	if res.Valid || len(res.Errors) != 2 {
+		t.Errorf("expected two errors, got %+v", res)
+	}
+}
@@ -0,0 +1,192 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"paperless-document-processor/pkg/paperless"
+	"paperless-document-processor/pkg/storage"
+)
+
+// pollTarget pairs a pipeline with the Paperless tags that trigger it.
+type pollTarget struct {
+	kind storage.JobKind
+	tags []string
+}
+
+// pollTargets lists the kinds that have trigger tags configured.  Payouts and
+// bank statements are skipped without an accounting client, as their
+// webhook routes are.
+func (s *Server) pollTargets() []pollTarget {
+	var targets []pollTarget
+	for _, t := range []pollTarget{
+		{storage.JobKindBill, s.cfg.PollBillTags},
+		{storage.JobKindPayout, s.cfg.PollPayoutTags},
+		{storage.JobKindBankStatement, s.cfg.PollBankStatementTags},
+	} {
+		if len(t.tags) == 0 {
+			continue
+		}
+		if t.kind != storage.JobKindBill && s.accountingClient == nil {
+			slog.Warn("Polling for kind disabled: accounting integration disabled", "kind", t.kind)
+			continue
+		}
+		targets = append(targets, t)
+	}
+	return targets
+}
+
+// startPoller polls Paperless every interval for documents carrying the
+// configured trigger tags and enqueues the ones no job exists for, until the
+// server shuts down.
+func (s *Server) startPoller(interval time.Duration) {
+	targets := s.pollTargets()
+	if len(targets) == 0 {
+		return
+	}
+	slog.Info("Started document poller", "interval", interval, "kinds", len(targets))
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			for _, t := range targets {
+				if err := s.pollKind(t); err != nil {
+					slog.Error("Polling failed", "kind", t.kind, "error", err)
+				}
+			}
+			select {
+			case <-s.ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// pollKind enqueues documents modified after the kind's high-water mark and
+// advances the mark past every document it handled.  The modified time is
+// used rather than the added time because tagging a document modifies it, so
+// documents tagged long after they were added are still found.  Documents
+// that already have a job or were processed are skipped by enqueuePolled.  On
+// an enqueue failure the mark stops before that document so the next poll
+// retries it.
+func (s *Server) pollKind(t pollTarget) error {
+	tagIDs, err := s.resolveTagIDs(t.tags)
+	if err != nil {
+		return err
+	}
+
+	mark, found, err := s.db.GetPollMark(t.kind)
+	if err != nil {
+		return err
+	}
+	if !found {
+		mark = s.cfg.PollSince
+		if mark.IsZero() {
+			mark = time.Now().UTC()
+		}
+		slog.Info("No poll mark stored, starting from", "kind", t.kind, "since", mark)
+		if err := s.db.SetPollMark(t.kind, mark); err != nil {
+			return err
+		}
+	}
+
+	docs, err := s.paperlessClient.ListDocuments(s.ctx, paperless.DocumentQuery{
+		TagsAll:       tagIDs,
+		ModifiedAfter: mark,
+		Ordering:      "modified",
+		PageSize:      100,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to list documents: %w", err)
+	}
+
+	newMark := mark
+	enqueued := 0
+	for _, doc := range docs {
+		modified, err := time.Parse(time.RFC3339Nano, doc.Modified)
+		if err != nil {
+			slog.Warn("Skipping polled document with unparseable modified time", "document_id", doc.ID, "modified", doc.Modified)
+			continue
+		}
+
+		queued, err := s.enqueuePolled(t.kind, doc.ID)
+		if err != nil {
+			slog.Error("Failed to enqueue polled document", "kind", t.kind, "document_id", doc.ID, "error", err)
+			break
+		}
+		if queued {
+			enqueued++
+		}
+		if modified.After(newMark) {
+			newMark = modified
+		}
+	}
+
+	if newMark.After(mark) {
+		if err := s.db.SetPollMark(t.kind, newMark); err != nil {
+			return err
+		}
+	}
+	slog.Info("Poll complete", "kind", t.kind, "found", len(docs), "enqueued", enqueued, "high_water", newMark)
+	return nil
+}
+
+// enqueuePolled queues docID for kind unless a job for it already exists
+// (typically from a webhook) or it was processed before jobs were tracked.
+func (s *Server) enqueuePolled(kind storage.JobKind, docID int) (bool, error) {
+	jobs, err := s.db.ListJobs(storage.JobFilter{PaperlessID: docID, Kind: kind, Limit: 1})
+	if err != nil {
+		return false, err
+	}
+	if len(jobs) > 0 {
+		slog.Debug("Polled document already has a job", "kind", kind, "document_id", docID, "job_id", jobs[0].ID)
+		return false, nil
+	}
+	processed, err := s.db.IsDocumentProcessed(docID)
+	if err != nil {
+		return false, err
+	}
+	if processed {
+		slog.Debug("Polled document already processed", "kind", kind, "document_id", docID)
+		return false, nil
+	}
+
+	docURL := s.documentURL(docID)
+	var req interface{}
+	switch kind {
+	case storage.JobKindBill:
+		req = BillRequest{DocURL: docURL}
+	case storage.JobKindPayout:
+		req = PayoutRequest{DocURL: docURL}
+	case storage.JobKindBankStatement:
+		req = BankStatementRequest{DocURL: docURL}
+	}
+	if _, err := s.submitJob(kind, docID, req); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// resolveTagIDs maps tag names to Paperless IDs, matching case-insensitively.
+// When some names are unknown it returns the IDs it did find together with
+// an error naming the missing ones.
+func (s *Server) resolveTagIDs(names []string) ([]int, error) {
+	ids := make([]int, 0, len(names))
+	var missing []string
+	for _, name := range names {
+		id, ok := s.tagID(name)
+		if !ok {
+			missing = append(missing, name)
+			continue
+		}
+		ids = append(ids, id)
+	}
+	if len(missing) > 0 {
+		return ids, fmt.Errorf("tags not found in Paperless: %s", strings.Join(missing, ", "))
+	}
+	return ids, nil
+}
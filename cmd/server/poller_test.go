@@ -0,0 +1,86 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"paperless-document-processor/config"
+	"paperless-document-processor/pkg/paperless"
+	"paperless-document-processor/pkg/storage"
+)
+
+func TestPollTargetsNeedAccountingForPayouts(t *testing.T) {
+	s := &Server{cfg: &config.Config{
+		PollBillTags:   []string{"inbox"},
+		PollPayoutTags: []string{"payout"},
+	}}
+	targets := s.pollTargets()
+	if len(targets) != 1 || targets[0].kind != storage.JobKindBill {
+		t.Errorf("targets = %+v, want only bills without an accounting client", targets)
+	}
+}
+
+func TestPollKind(t *testing.T) {
+	db, err := storage.InitDB("")
+	if err != nil {
+		t.Skipf("DuckDB unavailable: %v", err)
+	}
+	defer db.Close()
+
+	mark := time.Date(2025, 3, 1, 9, 0, 0, 0, time.UTC)
+	at := func(d time.Duration) string { return mark.Add(d).Format(time.RFC3339Nano) }
+	docs := []paperless.Document{
+		// Added long before the mark but tagged (and so modified) after it.
+		{ID: 1, Added: at(-30 * 24 * time.Hour), Modified: at(time.Hour)},
+		// Already queued by a webhook.
+		{ID: 2, Added: at(time.Hour), Modified: at(2 * time.Hour)},
+		// Processed before jobs were tracked.
+		{ID: 3, Added: at(-time.Hour), Modified: at(30 * time.Minute)},
+	}
+	var query string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/documents/" {
+			t.Errorf("Unexpected request: %s %s", r.Method, r.URL.Path)
+			return
+		}
+		query = r.URL.RawQuery
+		json.NewEncoder(w).Encode(paperless.PaginatedResponse[paperless.Document]{Count: len(docs), Results: docs})
+	}))
+	defer server.Close()
+
+	if _, err := db.EnqueueJob(storage.JobKindBill, 2, "{}"); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.SaveDocument(&storage.ProcessedDocument{PaperlessID: 3, Filename: "old.pdf"}); err != nil {
+		t.Fatal(err)
+	}
+
+	s := &Server{
+		ctx:             context.Background(),
+		cfg:             &config.Config{PaperlessURL: "http://paperless", PollSince: mark},
+		db:              db,
+		paperlessClient: paperless.NewClient(server.URL, "tok"),
+		tagIDs:          map[string]int{"Inbox": 5},
+	}
+	if err := s.pollKind(pollTarget{storage.JobKindBill, []string{"inbox"}}); err != nil {
+		t.Fatalf("pollKind: %v", err)
+	}
+
+	want := "modified__gt=2025-03-01T09%3A00%3A00Z&ordering=modified&page_size=100&tags__id__all=5"
+	if query != want {
+		t.Errorf("query = %s, want %s", query, want)
+	}
+	for docID, count := range map[int]int{1: 1, 2: 1, 3: 0} {
+		jobs, err := db.ListJobs(storage.JobFilter{PaperlessID: docID})
+		if err != nil || len(jobs) != count {
+			t.Errorf("document %d has %d jobs (%v), want %d", docID, len(jobs), err, count)
+		}
+	}
+	if got, _, err := db.GetPollMark(storage.JobKindBill); err != nil || !got.Equal(mark.Add(2*time.Hour)) {
+		t.Errorf("poll mark = %s, %v; want %s", got, err, mark.Add(2*time.Hour))
+	}
+}
@@ -0,0 +1,177 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"paperless-document-processor/pkg/payout"
+	"paperless-document-processor/pkg/storage"
+)
+
+// reprocessParams are the query parameters handleReprocess accepts.
+var reprocessParams = map[string]bool{"kind": true, "force": true, "update_accounting": true, "dry_run": true}
+
+// handleReprocess serves POST /documents/{id}/reprocess.
+//
+// Query parameters:
+//   - kind (required): bill, payout or bank_statement
+//   - force: purge the document's stored rows (processed_documents and, for
+//     payouts, every platform table) and run again even if it was processed.
+//     Not accepted for bank_statement: the accounting service cannot match
+//     transactions created by the earlier run, so every one would be created
+//     again.
+//   - update_accounting: update the accounting bill/payout created by the
+//     earlier run instead of creating a duplicate
+//   - dry_run: run the pipeline without writing anything, as the processing
+//     routes do, and return what would be written; nothing is purged or
+//     queued, so force has no effect
+//
+// Any other parameter is rejected.
+func (s *Server) handleReprocess(w http.ResponseWriter, r *http.Request) {
+	docID, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid document ID", http.StatusBadRequest)
+		return
+	}
+
+	q := r.URL.Query()
+	for name := range q {
+		if !reprocessParams[name] {
+			http.Error(w, fmt.Sprintf("Unknown query parameter %q", name), http.StatusBadRequest)
+			return
+		}
+	}
+	kind := storage.JobKind(q.Get("kind"))
+	switch kind {
+	case storage.JobKindBill, storage.JobKindPayout, storage.JobKindBankStatement:
+	default:
+		http.Error(w, "kind must be one of bill, payout, bank_statement", http.StatusBadRequest)
+		return
+	}
+	force, err := parseBoolParam(q.Get("force"))
+	if err != nil {
+		http.Error(w, "force must be true or false", http.StatusBadRequest)
+		return
+	}
+	updateAccounting, err := parseBoolParam(q.Get("update_accounting"))
+	if err != nil {
+		http.Error(w, "update_accounting must be true or false", http.StatusBadRequest)
+		return
+	}
+	dryRun, err := parseBoolParam(q.Get("dry_run"))
+	if err != nil {
+		http.Error(w, "dry_run must be true or false", http.StatusBadRequest)
+		return
+	}
+	if kind == storage.JobKindBankStatement {
+		if updateAccounting {
+			http.Error(w, "update_accounting is only supported for bill and payout", http.StatusBadRequest)
+			return
+		}
+		if force {
+			http.Error(w, "force is not supported for bank_statement: it would create every transaction again", http.StatusBadRequest)
+			return
+		}
+	}
+	if kind != storage.JobKindBill && s.accountingClient == nil {
+		http.Error(w, "Accounting integration disabled", http.StatusServiceUnavailable)
+		return
+	}
+
+	docURL := s.lastDocURL(docID, kind)
+	var req interface{}
+	switch kind {
+	case storage.JobKindBill:
+		req = BillRequest{DocURL: docURL, UpdateAccounting: updateAccounting, DryRun: dryRun}
+	case storage.JobKindPayout:
+		req = PayoutRequest{DocURL: docURL, UpdateAccounting: updateAccounting, DryRun: dryRun}
+	case storage.JobKindBankStatement:
+		req = BankStatementRequest{DocURL: docURL, DryRun: dryRun}
+	}
+	if dryRun {
+		s.runDryRun(w, kind, docID, func(dry *dryRunResult) error {
+			switch req := req.(type) {
+			case BillRequest:
+				return s.processBill(0, docID, req, &jobSummary{}, dry)
+			case PayoutRequest:
+				return s.processPayout(0, docID, req, &jobSummary{}, dry)
+			case BankStatementRequest:
+				return s.processBankStatement(0, docID, req, &jobSummary{}, dry)
+			}
+			return fmt.Errorf("unsupported job kind %q", kind)
+		})
+		return
+	}
+	payload, err := json.Marshal(req)
+	if err != nil {
+		slog.Error("Failed to encode job payload", "document_id", docID, "error", err)
+		http.Error(w, "Failed to enqueue job", http.StatusInternalServerError)
+		return
+	}
+	var tables []string
+	if force && kind == storage.JobKindPayout {
+		tables = s.platformTables()
+	}
+
+	slog.Info("Received reprocess request", "document_id", docID, "kind", kind, "force", force, "update_accounting", updateAccounting)
+	jobID, err := s.db.EnqueueReprocessJob(kind, docID, string(payload), force, tables)
+	switch {
+	case errors.Is(err, storage.ErrJobActive):
+		http.Error(w, "Document has a queued or running job", http.StatusConflict)
+		return
+	case errors.Is(err, storage.ErrDocumentProcessed):
+		http.Error(w, "Document already processed; pass force=true to purge and reprocess", http.StatusConflict)
+		return
+	case err != nil:
+		slog.Error("Failed to enqueue reprocess job", "kind", kind, "document_id", docID, "error", err)
+		http.Error(w, "Failed to enqueue job", http.StatusInternalServerError)
+		return
+	}
+	slog.Info("Job enqueued", "job_id", jobID, "kind", kind, "document_id", docID)
+	s.wakeWorkers()
+	writeJSON(w, http.StatusAccepted, enqueueResponse{JobID: jobID, State: storage.JobStateQueued})
+}
+
+func parseBoolParam(v string) (bool, error) {
+	if v == "" {
+		return false, nil
+	}
+	return strconv.ParseBool(v)
+}
+
+// platformTables lists every table the configured payout platforms import
+// into.
+func (s *Server) platformTables() []string {
+	s.cacheMu.RLock()
+	defer s.cacheMu.RUnlock()
+	seen := make(map[string]bool)
+	var tables []string
+	for name, id := range s.platformIDs {
+		for _, table := range payout.Tables(name, s.duckDBConfigs[id]) {
+			if !seen[table] {
+				seen[table] = true
+				tables = append(tables, table)
+			}
+		}
+	}
+	return tables
+}
+
+// lastDocURL returns the doc_url of the document's most recent job of the
+// given kind, falling back to the Paperless document URL.
+func (s *Server) lastDocURL(docID int, kind storage.JobKind) string {
+	jobs, err := s.db.ListJobs(storage.JobFilter{PaperlessID: docID, Kind: kind, Limit: 1})
+	if err == nil && len(jobs) > 0 {
+		var payload struct {
+			DocURL string `json:"doc_url"`
+		}
+		if err := json.Unmarshal([]byte(jobs[0].Payload), &payload); err == nil && payload.DocURL != "" {
+			return payload.DocURL
+		}
+	}
+	return s.documentURL(docID)
+}
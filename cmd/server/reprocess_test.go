@@ -0,0 +1,29 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestHandleReprocessRejectsBadParameters(t *testing.T) {
+	s := &Server{}
+	for _, tc := range []struct {
+		query, want string
+	}{
+		{"kind=payout&dryrun=true", `Unknown query parameter "dryrun"`},
+		{"kind=bank_statement&force=true", "force is not supported for bank_statement"},
+		{"kind=bank_statement&update_accounting=true", "update_accounting is only supported"},
+		{"kind=bill&dry_run=maybe", "dry_run must be true or false"},
+		{"kind=invoice", "kind must be one of"},
+	} {
+		r := httptest.NewRequest(http.MethodPost, "/documents/7/reprocess?"+tc.query, nil)
+		r.SetPathValue("id", "7")
+		rec := httptest.NewRecorder()
+		s.handleReprocess(rec, r)
+		if rec.Code != http.StatusBadRequest || !strings.Contains(rec.Body.String(), tc.want) {
+			t.Errorf("%s: got %d %q, want 400 %q", tc.query, rec.Code, rec.Body.String(), tc.want)
+		}
+	}
+}
@@ -0,0 +1,195 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"paperless-document-processor/pkg/dates"
+	"paperless-document-processor/pkg/docai"
+	"paperless-document-processor/pkg/paperless"
+	"paperless-document-processor/pkg/storage"
+	"paperless-document-processor/pkg/terms"
+)
+
+// billDueDate picks the due date of a bill issued on issued, which is zero
+// when the issue date is unknown.  In order of precedence it comes from the
+// document's DUE_DATE_FIELD custom field (a date or payment terms), the
+// invoice's due_date, the vendor's stored payment terms and
+// DEFAULT_PAYMENT_TERMS.  source says which one was used, for the bill's
+// notes; due is empty when none applies.  Values that were skipped are
+// logged and returned as problems.
+func (s *Server) billDueDate(docID int, doc *paperless.Document, extracted *docai.ExtractedData, issued time.Time, dry *dryRunResult) (due, source string, problems []string) {
+	problem := func(format string, args ...any) {
+		p := fmt.Sprintf(format, args...)
+		slog.Warn("Due date source skipped", "document_id", docID, "problem", p)
+		if dry != nil {
+			dry.notef("due date: %s", p)
+		}
+		problems = append(problems, p)
+	}
+	dp := s.cfg.DateParser()
+
+	if v, ok := s.dueDateOverride(doc); ok {
+		field := s.cfg.DueDateField
+		t, dateErr := dp.Parse(v)
+		pt, termsErr := terms.Parse(v)
+		switch {
+		case dateErr == nil && !issued.IsZero() && t.Before(issued):
+			problem("custom field %q date %s is before the issue date %s", field, t.Format(dates.ISO), issued.Format(dates.ISO))
+		case dateErr == nil:
+			return t.Format(dates.ISO), dueSource(dp, fmt.Sprintf("custom field %q", field), v), problems
+		case termsErr != nil:
+			problem("custom field %q value %q is neither a date (%v) nor payment terms", field, v, dateErr)
+		case issued.IsZero():
+			problem("custom field %q sets payment terms %s but the issue date is unknown", field, pt)
+		default:
+			return pt.Due(issued).Format(dates.ISO), fmt.Sprintf("custom field %q (%s)", field, pt), problems
+		}
+	}
+
+	if extracted.DueDate != "" {
+		t, err := dp.Parse(extracted.DueDate)
+		switch {
+		case err != nil:
+			problem("invoice due date: %v", err)
+		case !issued.IsZero() && t.Before(issued):
+			problem("invoice due date %s is before the issue date %s", t.Format(dates.ISO), issued.Format(dates.ISO))
+		default:
+			return t.Format(dates.ISO), dueSource(dp, "invoice", extracted.DueDate), problems
+		}
+	}
+
+	if issued.IsZero() {
+		return "", "", problems
+	}
+
+	if extracted.Supplier != "" {
+		stored, found, err := s.db.GetPaymentTerms(extracted.Supplier)
+		if err != nil {
+			problem("vendor payment terms: %v", err)
+		} else if found {
+			pt, err := terms.Parse(stored)
+			if err == nil {
+				return pt.Due(issued).Format(dates.ISO), fmt.Sprintf("vendor payment terms (%s)", pt), problems
+			}
+			problem("vendor payment terms: %v", err)
+		}
+	}
+
+	pt := s.cfg.DefaultPaymentTerms
+	return pt.Due(issued).Format(dates.ISO), fmt.Sprintf("default payment terms (%s)", pt), problems
+}
+
+// dueSource is the source of a due date read from value, noting when value
+// was an ambiguous date read in DATE_ORDER.
+func dueSource(dp dates.Parser, source, value string) string {
+	if amb := dp.Ambiguity(value); amb != nil {
+		return fmt.Sprintf("%s; %v", source, amb)
+	}
+	return source
+}
+
+// dueDateOverride returns the value of the document's DUE_DATE_FIELD custom
+// field, and false when the field is not configured or not set.
+func (s *Server) dueDateOverride(doc *paperless.Document) (string, bool) {
+	if s.cfg.DueDateField == "" {
+		return "", false
+	}
+	field, ok := s.customField(s.cfg.DueDateField)
+	if !ok {
+		return "", false
+	}
+	for _, cf := range doc.CustomFields {
+		if cf.Field == field.ID && cf.Value != nil {
+			if v := strings.TrimSpace(fmt.Sprint(cf.Value)); v != "" {
+				return v, true
+			}
+		}
+	}
+	return "", false
+}
+
+// handleListPaymentTerms serves GET /payment-terms, the stored terms of
+// every vendor.
+func (s *Server) handleListPaymentTerms(w http.ResponseWriter, r *http.Request) {
+	list, err := s.db.ListPaymentTerms()
+	if err != nil {
+		slog.Error("Failed to list payment terms", "error", err)
+		http.Error(w, "Failed to list payment terms", http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusOK, list)
+}
+
+// paymentTermsRequest is the body of PUT and DELETE /payment-terms.  The
+// vendor is in the body rather than the path so names containing "/" can be
+// addressed.
+type paymentTermsRequest struct {
+	Vendor string `json:"vendor"`
+	Terms  string `json:"terms"`
+}
+
+// decodePaymentTermsRequest reads the request body, writing a 400 response
+// and returning false when it is not valid JSON or names no vendor.
+func decodePaymentTermsRequest(w http.ResponseWriter, r *http.Request) (paymentTermsRequest, bool) {
+	var body paymentTermsRequest
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		http.Error(w, "Invalid JSON body", http.StatusBadRequest)
+		return body, false
+	}
+	if storage.VendorKey(body.Vendor) == "" {
+		http.Error(w, "Vendor name is required", http.StatusBadRequest)
+		return body, false
+	}
+	return body, true
+}
+
+// handleSetPaymentTerms serves PUT /payment-terms with a body such as
+// {"vendor": "Acme Traders", "terms": "net 15"}; see terms.Parse for the
+// accepted forms.
+func (s *Server) handleSetPaymentTerms(w http.ResponseWriter, r *http.Request) {
+	body, ok := decodePaymentTermsRequest(w, r)
+	if !ok {
+		return
+	}
+	pt, err := terms.Parse(body.Terms)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	stored, err := s.db.SetPaymentTerms(body.Vendor, string(pt))
+	if err != nil {
+		slog.Error("Failed to save payment terms", "vendor", body.Vendor, "error", err)
+		http.Error(w, "Failed to save payment terms", http.StatusInternalServerError)
+		return
+	}
+	slog.Info("Payment terms saved", "vendor", stored.Vendor, "terms", stored.Terms)
+	writeJSON(w, http.StatusOK, stored)
+}
+
+// handleDeletePaymentTerms serves DELETE /payment-terms with a body such as
+// {"vendor": "Acme Traders"}; the vendor's bills fall back to
+// DEFAULT_PAYMENT_TERMS.
+func (s *Server) handleDeletePaymentTerms(w http.ResponseWriter, r *http.Request) {
+	body, ok := decodePaymentTermsRequest(w, r)
+	if !ok {
+		return
+	}
+	deleted, err := s.db.DeletePaymentTerms(body.Vendor)
+	if err != nil {
+		slog.Error("Failed to delete payment terms", "vendor", body.Vendor, "error", err)
+		http.Error(w, "Failed to delete payment terms", http.StatusInternalServerError)
+		return
+	}
+	if !deleted {
+		http.Error(w, "No payment terms for vendor", http.StatusNotFound)
+		return
+	}
+	slog.Info("Payment terms deleted", "vendor", storage.VendorKey(body.Vendor))
+	w.WriteHeader(http.StatusNoContent)
+}
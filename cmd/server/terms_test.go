@@ -0,0 +1,73 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"paperless-document-processor/config"
+	"paperless-document-processor/pkg/docai"
+	"paperless-document-processor/pkg/paperless"
+	"paperless-document-processor/pkg/terms"
+)
+
+func TestBillDueDate(t *testing.T) {
+	s := &Server{
+		cfg:          &config.Config{DueDateField: "Due Date", DefaultPaymentTerms: terms.Net(30)},
+		customFields: map[string]paperless.CustomField{"Due Date": {ID: 9, Name: "Due Date"}},
+	}
+	issued := time.Date(2025, 1, 20, 0, 0, 0, 0, time.UTC)
+	override := func(v interface{}) *paperless.Document {
+		return &paperless.Document{CustomFields: []paperless.CustomFieldInstance{{Field: 9, Value: v}}}
+	}
+
+	cases := []struct {
+		name       string
+		doc        *paperless.Document
+		dueDate    string
+		issued     time.Time
+		want       string
+		wantSource string
+		problems   int
+	}{
+		{"default terms", &paperless.Document{}, "", issued, "2025-02-19", "default payment terms (net_30)", 0},
+		{"invoice due date", &paperless.Document{}, "05-Feb-2025", issued, "2025-02-05", "invoice", 0},
+		{"custom field date wins", override("2025-03-01"), "05-Feb-2025", issued, "2025-03-01", `custom field "Due Date"`, 0},
+		{"custom field terms", override("end of month"), "05-Feb-2025", issued, "2025-01-31", `custom field "Due Date" (end_of_month)`, 0},
+		{"unreadable custom field", override("soon"), "", issued, "2025-02-19", "default payment terms (net_30)", 1},
+		{"custom field date before issue", override("2025-01-01"), "05-Feb-2025", issued, "2025-02-05", "invoice", 1},
+		{"due date before issue", &paperless.Document{}, "2025-01-01", issued, "2025-02-19", "default payment terms (net_30)", 1},
+		{"ambiguous due date", &paperless.Document{}, "03/02/2025", issued, "2025-02-03", `invoice; date "03/02/2025" is ambiguous: read as 2025-02-03, could be 2025-03-02`, 0},
+		{"no issue date", &paperless.Document{}, "", time.Time{}, "", "", 0},
+		{"no issue date, invoice due date", &paperless.Document{}, "2025-02-05", time.Time{}, "2025-02-05", "invoice", 0},
+	}
+	for _, tc := range cases {
+		due, source, problems := s.billDueDate(1, tc.doc, &docai.ExtractedData{DueDate: tc.dueDate}, tc.issued, nil)
+		if due != tc.want || source != tc.wantSource || len(problems) != tc.problems {
+			t.Errorf("%s: got (%q, %q, %v), want (%q, %q, %d problems)", tc.name, due, source, problems, tc.want, tc.wantSource, tc.problems)
+		}
+	}
+}
+
+func TestPaymentTermsRequestValidation(t *testing.T) {
+	s := &Server{}
+	for _, tc := range []struct {
+		name    string
+		handler http.HandlerFunc
+		body    string
+		want    string
+	}{
+		{"set without vendor", s.handleSetPaymentTerms, `{"terms": "net 15"}`, "Vendor name is required"},
+		{"set with bad terms", s.handleSetPaymentTerms, `{"vendor": "Acme/Traders", "terms": "soon"}`, "unrecognised payment terms"},
+		{"delete without vendor", s.handleDeletePaymentTerms, `{"vendor": "  "}`, "Vendor name is required"},
+		{"delete with bad body", s.handleDeletePaymentTerms, `Acme`, "Invalid JSON body"},
+	} {
+		rec := httptest.NewRecorder()
+		tc.handler(rec, httptest.NewRequest(http.MethodPut, "/payment-terms", strings.NewReader(tc.body)))
+		if rec.Code != http.StatusBadRequest || !strings.Contains(rec.Body.String(), tc.want) {
+			t.Errorf("%s: got %d %q, want 400 %q", tc.name, rec.Code, rec.Body.String(), tc.want)
+		}
+	}
+}
@@ -0,0 +1,214 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"mime"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// maxWebhookBody bounds how much of a webhook request body is read.
+const maxWebhookBody = 1 << 20
+
+// webhookExpectation is appended to every payload error so that whoever is
+// configuring the Paperless workflow can see what the endpoint accepts.
+const webhookExpectation = `expected a JSON or form-encoded body with "doc_url" ` +
+	`(e.g. http://paperless:8000/documents/73/details) and/or "document_id" (e.g. 73); ` +
+	`optional: doc_title, correspondent, added, update_accounting, dry_run`
+
+// webhookPayload is a processing request in any of the shapes we accept: our
+// own {"doc_url": ...} JSON, a Paperless-ngx workflow webhook sent as JSON or
+// as form parameters using the {doc_url}, {doc_title}, {correspondent} and
+// {added} placeholders, or a bare document_id.
+type webhookPayload struct {
+	DocURL           string
+	DocumentID       int
+	DocTitle         string
+	Correspondent    string
+	Added            string
+	UpdateAccounting bool
+	DryRun           bool
+}
+
+// parseWebhookPayload reads r's body as JSON or form data, depending on its
+// Content-Type, and resolves the document ID from document_id, doc_url or
+// both.  Errors describe what was wrong and what was expected and are meant
+// to be returned to the caller as a 400.
+func parseWebhookPayload(r *http.Request) (webhookPayload, error) {
+	var p webhookPayload
+
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxWebhookBody))
+	if err != nil {
+		return p, fmt.Errorf("failed to read request body: %w", err)
+	}
+
+	fields, err := decodeWebhookFields(r.Header.Get("Content-Type"), body)
+	if err != nil {
+		return p, fmt.Errorf("%v; %s", err, webhookExpectation)
+	}
+
+	p.DocURL = strings.TrimSpace(fields["doc_url"])
+	p.DocTitle = fields["doc_title"]
+	p.Correspondent = fields["correspondent"]
+	p.Added = fields["added"]
+	if p.UpdateAccounting, err = parseBoolParam(fields["update_accounting"]); err != nil {
+		return p, fmt.Errorf("update_accounting must be true or false, got %q", fields["update_accounting"])
+	}
+	if p.DryRun, err = parseBoolParam(fields["dry_run"]); err != nil {
+		return p, fmt.Errorf("dry_run must be true or false, got %q", fields["dry_run"])
+	}
+
+	var urlID int
+	if p.DocURL != "" {
+		if urlID, err = documentIDFromURL(p.DocURL); err != nil {
+			return p, fmt.Errorf("%v; %s", err, webhookExpectation)
+		}
+	}
+
+	if raw := strings.TrimSpace(fields["document_id"]); raw != "" {
+		id, err := strconv.Atoi(raw)
+		if err != nil || id <= 0 {
+			return p, fmt.Errorf("document_id %q is not a positive integer; %s", raw, webhookExpectation)
+		}
+		if urlID != 0 && urlID != id {
+			return p, fmt.Errorf("document_id %d does not match document %d in doc_url %q", id, urlID, p.DocURL)
+		}
+		p.DocumentID = id
+	} else {
+		p.DocumentID = urlID
+	}
+
+	if p.DocumentID == 0 {
+		return p, errors.New("missing document reference; " + webhookExpectation)
+	}
+	return p, nil
+}
+
+// decodeWebhookFields flattens a JSON object or form body into string
+// values.  JSON numbers and booleans are kept in their literal form.
+func decodeWebhookFields(contentType string, body []byte) (map[string]string, error) {
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	switch {
+	case mediaType == "application/x-www-form-urlencoded":
+		return decodeFormFields(body)
+	case mediaType == "multipart/form-data":
+		req := &http.Request{
+			Method: http.MethodPost,
+			Header: http.Header{"Content-Type": {contentType}},
+			Body:   io.NopCloser(bytes.NewReader(body)),
+		}
+		if err := req.ParseMultipartForm(maxWebhookBody); err != nil {
+			return nil, fmt.Errorf("invalid multipart form body: %v", err)
+		}
+		fields := make(map[string]string)
+		for k, v := range req.MultipartForm.Value {
+			if len(v) > 0 {
+				fields[k] = v[0]
+			}
+		}
+		return fields, nil
+	case mediaType == "application/json" || strings.HasSuffix(mediaType, "+json"):
+		return decodeJSONFields(body)
+	case mediaType == "":
+		// Clients that omit the header still usually send JSON.
+		if trimmed := bytes.TrimSpace(body); len(trimmed) > 0 && trimmed[0] != '{' {
+			return decodeFormFields(body)
+		}
+		return decodeJSONFields(body)
+	default:
+		return nil, fmt.Errorf("unsupported Content-Type %q", contentType)
+	}
+}
+
+func decodeFormFields(body []byte) (map[string]string, error) {
+	values, err := url.ParseQuery(string(body))
+	if err != nil {
+		return nil, fmt.Errorf("invalid form body: %v", err)
+	}
+	fields := make(map[string]string, len(values))
+	for k := range values {
+		fields[k] = values.Get(k)
+	}
+	return fields, nil
+}
+
+func decodeJSONFields(body []byte) (map[string]string, error) {
+	if len(bytes.TrimSpace(body)) == 0 {
+		return nil, errors.New("empty request body")
+	}
+	dec := json.NewDecoder(bytes.NewReader(body))
+	dec.UseNumber()
+	var raw map[string]any
+	if err := dec.Decode(&raw); err != nil {
+		return nil, fmt.Errorf("invalid JSON body: %v", err)
+	}
+	fields := make(map[string]string, len(raw))
+	for k, v := range raw {
+		switch v := v.(type) {
+		case nil:
+		case string:
+			fields[k] = v
+		case json.Number:
+			fields[k] = v.String()
+		case bool:
+			fields[k] = strconv.FormatBool(v)
+		default:
+			return nil, fmt.Errorf("field %q must be a string, number or boolean", k)
+		}
+	}
+	return fields, nil
+}
+
+// documentIDFromURL extracts the document ID from a Paperless URL.  It
+// accepts the UI forms /documents/73/ and /documents/73/details, the API form
+// /api/documents/73/ and, as before, any URL whose last path segment is the ID.
+func documentIDFromURL(raw string) (int, error) {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return 0, fmt.Errorf("doc_url %q is not a valid URL", raw)
+	}
+	segments := strings.FieldsFunc(u.Path, func(r rune) bool { return r == '/' })
+	for i := 0; i+1 < len(segments); i++ {
+		if segments[i] == "documents" {
+			if id, err := strconv.Atoi(segments[i+1]); err == nil && id > 0 {
+				return id, nil
+			}
+		}
+	}
+	if len(segments) > 0 {
+		if id, err := strconv.Atoi(segments[len(segments)-1]); err == nil && id > 0 {
+			return id, nil
+		}
+	}
+	return 0, fmt.Errorf("doc_url %q does not contain a document ID", raw)
+}
+
+// decodeWebhook parses the request and, on failure, answers with a 400
+// describing the accepted shapes.  A doc_url is synthesised from
+// PAPERLESS_URL when only a document_id was sent.
+func (s *Server) decodeWebhook(w http.ResponseWriter, r *http.Request, kind string) (webhookPayload, bool) {
+	p, err := parseWebhookPayload(r)
+	if err != nil {
+		slog.Warn("Rejected webhook payload", "kind", kind, "content_type", r.Header.Get("Content-Type"), "error", err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return p, false
+	}
+	if p.DocURL == "" {
+		p.DocURL = s.documentURL(p.DocumentID)
+	}
+	slog.Info("Received "+kind+" request", "doc_url", p.DocURL, "document_id", p.DocumentID,
+		"doc_title", p.DocTitle, "correspondent", p.Correspondent, "added", p.Added, "dry_run", p.DryRun)
+	return p, true
+}
+
+// documentURL is the Paperless UI URL of a document.
+func (s *Server) documentURL(docID int) string {
+	return fmt.Sprintf("%s/documents/%d/details", strings.TrimRight(s.cfg.PaperlessURL, "/"), docID)
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestDocumentIDFromURL(t *testing.T) {
+	cases := map[string]int{
+		"http://paperless:8000/documents/73/details":      73,
+		"http://paperless:8000/documents/73/details?x=1":  73,
+		"http://paperless:8000/documents/73/":             73,
+		"http://paperless:8000/documents/73":              73,
+		"http://paperless:8000/api/documents/73/":         73,
+		"https://paperless.example.com/sub/documents/9/":  9,
+		"http://webserver:8000/somewhere/else/41":         41,
+		"http://paperless:8000/documents/73/details#note": 73,
+	}
+	for raw, want := range cases {
+		got, err := documentIDFromURL(raw)
+		if err != nil {
+			t.Errorf("documentIDFromURL(%q) error: %v", raw, err)
+			continue
+		}
+		if got != want {
+			t.Errorf("documentIDFromURL(%q) = %d, want %d", raw, got, want)
+		}
+	}
+
+	for _, raw := range []string{"", "http://paperless:8000/documents/", "{doc_url}", "http://paperless:8000/documents/abc/details"} {
+		if _, err := documentIDFromURL(raw); err == nil {
+			t.Errorf("documentIDFromURL(%q) expected error", raw)
+		}
+	}
+}
+
+func TestParseWebhookPayload(t *testing.T) {
+	cases := []struct {
+		name        string
+		contentType string
+		body        string
+		wantID      int
+		wantTitle   string
+		wantDryRun  bool
+	}{
+		{"legacy json", "application/json", `{"doc_url": "http://paperless:8000/documents/73/"}`, 73, "", false},
+		{"no content type", "", `{"doc_url": "http://paperless:8000/documents/73/details"}`, 73, "", false},
+		{"workflow json", "application/json", `{"doc_url": "http://paperless:8000/documents/73/details", "doc_title": "Invoice 12", "correspondent": "Acme", "added": "2024-05-01"}`, 73, "Invoice 12", false},
+		{"numeric document_id", "application/json", `{"document_id": 73, "dry_run": true}`, 73, "", true},
+		{"string document_id", "application/json; charset=utf-8", `{"document_id": "73"}`, 73, "", false},
+		{"form", "application/x-www-form-urlencoded", "doc_url=http%3A%2F%2Fpaperless%3A8000%2Fdocuments%2F73%2Fdetails&doc_title=Invoice+12", 73, "Invoice 12", false},
+		{"form document_id", "application/x-www-form-urlencoded", "document_id=73&dry_run=true", 73, "", true},
+		{"form without content type", "", "document_id=73", 73, "", false},
+		{"matching id and url", "application/json", `{"document_id": 73, "doc_url": "http://paperless:8000/documents/73/details"}`, 73, "", false},
+	}
+	for _, tc := range cases {
+		r := httptest.NewRequest(http.MethodPost, "/bills", strings.NewReader(tc.body))
+		if tc.contentType != "" {
+			r.Header.Set("Content-Type", tc.contentType)
+		}
+		p, err := parseWebhookPayload(r)
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", tc.name, err)
+			continue
+		}
+		if p.DocumentID != tc.wantID {
+			t.Errorf("%s: DocumentID = %d, want %d", tc.name, p.DocumentID, tc.wantID)
+		}
+		if p.DocTitle != tc.wantTitle {
+			t.Errorf("%s: DocTitle = %q, want %q", tc.name, p.DocTitle, tc.wantTitle)
+		}
+		if p.DryRun != tc.wantDryRun {
+			t.Errorf("%s: DryRun = %v, want %v", tc.name, p.DryRun, tc.wantDryRun)
+		}
+	}
+}
+
+func TestParseWebhookPayload_Errors(t *testing.T) {
+	cases := []struct {
+		name        string
+		contentType string
+		body        string
+		wantInError string
+	}{
+		{"empty", "application/json", "", "document_id"},
+		{"bad json", "application/json", `{"doc_url":`, "invalid JSON"},
+		{"no reference", "application/json", `{"doc_title": "x"}`, "missing document reference"},
+		{"unsubstituted placeholder", "application/json", `{"doc_url": "{doc_url}"}`, "does not contain a document ID"},
+		{"bad document_id", "application/json", `{"document_id": "abc"}`, "positive integer"},
+		{"mismatch", "application/json", `{"document_id": 5, "doc_url": "http://p/documents/73/details"}`, "does not match"},
+		{"nested value", "application/json", `{"doc_url": {"url": "x"}}`, "must be a string"},
+		{"bad bool", "application/x-www-form-urlencoded", "document_id=1&dry_run=maybe", "dry_run"},
+		{"unsupported type", "text/plain", "73", "unsupported Content-Type"},
+	}
+	for _, tc := range cases {
+		r := httptest.NewRequest(http.MethodPost, "/bills", strings.NewReader(tc.body))
+		r.Header.Set("Content-Type", tc.contentType)
+		_, err := parseWebhookPayload(r)
+		if err == nil {
+			t.Errorf("%s: expected error", tc.name)
+			continue
+		}
+		if !strings.Contains(err.Error(), tc.wantInError) {
+			t.Errorf("%s: error %q does not mention %q", tc.name, err, tc.wantInError)
+		}
+	}
+}
@@ -3,7 +3,13 @@ package config
 import (
 	"fmt"
 	"os"
+	"strconv"
 	"strings"
+	"time"
+
+	"paperless-document-processor/pkg/dates"
+	"paperless-document-processor/pkg/retry"
+	"paperless-document-processor/pkg/terms"
 
 	"github.com/joho/godotenv"
 )
@@ -19,19 +25,110 @@ type Config struct {
 	GoogleCredentialsPath    string // Optional
 	LogLevel                 string
 	PayoutConfigPath         string // JSON file for platform options
+	CustomFieldMappingPath   string // JSON file mapping DocAI entities to custom fields
+	CustomFieldsAutoCreate   bool   // create mapped custom fields missing in Paperless
 	BankStatementProcessorID string
+	PayoutProcessorID        string // DocAI processor for payout PDF tables (Form Parser); defaults to DocumentAIProcessorID
+
+	// GSTINs are our own GST registrations.  They tell the buyer's GSTIN
+	// on a bill apart from the supplier's.
+	GSTINs []string
+
+	// DateLayouts are Go time layouts (e.g. "02/01/2006") tried before the
+	// built-in ones when reading bill, transaction and payout dates; a match
+	// is taken as is.  DateOrder is DMY or MDY, how numeric dates such as
+	// 03/04/2025 are read; those that read validly both ways are read in
+	// this order and flagged for review unless a layout matches.
+	DateLayouts []string
+	DateOrder   dates.Order
+
+	// DefaultPaymentTerms set the due date of bills whose invoice prints
+	// none and whose vendor has no stored terms.  DueDateField names a
+	// Paperless custom field that, when set on a document, overrides the
+	// due date: a date, or payment terms such as "net 15".
+	DefaultPaymentTerms terms.Terms
+	DueDateField        string
 
 	// Accounting (optional)
 	AccountingURL  string
 	AccountingUser string
 	AccountingPass string
 
-	// Tika (optional, used for payout XLSX)
+	// Tika (optional, used for payout PDF tables)
 	TikaURL string
 
 	// LibreOffice parser service (optional, used for payout XLSX when DuckDB fails)
 	LibreOfficeURL      string
 	LibreOfficeDataPath string
+
+	// Job queue
+	WorkerCount int // number of concurrent job workers
+
+	// Retry policies for transient failures, per external client.  Each is
+	// configured with <PREFIX>_RETRY_MAX_ATTEMPTS, _INITIAL_BACKOFF,
+	// _MAX_BACKOFF and _JITTER (e.g. PAPERLESS_RETRY_MAX_ATTEMPTS=5).
+	PaperlessRetry   retry.Policy
+	DocAIRetry       retry.Policy
+	AccountingRetry  retry.Policy
+	LibreOfficeRetry retry.Policy
+
+	// Webhook authentication.  Every mode that is configured must pass; when
+	// none is configured the webhook routes are open.
+	WebhookToken           string        // required "Authorization: Bearer <token>"
+	WebhookHMACSecret      string        // HMAC-SHA256 key for signed requests
+	WebhookSignatureHeader string        // header carrying the hex signature
+	WebhookTimestampHeader string        // header carrying the signing Unix time
+	WebhookReplayWindow    time.Duration // max age/skew of a signed request
+	WebhookAllowedIPs      []string      // client IPs or CIDRs allowed to call
+
+	// Polling discovers documents by tag as a fallback for webhooks.  It is
+	// disabled when PollInterval is 0.  A document must carry every tag in
+	// a kind's list to be picked up for that pipeline.
+	PollInterval          time.Duration
+	PollBillTags          []string
+	PollPayoutTags        []string
+	PollBankStatementTags []string
+	PollSince             time.Time // first high-water mark; zero means start from now
+
+	// Tags changed on the Paperless document when a job finishes, per
+	// pipeline.  Configured with <PREFIX>_<SUCCESS|FAILURE>_<ADD|REMOVE>_TAGS
+	// (e.g. BILL_SUCCESS_ADD_TAGS=processed-bill).
+	BillTags          TagTransitions
+	PayoutTags        TagTransitions
+	BankStatementTags TagTransitions
+
+	// PaperlessNotes posts a note to the document when a job finishes: the
+	// failing step and error, or a summary of what was created.
+	PaperlessNotes bool
+
+	// CacheRefreshInterval is how often the Paperless tags, custom fields and
+	// correspondents are reloaded (and payout platforms re-resolved against
+	// the tags).  0 disables the periodic refresh; POST /admin/refresh still
+	// works.
+	CacheRefreshInterval time.Duration
+
+	// PayoutConfigWatchInterval is how often the payout config file's
+	// modification time is checked for a reload.  0 disables the check;
+	// SIGHUP always reloads.
+	PayoutConfigWatchInterval time.Duration
+}
+
+// TagTransition lists Paperless tag names to add to and remove from a
+// document.
+type TagTransition struct {
+	Add    []string
+	Remove []string
+}
+
+func (t TagTransition) Empty() bool {
+	return len(t.Add) == 0 && len(t.Remove) == 0
+}
+
+// TagTransitions are applied when a job succeeds or fails (including dead
+// lettered jobs).
+type TagTransitions struct {
+	Success TagTransition
+	Failure TagTransition
 }
 
 func Load() (*Config, error) {
@@ -56,10 +153,82 @@ func Load() (*Config, error) {
 		TikaURL:          getEnv("TIKA_URL", "http://localhost:9998"),
 		PayoutConfigPath: os.Getenv("PAYOUT_EXCEL_DUCKDB_CONFIG_PATH"),
 
+		CustomFieldMappingPath: os.Getenv("CUSTOM_FIELD_MAPPING_PATH"),
+
 		LibreOfficeURL:      os.Getenv("LIBREOFFICE_URL"),
 		LibreOfficeDataPath: getEnv("LIBREOFFICE_DATA_PATH", "/data"),
 
 		BankStatementProcessorID: os.Getenv("BANK_STATEMENT_PROCESSOR_ID"),
+		PayoutProcessorID:        os.Getenv("PAYOUT_PROCESSOR_ID"),
+		GSTINs:                   getEnvList("GSTINS"),
+		DateLayouts:              ParseDateLayouts(os.Getenv("DATE_LAYOUTS")),
+		DateOrder:                dates.Order(strings.ToUpper(getEnv("DATE_ORDER", string(dates.DayFirst)))),
+		DueDateField:             os.Getenv("DUE_DATE_FIELD"),
+
+		WebhookToken:           os.Getenv("WEBHOOK_TOKEN"),
+		WebhookHMACSecret:      os.Getenv("WEBHOOK_HMAC_SECRET"),
+		WebhookSignatureHeader: getEnv("WEBHOOK_SIGNATURE_HEADER", "X-Webhook-Signature"),
+		WebhookTimestampHeader: getEnv("WEBHOOK_TIMESTAMP_HEADER", "X-Webhook-Timestamp"),
+		WebhookAllowedIPs:      getEnvList("WEBHOOK_ALLOWED_IPS"),
+
+		PollBillTags:          getEnvList("POLL_BILL_TAGS"),
+		PollPayoutTags:        getEnvList("POLL_PAYOUT_TAGS"),
+		PollBankStatementTags: getEnvList("POLL_BANK_STATEMENT_TAGS"),
+
+		BillTags:          getTagTransitions("BILL"),
+		PayoutTags:        getTagTransitions("PAYOUT"),
+		BankStatementTags: getTagTransitions("BANK_STATEMENT"),
+	}
+
+	workerCount, err := getEnvInt("WORKER_COUNT", 2)
+	if err != nil {
+		return nil, err
+	}
+	cfg.WorkerCount = workerCount
+
+	if cfg.WebhookReplayWindow, err = getEnvDuration("WEBHOOK_REPLAY_WINDOW", 5*time.Minute); err != nil {
+		return nil, err
+	}
+
+	if cfg.PaperlessNotes, err = getEnvBool("PAPERLESS_NOTES", true); err != nil {
+		return nil, err
+	}
+	if cfg.CustomFieldsAutoCreate, err = getEnvBool("CUSTOM_FIELDS_AUTO_CREATE", false); err != nil {
+		return nil, err
+	}
+
+	if cfg.CacheRefreshInterval, err = getEnvDuration("CACHE_REFRESH_INTERVAL", 10*time.Minute); err != nil {
+		return nil, err
+	}
+
+	if cfg.PayoutConfigWatchInterval, err = getEnvDuration("PAYOUT_CONFIG_WATCH_INTERVAL", 30*time.Second); err != nil {
+		return nil, err
+	}
+
+	if cfg.DefaultPaymentTerms, err = terms.Parse(getEnv("DEFAULT_PAYMENT_TERMS", "net 30")); err != nil {
+		return nil, fmt.Errorf("DEFAULT_PAYMENT_TERMS: %w", err)
+	}
+
+	if cfg.PollInterval, err = getEnvDuration("POLL_INTERVAL", 0); err != nil {
+		return nil, err
+	}
+	if since := os.Getenv("POLL_SINCE"); since != "" {
+		if cfg.PollSince, err = parseTime(since); err != nil {
+			return nil, fmt.Errorf("POLL_SINCE must be a date (2006-01-02) or RFC 3339 time: %w", err)
+		}
+	}
+
+	for prefix, policy := range map[string]*retry.Policy{
+		"PAPERLESS":   &cfg.PaperlessRetry,
+		"DOCAI":       &cfg.DocAIRetry,
+		"ACCOUNTING":  &cfg.AccountingRetry,
+		"LIBREOFFICE": &cfg.LibreOfficeRetry,
+	} {
+		p, err := getRetryPolicy(prefix)
+		if err != nil {
+			return nil, err
+		}
+		*policy = p
 	}
 
 	if err := cfg.validate(); err != nil {
@@ -88,6 +257,27 @@ func (c *Config) validate() error {
 	if c.BankStatementProcessorID == "" {
 		return fmt.Errorf("BANK_STATEMENT_PROCESSOR_ID is required")
 	}
+	if c.WorkerCount < 1 {
+		return fmt.Errorf("WORKER_COUNT must be at least 1")
+	}
+	if c.PollInterval < 0 {
+		return fmt.Errorf("POLL_INTERVAL must not be negative")
+	}
+	if c.PollInterval > 0 && len(c.PollBillTags)+len(c.PollPayoutTags)+len(c.PollBankStatementTags) == 0 {
+		return fmt.Errorf("POLL_INTERVAL is set but none of POLL_BILL_TAGS, POLL_PAYOUT_TAGS, POLL_BANK_STATEMENT_TAGS is")
+	}
+	if c.PayoutConfigWatchInterval < 0 {
+		return fmt.Errorf("PAYOUT_CONFIG_WATCH_INTERVAL must not be negative")
+	}
+	if c.CacheRefreshInterval < 0 {
+		return fmt.Errorf("CACHE_REFRESH_INTERVAL must not be negative")
+	}
+	if _, err := ParseDateOrder(string(c.DateOrder)); err != nil {
+		return fmt.Errorf("DATE_ORDER %w", err)
+	}
+	if c.WebhookHMACSecret != "" && c.WebhookReplayWindow <= 0 {
+		return fmt.Errorf("WEBHOOK_REPLAY_WINDOW must be positive")
+	}
 	return nil
 }
 
@@ -98,16 +288,253 @@ func getEnv(key, fallback string) string {
 	return fallback
 }
 
+// getTagTransitions reads <prefix>_SUCCESS_ADD_TAGS, _SUCCESS_REMOVE_TAGS,
+// _FAILURE_ADD_TAGS and _FAILURE_REMOVE_TAGS.
+func getTagTransitions(prefix string) TagTransitions {
+	return TagTransitions{
+		Success: TagTransition{
+			Add:    getEnvList(prefix + "_SUCCESS_ADD_TAGS"),
+			Remove: getEnvList(prefix + "_SUCCESS_REMOVE_TAGS"),
+		},
+		Failure: TagTransition{
+			Add:    getEnvList(prefix + "_FAILURE_ADD_TAGS"),
+			Remove: getEnvList(prefix + "_FAILURE_REMOVE_TAGS"),
+		},
+	}
+}
+
+// getEnvList splits a comma-separated variable, dropping empty entries.
+func getEnvList(key string) []string {
+	var list []string
+	for _, item := range strings.Split(os.Getenv(key), ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
+
+// ParseDateLayouts splits a semicolon-separated list of time layouts, as
+// given in DATE_LAYOUTS; layouts may contain commas ("Jan 2, 2006").
+func ParseDateLayouts(value string) []string {
+	var list []string
+	for _, item := range strings.Split(value, ";") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
+
+// ParseDateOrder reads a date order as given in DATE_ORDER, DMY or MDY in
+// any case.
+func ParseDateOrder(value string) (dates.Order, error) {
+	order := dates.Order(strings.ToUpper(strings.TrimSpace(value)))
+	if order != dates.DayFirst && order != dates.MonthFirst {
+		return "", fmt.Errorf("must be DMY or MDY, got %q", value)
+	}
+	return order, nil
+}
+
+// DateParser returns the parser for the configured date layouts and order.
+func (c *Config) DateParser() dates.Parser {
+	return dates.Parser{Layouts: c.DateLayouts, Order: c.DateOrder}
+}
+
+func parseTime(value string) (time.Time, error) {
+	if t, err := time.Parse("2006-01-02", value); err == nil {
+		return t, nil
+	}
+	return time.Parse(time.RFC3339, value)
+}
+
+func getEnvDuration(key string, fallback time.Duration) (time.Duration, error) {
+	value, exists := os.LookupEnv(key)
+	if !exists || value == "" {
+		return fallback, nil
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, fmt.Errorf("%s must be a duration (e.g. 500ms, 2s): %w", key, err)
+	}
+	return d, nil
+}
+
+func getEnvFloat(key string, fallback float64) (float64, error) {
+	value, exists := os.LookupEnv(key)
+	if !exists || value == "" {
+		return fallback, nil
+	}
+	f, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return 0, fmt.Errorf("%s must be a number: %w", key, err)
+	}
+	return f, nil
+}
+
+// getRetryPolicy reads <prefix>_RETRY_* variables, falling back to
+// retry.DefaultPolicy for any that are unset.
+func getRetryPolicy(prefix string) (retry.Policy, error) {
+	p := retry.DefaultPolicy
+	var err error
+	if p.MaxAttempts, err = getEnvInt(prefix+"_RETRY_MAX_ATTEMPTS", p.MaxAttempts); err != nil {
+		return p, err
+	}
+	if p.InitialBackoff, err = getEnvDuration(prefix+"_RETRY_INITIAL_BACKOFF", p.InitialBackoff); err != nil {
+		return p, err
+	}
+	if p.MaxBackoff, err = getEnvDuration(prefix+"_RETRY_MAX_BACKOFF", p.MaxBackoff); err != nil {
+		return p, err
+	}
+	if p.Jitter, err = getEnvFloat(prefix+"_RETRY_JITTER", p.Jitter); err != nil {
+		return p, err
+	}
+	if p.MaxAttempts < 1 {
+		return p, fmt.Errorf("%s_RETRY_MAX_ATTEMPTS must be at least 1", prefix)
+	}
+	if p.Jitter < 0 || p.Jitter > 1 {
+		return p, fmt.Errorf("%s_RETRY_JITTER must be between 0 and 1", prefix)
+	}
+	return p, nil
+}
+
+func getEnvBool(key string, fallback bool) (bool, error) {
+	value, exists := os.LookupEnv(key)
+	if !exists || value == "" {
+		return fallback, nil
+	}
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		return false, fmt.Errorf("%s must be true or false: %w", key, err)
+	}
+	return b, nil
+}
+
+func getEnvInt(key string, fallback int) (int, error) {
+	value, exists := os.LookupEnv(key)
+	if !exists || value == "" {
+		return fallback, nil
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, fmt.Errorf("%s must be an integer: %w", key, err)
+	}
+	return n, nil
+}
+
 type PayoutConfigs struct {
 	Platforms map[string]PlatformConfig `json:"platforms"`
+	Outlets   OutletConfig              `json:"outlets,omitempty"`
+}
+
+// OutletConfig decides which outlet a payout is booked to.  Sources are
+// tried in order and the first that yields a value wins; Default is used
+// when none does.
+type OutletConfig struct {
+	Sources []OutletSource `json:"sources,omitempty"`
+	// Names maps a resolved tag suffix, custom field value or storage path
+	// name to an outlet name.  Values without an entry are used as is.
+	Names   map[string]string `json:"names,omitempty"`
+	Default string            `json:"default,omitempty"`
+}
+
+// Outlet source types.
+const (
+	OutletSourceTag         = "tag"          // a document tag named <TagPrefix><outlet>
+	OutletSourceCustomField = "custom_field" // the value of custom field Field
+	OutletSourceStoragePath = "storage_path" // the name of the document's storage path
+	OutletSourceExport      = "export"       // the platform's outlet expression
+)
+
+type OutletSource struct {
+	Type      string `json:"type"`
+	TagPrefix string `json:"tag_prefix,omitempty"`
+	Field     string `json:"field,omitempty"`
 }
 
 type PlatformConfig struct {
 	// Method controls which backend reads the Excel file for this platform.
-	// Accepted values: "duckdb" (default) or "libreoffice".
-	Method        string         `json:"method,omitempty"`
+	// Accepted values: "duckdb" (default) or "libreoffice".  CSV files are
+	// always read by DuckDB.
+	Method string `json:"method,omitempty"`
+	// PDFMethod controls how tables are extracted from PDF settlement
+	// reports: "tika" (default) or "docai".
+	PDFMethod     string         `json:"pdf_method,omitempty"`
 	ImportConfigs []ImportConfig `json:"import_configs,omitempty"`
 	ExportConfigs []ExportConfig `json:"export_configs,omitempty"`
+	// Outlet reads the platform's restaurant ID from the imported tables,
+	// for the "export" outlet source.
+	Outlet *OutletExport `json:"outlet,omitempty"`
+	// Adjustments run in order on the PayoutInput decoded from the export
+	// configs, for platform quirks such as amounts reported with the wrong
+	// sign.
+	Adjustments []Adjustment `json:"adjustments,omitempty"`
+	// Checks must all pass before the payout is posted to accounting.
+	Checks []PayoutCheck `json:"checks,omitempty"`
+	// Fingerprint identifies the platform's reports when the document has
+	// no platform tag.
+	Fingerprint *Fingerprint `json:"fingerprint,omitempty"`
+}
+
+// Fingerprint recognises a platform's payout reports by their content.
+// Every rule that is set must match.  Sheet and cell rules only match .xlsx
+// workbooks.
+type Fingerprint struct {
+	// Filename is a regular expression matched against the document's
+	// original file name, e.g. "(?i)^invoice_.*_swiggy".
+	Filename string `json:"filename,omitempty"`
+	// Sheets must all exist in the workbook (names compared ignoring case).
+	Sheets []string          `json:"sheets,omitempty"`
+	Cells  []FingerprintCell `json:"cells,omitempty"`
+}
+
+// FingerprintCell requires a cell to hold Value, compared ignoring case and
+// extra whitespace.  An empty Sheet means the first sheet.
+type FingerprintCell struct {
+	Sheet string `json:"sheet,omitempty"`
+	Cell  string `json:"cell"`
+	Value string `json:"value"`
+}
+
+// PayoutCheck is an invariant verified on the adjusted PayoutInput.  Either
+// Expression is set, or Left and Right are:
+//   - Expression is a DuckDB boolean expression over the PayoutInput fields
+//     (e.g. "TotalOrders > 0", "PeriodStart <= PeriodEnd").
+//   - Left and Right are numeric expressions that must agree within
+//     Tolerance.  They are evaluated over the PayoutInput fields, or over
+//     the rows of LeftTable/RightTable when set, which reconciles the
+//     exported numbers against the platform's own summary sheet.
+type PayoutCheck struct {
+	Name       string  `json:"name"`
+	Expression string  `json:"expression,omitempty"`
+	Left       string  `json:"left,omitempty"`
+	LeftTable  string  `json:"left_table,omitempty"`
+	Right      string  `json:"right,omitempty"`
+	RightTable string  `json:"right_table,omitempty"`
+	Tolerance  float64 `json:"tolerance,omitempty"`
+}
+
+// Adjustment changes one PayoutInput field.  Exactly one of Expression,
+// Negate and Default is set:
+//   - Expression is a DuckDB SQL expression over the PayoutInput fields by
+//     name (e.g. "FinalPayoutAmt + MarketingAdsAmt"); its result replaces
+//     the field.
+//   - Negate flips the sign of a numeric field.
+//   - Default is used when the field is still empty or zero.
+type Adjustment struct {
+	Field      string `json:"field"`
+	Expression string `json:"expression,omitempty"`
+	Negate     bool   `json:"negate,omitempty"`
+	Default    string `json:"default,omitempty"`
+}
+
+// OutletExport evaluates Expression over TableName (like a reader config)
+// and maps the result through RestaurantIDs.  An ID missing from the map
+// fails the payout rather than booking it to the wrong outlet.
+type OutletExport struct {
+	TableName     string            `json:"table_name"`
+	Expression    string            `json:"expression"`
+	RestaurantIDs map[string]string `json:"restaurant_ids"`
 }
 
 type ImportConfig struct {
@@ -123,6 +550,13 @@ type ImportConfig struct {
 	// true, the final row of each parsed result is dropped before it is
 	// inserted into DuckDB.
 	Footer *bool `json:"footer,omitempty"`
+	// Delimiter and Skip are passed to read_csv for CSV files (delim and
+	// skip).  DuckDB sniffs the delimiter when it is empty.
+	Delimiter string `json:"delimiter,omitempty"`
+	Skip      int    `json:"skip,omitempty"`
+	// TableIndex selects the table of a PDF to import, counting from 0 in
+	// the order the tables appear.
+	TableIndex int `json:"table_index,omitempty"`
 }
 
 type ExportConfig struct {
@@ -165,6 +599,25 @@ func (p ImportConfig) ToOptionString() string {
 	return options
 }
 
+// ToCSVOptionString renders the read_csv options for this import config,
+// each followed by a comma.
+func (p ImportConfig) ToCSVOptionString() string {
+	var options string
+	if p.Header != nil {
+		options += fmt.Sprintf("header=%t,", *p.Header)
+	}
+	if p.AllVarchar != nil {
+		options += fmt.Sprintf("all_varchar=%t,", *p.AllVarchar)
+	}
+	if p.Delimiter != "" {
+		options += fmt.Sprintf("delim='%s',", strings.ReplaceAll(p.Delimiter, "'", "''"))
+	}
+	if p.Skip > 0 {
+		options += fmt.Sprintf("skip=%d,", p.Skip)
+	}
+	return options
+}
+
 func (p ExportConfig) ToSelectExpresssions() string {
 	var expressions string
 	for _, readerConfig := range p.ReaderConfigs {
@@ -190,6 +643,12 @@ func (p PlatformConfig) UseLibreOffice() bool {
 	return strings.EqualFold(p.Method, "libreoffice")
 }
 
+// UseDocAITables reports whether PDF tables should be extracted by Document
+// AI rather than Tika.
+func (p PlatformConfig) UseDocAITables() bool {
+	return strings.EqualFold(p.PDFMethod, "docai")
+}
+
 func (p ExportConfig) GetTableName(platform string) string {
 	return p.TableName
 }
@@ -0,0 +1,273 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"paperless-document-processor/pkg/accounting"
+	"paperless-document-processor/pkg/excel"
+)
+
+// PayoutConfigError lists every problem found while validating a payout
+// config, so a bad file can be fixed in one pass.
+type PayoutConfigError struct {
+	Problems []string
+}
+
+func (e *PayoutConfigError) Error() string {
+	return "invalid payout config: " + strings.Join(e.Problems, "; ")
+}
+
+// LoadPayoutConfigs reads and validates the payout config file.
+func LoadPayoutConfigs(path string) (PayoutConfigs, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return PayoutConfigs{}, fmt.Errorf("failed to read payout config file: %w", err)
+	}
+	return ParsePayoutConfigs(data)
+}
+
+// ParsePayoutConfigs decodes a payout config, rejecting unknown keys, and
+// validates it.
+func ParsePayoutConfigs(data []byte) (PayoutConfigs, error) {
+	var pc PayoutConfigs
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&pc); err != nil {
+		return PayoutConfigs{}, &PayoutConfigError{Problems: []string{fmt.Sprintf("failed to parse payout config JSON: %v", err)}}
+	}
+	if err := pc.Validate(); err != nil {
+		return PayoutConfigs{}, err
+	}
+	return pc, nil
+}
+
+// Validate checks every platform: import ranges must parse, relative ranges
+// must reference an earlier config with a fixed range, export and outlet
+// tables must be produced by an import and export columns must be
+// PayoutInput fields.  Outlet sources and fingerprints must be complete.
+// The returned error is a *PayoutConfigError.
+func (pc PayoutConfigs) Validate() error {
+	var problems []string
+	if len(pc.Platforms) == 0 {
+		problems = append(problems, "no platforms configured")
+	}
+
+	for _, p := range pc.Outlets.validate() {
+		problems = append(problems, "outlets: "+p)
+	}
+
+	platforms := make([]string, 0, len(pc.Platforms))
+	for name := range pc.Platforms {
+		platforms = append(platforms, name)
+	}
+	sort.Strings(platforms)
+	for _, name := range platforms {
+		for _, p := range pc.Platforms[name].validate(name) {
+			problems = append(problems, fmt.Sprintf("%s: %s", name, p))
+		}
+	}
+
+	if len(problems) > 0 {
+		return &PayoutConfigError{Problems: problems}
+	}
+	return nil
+}
+
+func (p PlatformConfig) validate(platform string) []string {
+	var problems []string
+	switch strings.ToLower(p.Method) {
+	case "", "duckdb", "libreoffice":
+	default:
+		problems = append(problems, fmt.Sprintf("unknown method %q", p.Method))
+	}
+	switch strings.ToLower(p.PDFMethod) {
+	case "", "tika", "docai":
+	default:
+		problems = append(problems, fmt.Sprintf("unknown pdf_method %q", p.PDFMethod))
+	}
+	if len(p.ImportConfigs) == 0 {
+		problems = append(problems, "no import_configs")
+	}
+
+	tables := make(map[string]bool)
+	for i, ic := range p.ImportConfigs {
+		tables[ic.GetTableName(platform)] = true
+		if ic.Range != "" {
+			if _, err := excel.NewRange(ic.Range); err != nil {
+				problems = append(problems, fmt.Sprintf("import_configs[%d]: %v", i, err))
+			}
+		}
+		if ic.Skip < 0 || ic.TableIndex < 0 {
+			problems = append(problems, fmt.Sprintf("import_configs[%d]: skip and table_index must not be negative", i))
+		}
+
+		ref := ic.RelativeRange.RelativeConfigIndex
+		switch {
+		case ref < 0:
+			problems = append(problems, fmt.Sprintf("import_configs[%d]: relative_config_index %d is negative", i, ref))
+		case ref > 0 && ref >= i:
+			problems = append(problems, fmt.Sprintf("import_configs[%d]: relative_config_index %d must reference an earlier config", i, ref))
+		case ref > 0:
+			if refRange := p.ImportConfigs[ref].Range; refRange == "" {
+				problems = append(problems, fmt.Sprintf("import_configs[%d]: referenced import_configs[%d] has no range", i, ref))
+			}
+		}
+	}
+
+	for i, ec := range p.ExportConfigs {
+		if ec.TableName == "" {
+			problems = append(problems, fmt.Sprintf("export_configs[%d]: table_name is required", i))
+		} else if !tables[ec.GetTableName(platform)] {
+			problems = append(problems, fmt.Sprintf("export_configs[%d]: table %q is not produced by any import config", i, ec.TableName))
+		}
+		for j, rc := range ec.ReaderConfigs {
+			if _, ok := PayoutInputField(rc.ColumnName); !ok {
+				problems = append(problems, fmt.Sprintf("export_configs[%d].reader_configs[%d]: column %q is not a PayoutInput field", i, j, rc.ColumnName))
+			}
+			if strings.TrimSpace(rc.Expression) == "" {
+				problems = append(problems, fmt.Sprintf("export_configs[%d].reader_configs[%d]: expression is required", i, j))
+			}
+		}
+	}
+	if o := p.Outlet; o != nil {
+		if o.TableName == "" || strings.TrimSpace(o.Expression) == "" {
+			problems = append(problems, "outlet: table_name and expression are required")
+		} else if !tables[o.TableName] {
+			problems = append(problems, fmt.Sprintf("outlet: table %q is not produced by any import config", o.TableName))
+		}
+		if len(o.RestaurantIDs) == 0 {
+			problems = append(problems, "outlet: restaurant_ids is empty")
+		}
+	}
+	for i, a := range p.Adjustments {
+		if err := a.validate(); err != nil {
+			problems = append(problems, fmt.Sprintf("adjustments[%d]: %v", i, err))
+		}
+	}
+	if p.Fingerprint != nil {
+		for _, problem := range p.Fingerprint.validate() {
+			problems = append(problems, "fingerprint: "+problem)
+		}
+	}
+	names := make(map[string]bool)
+	for i, c := range p.Checks {
+		if names[c.Name] {
+			problems = append(problems, fmt.Sprintf("checks[%d]: duplicate name %q", i, c.Name))
+		}
+		names[c.Name] = true
+		if err := c.validate(tables); err != nil {
+			problems = append(problems, fmt.Sprintf("checks[%d]: %v", i, err))
+		}
+	}
+	return problems
+}
+
+func (c PayoutCheck) validate(tables map[string]bool) error {
+	if c.Name == "" {
+		return fmt.Errorf("name is required")
+	}
+	comparison := c.Left != "" || c.Right != ""
+	switch {
+	case c.Expression != "" && comparison:
+		return fmt.Errorf("set either expression or left and right, not both")
+	case c.Expression == "" && (c.Left == "" || c.Right == ""):
+		return fmt.Errorf("expression, or both left and right, are required")
+	case c.Tolerance < 0:
+		return fmt.Errorf("tolerance must not be negative")
+	}
+	for _, table := range []string{c.LeftTable, c.RightTable} {
+		if table != "" && !tables[table] {
+			return fmt.Errorf("table %q is not produced by any import config", table)
+		}
+	}
+	return nil
+}
+
+func (f Fingerprint) validate() []string {
+	var problems []string
+	if f.Filename == "" && len(f.Sheets) == 0 && len(f.Cells) == 0 {
+		problems = append(problems, "at least one of filename, sheets and cells is required")
+	}
+	if f.Filename != "" {
+		if _, err := regexp.Compile(f.Filename); err != nil {
+			problems = append(problems, fmt.Sprintf("invalid filename pattern: %v", err))
+		}
+	}
+	for i, c := range f.Cells {
+		cell, err := excel.NewCell(strings.ReplaceAll(c.Cell, "$", ""))
+		if err != nil || cell.Column == "" || cell.Row == 0 {
+			problems = append(problems, fmt.Sprintf("cells[%d]: %q is not a cell address", i, c.Cell))
+		}
+		if strings.TrimSpace(c.Value) == "" {
+			problems = append(problems, fmt.Sprintf("cells[%d]: value is required", i))
+		}
+	}
+	return problems
+}
+
+func (a Adjustment) validate() error {
+	field, ok := PayoutInputField(a.Field)
+	if !ok {
+		return fmt.Errorf("field %q is not a PayoutInput field", a.Field)
+	}
+	set := 0
+	for _, b := range []bool{strings.TrimSpace(a.Expression) != "", a.Negate, a.Default != ""} {
+		if b {
+			set++
+		}
+	}
+	if set != 1 {
+		return fmt.Errorf("exactly one of expression, negate and default must be set")
+	}
+	numeric := field.Type.Kind() != reflect.String
+	if a.Negate && !numeric {
+		return fmt.Errorf("negate needs a numeric field, %s is text", field.Name)
+	}
+	if a.Default != "" && numeric {
+		if _, err := strconv.ParseFloat(a.Default, 64); err != nil {
+			return fmt.Errorf("default %q is not a number", a.Default)
+		}
+	}
+	return nil
+}
+
+func (o OutletConfig) validate() []string {
+	var problems []string
+	for i, src := range o.Sources {
+		switch src.Type {
+		case OutletSourceTag:
+			if src.TagPrefix == "" {
+				problems = append(problems, fmt.Sprintf("sources[%d]: tag source needs tag_prefix", i))
+			}
+		case OutletSourceCustomField:
+			if src.Field == "" {
+				problems = append(problems, fmt.Sprintf("sources[%d]: custom_field source needs field", i))
+			}
+		case OutletSourceStoragePath, OutletSourceExport:
+		default:
+			problems = append(problems, fmt.Sprintf("sources[%d]: unknown type %q", i, src.Type))
+		}
+	}
+	return problems
+}
+
+// PayoutInputField looks up the PayoutInput field an export column or
+// adjustment refers to.  Fields are matched by name, ignoring case, the same
+// way export rows are decoded.
+func PayoutInputField(name string) (reflect.StructField, bool) {
+	t := reflect.TypeOf(accounting.PayoutInput{})
+	for i := 0; i < t.NumField(); i++ {
+		if strings.EqualFold(t.Field(i).Name, name) {
+			return t.Field(i), true
+		}
+	}
+	return reflect.StructField{}, false
+}
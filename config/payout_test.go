@@ -0,0 +1,102 @@
+package config
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestLoadPayoutConfigs_Example(t *testing.T) {
+	if _, err := LoadPayoutConfigs("../payout_configs.json"); err != nil {
+		t.Fatalf("example payout config should be valid: %v", err)
+	}
+}
+
+func TestParsePayoutConfigs_Problems(t *testing.T) {
+	cases := map[string]struct {
+		body string
+		want string
+	}{
+		"unknown key": {
+			`{"platforms": {"swiggy": {"import_configs": [{"sheet": "A", "rnage": "A1:B"}]}}}`,
+			`unknown field "rnage"`,
+		},
+		"bad range": {
+			`{"platforms": {"swiggy": {"import_configs": [{"sheet": "A", "range": "A1"}]}}}`,
+			"swiggy: import_configs[0]: invalid range format: A1",
+		},
+		"forward relative index": {
+			`{"platforms": {"swiggy": {"import_configs": [{"range": "A1:B"}, {"relative_range": {"relative_config_index": 2}}, {"range": "C1:D"}]}}}`,
+			"import_configs[1]: relative_config_index 2 must reference an earlier config",
+		},
+		"relative to config without range": {
+			`{"platforms": {"swiggy": {"import_configs": [{"range": "A1:B"}, {"sheet": "S"}, {"relative_range": {"relative_config_index": 1}}]}}}`,
+			"import_configs[2]: referenced import_configs[1] has no range",
+		},
+		"unknown export column": {
+			`{"platforms": {"swiggy": {"import_configs": [{"table_name": "t", "range": "A1:B"}], "export_configs": [{"table_name": "t", "reader_configs": [{"column_name": "NetPayout", "expression": "SUM(#2)"}]}]}}}`,
+			`column "NetPayout" is not a PayoutInput field`,
+		},
+		"export table not imported": {
+			`{"platforms": {"swiggy": {"import_configs": [{"table_name": "t", "range": "A1:B"}], "export_configs": [{"table_name": "u", "reader_configs": [{"column_name": "finalpayoutamt", "expression": "SUM(#2)"}]}]}}}`,
+			`table "u" is not produced by any import config`,
+		},
+		"adjustment field": {
+			`{"platforms": {"swiggy": {"import_configs": [{"range": "A1:B"}], "adjustments": [{"field": "NetPayout", "negate": true}]}}}`,
+			`adjustments[0]: field "NetPayout" is not a PayoutInput field`,
+		},
+		"adjustment with two actions": {
+			`{"platforms": {"swiggy": {"import_configs": [{"range": "A1:B"}], "adjustments": [{"field": "FinalPayoutAmt", "negate": true, "default": "0"}]}}}`,
+			"exactly one of expression, negate and default must be set",
+		},
+		"empty fingerprint": {
+			`{"platforms": {"swiggy": {"import_configs": [{"range": "A1:B"}], "fingerprint": {}}}}`,
+			"swiggy: fingerprint: at least one of filename, sheets and cells is required",
+		},
+		"fingerprint cell without row": {
+			`{"platforms": {"swiggy": {"import_configs": [{"range": "A1:B"}], "fingerprint": {"filename": "(?i)swiggy", "cells": [{"cell": "B", "value": "Order ID"}]}}}}`,
+			`fingerprint: cells[0]: "B" is not a cell address`,
+		},
+		"unknown pdf method": {
+			`{"platforms": {"swiggy": {"pdf_method": "ocr", "import_configs": [{"table_index": 1}]}}}`,
+			`unknown pdf_method "ocr"`,
+		},
+		"check without expression": {
+			`{"platforms": {"swiggy": {"import_configs": [{"range": "A1:B"}], "checks": [{"name": "net", "left": "FinalPayoutAmt"}]}}}`,
+			"checks[0]: expression, or both left and right, are required",
+		},
+		"check against unknown table": {
+			`{"platforms": {"swiggy": {"import_configs": [{"table_name": "t", "range": "A1:B"}], "checks": [{"name": "net", "left": "GrossSalesAmt", "right": "SUM(#2)", "right_table": "summary"}]}}}`,
+			`checks[0]: table "summary" is not produced by any import config`,
+		},
+		"negated text field": {
+			`{"platforms": {"swiggy": {"import_configs": [{"range": "A1:B"}], "adjustments": [{"field": "UtrNumber", "negate": true}]}}}`,
+			"negate needs a numeric field",
+		},
+	}
+	for name, tc := range cases {
+		_, err := ParsePayoutConfigs([]byte(tc.body))
+		var cfgErr *PayoutConfigError
+		if !errors.As(err, &cfgErr) {
+			t.Errorf("%s: expected PayoutConfigError, got %v", name, err)
+			continue
+		}
+		if !strings.Contains(err.Error(), tc.want) {
+			t.Errorf("%s: expected %q in %q", name, tc.want, err)
+		}
+	}
+}
+
+func TestPayoutConfigsValidate_ReportsAllProblems(t *testing.T) {
+	_, err := ParsePayoutConfigs([]byte(`{"platforms": {
+		"swiggy": {"method": "excel", "import_configs": [{"range": "A1:B"}]},
+		"zomato": {"import_configs": [{"range": "bad"}]}
+	}}`))
+	var cfgErr *PayoutConfigError
+	if !errors.As(err, &cfgErr) || len(cfgErr.Problems) != 2 {
+		t.Fatalf("expected two problems, got %v", err)
+	}
+	if !strings.HasPrefix(cfgErr.Problems[0], "swiggy:") || !strings.HasPrefix(cfgErr.Problems[1], "zomato:") {
+		t.Errorf("expected problems sorted by platform, got %v", cfgErr.Problems)
+	}
+}
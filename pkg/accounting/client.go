@@ -2,11 +2,15 @@ package accounting
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
 	"strings"
+
+	"paperless-document-processor/pkg/money"
+	"paperless-document-processor/pkg/retry"
 )
 
 type Client struct {
@@ -14,6 +18,7 @@ type Client struct {
 	user    string
 	pass    string
 	client  *http.Client
+	retry   retry.Policy
 }
 
 type Contact struct {
@@ -46,6 +51,37 @@ type BillInput struct {
 	Status     string `json:"status"`
 	FileURL    string `json:"file_url,omitempty"`
 	Notes      string `json:"notes,omitempty"`
+	// LineItems are what the bill is for, as read from the invoice.
+	LineItems []BillLineItem `json:"line_items,omitempty"`
+	// GST is the tax breakdown of a GST invoice, used for input tax credit.
+	GST *GSTBreakdown `json:"gst,omitempty"`
+}
+
+// BillLineItem is one purchased item on a bill.
+type BillLineItem struct {
+	Description string  `json:"description"`
+	ProductCode string  `json:"product_code,omitempty"`
+	Quantity    float64 `json:"quantity,omitempty"`
+	Unit        string  `json:"unit,omitempty"`
+	UnitPrice   int     `json:"unit_price,omitempty"` // in paise
+	Amount      int     `json:"amount"`               // in paise
+}
+
+// GSTBreakdown is the GST detail of a bill.
+type GSTBreakdown struct {
+	SupplierGSTIN string   `json:"supplier_gstin,omitempty"`
+	BuyerGSTIN    string   `json:"buyer_gstin,omitempty"`
+	PlaceOfSupply string   `json:"place_of_supply,omitempty"` // two-digit state code
+	TaxableAmount int      `json:"taxable_amount,omitempty"`  // in paise
+	Taxes         []GSTTax `json:"taxes,omitempty"`
+	HSNCodes      []string `json:"hsn_codes,omitempty"`
+}
+
+// GSTTax is one tax line of a bill: CGST, SGST, UTGST, IGST or CESS.
+type GSTTax struct {
+	Type   string  `json:"type"`
+	Rate   float64 `json:"rate,omitempty"` // percent
+	Amount int     `json:"amount"`         // in paise
 }
 
 type Platform string
@@ -56,36 +92,36 @@ const (
 )
 
 type Payout struct {
-	ID                    int      `json:"id"`
-	OutletName            string   `json:"outlet_name"`
-	Platform              Platform `json:"platform"`
-	PeriodStart           string   `json:"period_start"`
-	PeriodEnd             string   `json:"period_end"`
-	SettlementDate        string   `json:"settlement_date"`
-	TotalOrders           int      `json:"total_orders"`
-	GrossSalesAmt         float32  `json:"gross_sales_amt"`
-	RestaurantDiscountAmt float32  `json:"restaurant_discount_amt"`
-	PlatformCommissionAmt float32  `json:"platform_commission_amt"`
-	TaxesTcsTdsAmt        float32  `json:"taxes_tcs_tds_amt"`
-	MarketingAdsAmt       float32  `json:"marketing_ads_amt"`
-	FinalPayoutAmt        float32  `json:"final_payout_amt"`
-	UtrNumber             string   `json:"utr_number"`
+	ID                    int         `json:"id"`
+	OutletName            string      `json:"outlet_name"`
+	Platform              Platform    `json:"platform"`
+	PeriodStart           string      `json:"period_start"`
+	PeriodEnd             string      `json:"period_end"`
+	SettlementDate        string      `json:"settlement_date"`
+	TotalOrders           int         `json:"total_orders"`
+	GrossSalesAmt         money.Money `json:"gross_sales_amt"`
+	RestaurantDiscountAmt money.Money `json:"restaurant_discount_amt"`
+	PlatformCommissionAmt money.Money `json:"platform_commission_amt"`
+	TaxesTcsTdsAmt        money.Money `json:"taxes_tcs_tds_amt"`
+	MarketingAdsAmt       money.Money `json:"marketing_ads_amt"`
+	FinalPayoutAmt        money.Money `json:"final_payout_amt"`
+	UtrNumber             string      `json:"utr_number"`
 }
 
 type PayoutInput struct {
-	OutletName            string   `json:"outlet_name"`
-	Platform              Platform `json:"platform"`
-	PeriodStart           string   `json:"period_start"`
-	PeriodEnd             string   `json:"period_end"`
-	SettlementDate        string   `json:"settlement_date"`
-	TotalOrders           int      `json:"total_orders"`
-	GrossSalesAmt         float32  `json:"gross_sales_amt"`
-	RestaurantDiscountAmt float32  `json:"restaurant_discount_amt"`
-	PlatformCommissionAmt float32  `json:"platform_commission_amt"`
-	TaxesTcsTdsAmt        float32  `json:"taxes_tcs_tds_amt"`
-	MarketingAdsAmt       float32  `json:"marketing_ads_amt"`
-	FinalPayoutAmt        float32  `json:"final_payout_amt"`
-	UtrNumber             string   `json:"utr_number"`
+	OutletName            string      `json:"outlet_name"`
+	Platform              Platform    `json:"platform"`
+	PeriodStart           string      `json:"period_start"`
+	PeriodEnd             string      `json:"period_end"`
+	SettlementDate        string      `json:"settlement_date"`
+	TotalOrders           int         `json:"total_orders"`
+	GrossSalesAmt         money.Money `json:"gross_sales_amt"`
+	RestaurantDiscountAmt money.Money `json:"restaurant_discount_amt"`
+	PlatformCommissionAmt money.Money `json:"platform_commission_amt"`
+	TaxesTcsTdsAmt        money.Money `json:"taxes_tcs_tds_amt"`
+	MarketingAdsAmt       money.Money `json:"marketing_ads_amt"`
+	FinalPayoutAmt        money.Money `json:"final_payout_amt"`
+	UtrNumber             string      `json:"utr_number"`
 }
 
 func (p PayoutInput) String() string {
@@ -105,20 +141,20 @@ type AccountInput struct {
 }
 
 type Transaction struct {
-	ID              int     `json:"id"`
-	AccountID       int     `json:"account_id"`
-	Type            string  `json:"type"`   // income, expense
-	Amount          float64 `json:"amount"` // raw value; server Money type handles ×100 conversion
-	TransactionDate *string `json:"transaction_date"`
-	Description     *string `json:"description"`
+	ID              int         `json:"id"`
+	AccountID       int         `json:"account_id"`
+	Type            string      `json:"type"`   // income, expense
+	Amount          money.Money `json:"amount"` // in rupees; server Money type handles ×100 conversion
+	TransactionDate *string     `json:"transaction_date"`
+	Description     *string     `json:"description"`
 }
 
 type TransactionInput struct {
-	AccountID       int     `json:"account_id"`       // required
-	Type            string  `json:"type"`             // "income" or "expense"
-	Amount          float64 `json:"amount"`           // raw decimal; accounting service converts to paise
-	TransactionDate *string `json:"transaction_date"` // YYYY-MM-DD
-	Description     *string `json:"description"`
+	AccountID       int         `json:"account_id"`       // required
+	Type            string      `json:"type"`             // "income" or "expense"
+	Amount          money.Money `json:"amount"`           // exact decimal rupees; accounting service converts to paise
+	TransactionDate *string     `json:"transaction_date"` // YYYY-MM-DD
+	Description     *string     `json:"description"`
 }
 
 type Response[T any] struct {
@@ -135,52 +171,104 @@ func NewClient(baseURL, user, pass string) *Client {
 	}
 }
 
-func (c *Client) request(method, path string, body interface{}) (*http.Response, error) {
+// SetRetryPolicy configures how transient failures (timeouts, 429, 5xx) are
+// retried.  The default is a single attempt.
+func (c *Client) SetRetryPolicy(p retry.Policy) {
+	c.retry = p
+}
+
+// request performs an API call.  Transient failures (429 and 5xx responses)
+// are returned as a *retry.StatusError and, for GET, PUT and DELETE, retried
+// per the client's policy until ctx is done; any other response is handed
+// back for the caller to check.  POSTs are never retried: one that timed out
+// may still have been committed, and sending it again would duplicate the
+// record.
+func (c *Client) request(ctx context.Context, method, path string, body interface{}) (*http.Response, error) {
 	u := fmt.Sprintf("%s/api/v1/%s", c.baseURL, strings.TrimLeft(path, "/"))
-	var buf io.Reader
+	var jsonBody []byte
 	if body != nil {
-		jsonBody, err := json.Marshal(body)
+		var err error
+		jsonBody, err = json.Marshal(body)
 		if err != nil {
 			return nil, err
 		}
-		buf = bytes.NewBuffer(jsonBody)
 	}
 
-	req, err := http.NewRequest(method, u, buf)
-	if err != nil {
-		return nil, err
+	policy := c.retry
+	if method == http.MethodPost {
+		policy = retry.Policy{}
 	}
 
-	req.SetBasicAuth(c.user, c.pass)
-	if body != nil {
-		req.Header.Set("Content-Type", "application/json")
-	}
+	var resp *http.Response
+	err := retry.Do(ctx, policy, "accounting "+method+" "+path, func() error {
+		var buf io.Reader
+		if jsonBody != nil {
+			buf = bytes.NewReader(jsonBody)
+		}
 
-	return c.client.Do(req)
+		req, err := http.NewRequestWithContext(ctx, method, u, buf)
+		if err != nil {
+			return err
+		}
+
+		req.SetBasicAuth(c.user, c.pass)
+		if body != nil {
+			req.Header.Set("Content-Type", "application/json")
+		}
+
+		r, err := c.client.Do(req)
+		if err != nil {
+			return err
+		}
+		if r.StatusCode == http.StatusTooManyRequests || r.StatusCode >= 500 {
+			respBody, _ := io.ReadAll(r.Body)
+			r.Body.Close()
+			return retry.NewStatusError(r, fmt.Errorf("accounting %s %s: status %d: %s", method, path, r.StatusCode, string(respBody)))
+		}
+		resp = r
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return resp, nil
 }
 
-func (c *Client) GetOrCreateVendor(name string) (int, error) {
-	// 1. Check if exists
-	resp, err := c.request("GET", "contacts?type=vendor", nil)
+// FindVendor looks up a vendor contact by name (case-insensitive) without
+// creating it.  The boolean is false when no vendor matches.
+func (c *Client) FindVendor(ctx context.Context, name string) (int, bool, error) {
+	resp, err := c.request(ctx, "GET", "contacts?type=vendor", nil)
 	if err != nil {
-		return 0, err
+		return 0, false, err
 	}
 	defer resp.Body.Close()
 
 	var listResp Response[[]Contact]
 	if err := json.NewDecoder(resp.Body).Decode(&listResp); err != nil {
-		return 0, err
+		return 0, false, err
 	}
 
 	for _, contact := range listResp.Data {
 		if strings.EqualFold(contact.Name, name) {
-			return contact.ID, nil
+			return contact.ID, true, nil
 		}
 	}
+	return 0, false, nil
+}
+
+func (c *Client) GetOrCreateVendor(ctx context.Context, name string) (int, error) {
+	// 1. Check if exists
+	id, found, err := c.FindVendor(ctx, name)
+	if err != nil {
+		return 0, err
+	}
+	if found {
+		return id, nil
+	}
 
 	// 2. Create if not exists
 	input := ContactInput{Name: name, Type: "vendor"}
-	resp, err = c.request("POST", "contacts", input)
+	resp, err := c.request(ctx, "POST", "contacts", input)
 	if err != nil {
 		return 0, err
 	}
@@ -194,8 +282,8 @@ func (c *Client) GetOrCreateVendor(name string) (int, error) {
 	return createResp.Data.ID, nil
 }
 
-func (c *Client) CreateBill(bill BillInput) (int, error) {
-	resp, err := c.request("POST", "bills", bill)
+func (c *Client) CreateBill(ctx context.Context, bill BillInput) (int, error) {
+	resp, err := c.request(ctx, "POST", "bills", bill)
 	if err != nil {
 		return 0, err
 	}
@@ -203,7 +291,7 @@ func (c *Client) CreateBill(bill BillInput) (int, error) {
 
 	if resp.StatusCode != http.StatusCreated && resp.StatusCode != http.StatusOK {
 		body, _ := io.ReadAll(resp.Body)
-		return 0, fmt.Errorf("failed to create bill: %d %s", resp.StatusCode, string(body))
+		return 0, retry.NewStatusError(resp, fmt.Errorf("failed to create bill: %d %s", resp.StatusCode, string(body)))
 	}
 
 	var createResp Response[Bill]
@@ -214,8 +302,32 @@ func (c *Client) CreateBill(bill BillInput) (int, error) {
 	return createResp.Data.ID, nil
 }
 
-func (c *Client) CreatePayout(payout PayoutInput) (int, error) {
-	resp, err := c.request("POST", "payouts", payout)
+// UpdateBill replaces the fields of an existing bill.
+func (c *Client) UpdateBill(ctx context.Context, id int, bill BillInput) error {
+	return c.update(ctx, fmt.Sprintf("bills/%d", id), "bill", bill)
+}
+
+// UpdatePayout replaces the fields of an existing payout.
+func (c *Client) UpdatePayout(ctx context.Context, id int, payout PayoutInput) error {
+	return c.update(ctx, fmt.Sprintf("payouts/%d", id), "payout", payout)
+}
+
+func (c *Client) update(ctx context.Context, path, what string, body interface{}) error {
+	resp, err := c.request(ctx, "PUT", path, body)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNoContent {
+		respBody, _ := io.ReadAll(resp.Body)
+		return retry.NewStatusError(resp, fmt.Errorf("failed to update %s: %d %s", what, resp.StatusCode, string(respBody)))
+	}
+	return nil
+}
+
+func (c *Client) CreatePayout(ctx context.Context, payout PayoutInput) (int, error) {
+	resp, err := c.request(ctx, "POST", "payouts", payout)
 	if err != nil {
 		return 0, err
 	}
@@ -223,7 +335,7 @@ func (c *Client) CreatePayout(payout PayoutInput) (int, error) {
 
 	if resp.StatusCode != http.StatusCreated && resp.StatusCode != http.StatusOK {
 		body, _ := io.ReadAll(resp.Body)
-		return 0, fmt.Errorf("failed to create payout: %d %s", resp.StatusCode, string(body))
+		return 0, retry.NewStatusError(resp, fmt.Errorf("failed to create payout: %d %s", resp.StatusCode, string(body)))
 	}
 
 	var createResp Response[Payout]
@@ -234,28 +346,41 @@ func (c *Client) CreatePayout(payout PayoutInput) (int, error) {
 	return createResp.Data.ID, nil
 }
 
-func (c *Client) GetOrCreateBankAccount(name string) (int, error) {
-	// List all accounts and find by name (case-insensitive)
-	resp, err := c.request("GET", "accounts", nil)
+// FindBankAccount looks up an account by name (case-insensitive) without
+// creating it.  The boolean is false when no account matches.
+func (c *Client) FindBankAccount(ctx context.Context, name string) (int, bool, error) {
+	resp, err := c.request(ctx, "GET", "accounts", nil)
 	if err != nil {
-		return 0, err
+		return 0, false, err
 	}
 	defer resp.Body.Close()
 
 	var listResp Response[[]Account]
 	if err := json.NewDecoder(resp.Body).Decode(&listResp); err != nil {
-		return 0, fmt.Errorf("failed to decode accounts list: %w", err)
+		return 0, false, fmt.Errorf("failed to decode accounts list: %w", err)
 	}
 
 	for _, a := range listResp.Data {
 		if strings.EqualFold(a.Name, name) {
-			return a.ID, nil
+			return a.ID, true, nil
 		}
 	}
+	return 0, false, nil
+}
+
+func (c *Client) GetOrCreateBankAccount(ctx context.Context, name string) (int, error) {
+	// List all accounts and find by name (case-insensitive)
+	id, found, err := c.FindBankAccount(ctx, name)
+	if err != nil {
+		return 0, err
+	}
+	if found {
+		return id, nil
+	}
 
 	// Create if not found
 	input := AccountInput{Name: name, Type: "bank", OpeningBalance: 0}
-	cresp, err := c.request("POST", "accounts", input)
+	cresp, err := c.request(ctx, "POST", "accounts", input)
 	if err != nil {
 		return 0, err
 	}
@@ -263,7 +388,7 @@ func (c *Client) GetOrCreateBankAccount(name string) (int, error) {
 
 	if cresp.StatusCode != http.StatusCreated && cresp.StatusCode != http.StatusOK {
 		body, _ := io.ReadAll(cresp.Body)
-		return 0, fmt.Errorf("failed to create bank account: %d %s", cresp.StatusCode, string(body))
+		return 0, retry.NewStatusError(cresp, fmt.Errorf("failed to create bank account: %d %s", cresp.StatusCode, string(body)))
 	}
 
 	var createResp Response[Account]
@@ -273,8 +398,8 @@ func (c *Client) GetOrCreateBankAccount(name string) (int, error) {
 	return createResp.Data.ID, nil
 }
 
-func (c *Client) CreateTransaction(txn TransactionInput) (int, error) {
-	resp, err := c.request("POST", "transactions", txn)
+func (c *Client) CreateTransaction(ctx context.Context, txn TransactionInput) (int, error) {
+	resp, err := c.request(ctx, "POST", "transactions", txn)
 	if err != nil {
 		return 0, err
 	}
@@ -282,7 +407,7 @@ func (c *Client) CreateTransaction(txn TransactionInput) (int, error) {
 
 	if resp.StatusCode != http.StatusCreated && resp.StatusCode != http.StatusOK {
 		body, _ := io.ReadAll(resp.Body)
-		return 0, fmt.Errorf("failed to create transaction: %d %s", resp.StatusCode, string(body))
+		return 0, retry.NewStatusError(resp, fmt.Errorf("failed to create transaction: %d %s", resp.StatusCode, string(body)))
 	}
 
 	var createResp Response[Transaction]
@@ -1,10 +1,18 @@
 package accounting
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
+	"io"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
+	"time"
+
+	"paperless-document-processor/pkg/money"
+	"paperless-document-processor/pkg/retry"
 )
 
 func TestGetOrCreateVendor_Existing(t *testing.T) {
@@ -21,7 +29,7 @@ func TestGetOrCreateVendor_Existing(t *testing.T) {
 	defer server.Close()
 
 	client := NewClient(server.URL, "user", "pass")
-	id, err := client.GetOrCreateVendor("Acme Corp")
+	id, err := client.GetOrCreateVendor(context.Background(), "Acme Corp")
 	if err != nil {
 		t.Fatalf("Expected no error, got %v", err)
 	}
@@ -55,7 +63,7 @@ func TestGetOrCreateVendor_New(t *testing.T) {
 	defer server.Close()
 
 	client := NewClient(server.URL, "user", "pass")
-	id, err := client.GetOrCreateVendor("New Corp")
+	id, err := client.GetOrCreateVendor(context.Background(), "New Corp")
 	if err != nil {
 		t.Fatalf("Expected no error, got %v", err)
 	}
@@ -86,7 +94,7 @@ func TestCreateBill(t *testing.T) {
 
 	client := NewClient(server.URL, "user", "pass")
 	contactID := 10
-	id, err := client.CreateBill(BillInput{
+	id, err := client.CreateBill(context.Background(), BillInput{
 		ContactID:  &contactID,
 		BillNumber: "BILL-001",
 		Amount:     10050,
@@ -102,14 +110,18 @@ func TestCreateBill(t *testing.T) {
 func TestCreatePayout(t *testing.T) {
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		if r.Method == "POST" && r.URL.Path == "/api/v1/payouts" {
+			body, _ := io.ReadAll(r.Body)
+			if !strings.Contains(string(body), `"final_payout_amt":340000.10`) {
+				t.Errorf("Expected exact amount 340000.10 in %s", body)
+			}
 			var input PayoutInput
-			json.NewDecoder(r.Body).Decode(&input)
-			if input.FinalPayoutAmt != 340000 {
-				t.Errorf("Expected amount 340000, got %d", input.FinalPayoutAmt)
+			json.Unmarshal(body, &input)
+			if input.FinalPayoutAmt.Minor != 34000010 {
+				t.Errorf("Expected amount 34000010 paise, got %d", input.FinalPayoutAmt.Minor)
 			}
 			w.Header().Set("Content-Type", "application/json")
 			w.WriteHeader(http.StatusCreated)
-			json.NewEncoder(w).Encode(Response[Payout]{Data: Payout{ID: 40, FinalPayoutAmt: 340000}})
+			json.NewEncoder(w).Encode(Response[Payout]{Data: Payout{ID: 40, FinalPayoutAmt: input.FinalPayoutAmt}})
 			return
 		}
 		t.Errorf("Unexpected request: %s %s", r.Method, r.URL.Path)
@@ -117,10 +129,10 @@ func TestCreatePayout(t *testing.T) {
 	defer server.Close()
 
 	client := NewClient(server.URL, "user", "pass")
-	id, err := client.CreatePayout(PayoutInput{
+	id, err := client.CreatePayout(context.Background(), PayoutInput{
 		OutletName:     "Test Outlet",
 		Platform:       "Swiggy",
-		FinalPayoutAmt: 340000,
+		FinalPayoutAmt: money.MustParse("3,40,000.10"),
 	})
 	if err != nil {
 		t.Fatalf("Expected no error, got %v", err)
@@ -129,3 +141,117 @@ func TestCreatePayout(t *testing.T) {
 		t.Errorf("Expected ID 40, got %d", id)
 	}
 }
+
+func TestCreateBill_ServerErrorNotRetried(t *testing.T) {
+	// The first attempt may have been committed; a retry would duplicate
+	// the bill.
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		http.Error(w, "temporarily unavailable", http.StatusBadGateway)
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL, "user", "pass")
+	client.SetRetryPolicy(retry.Policy{MaxAttempts: 3})
+	_, err := client.CreateBill(context.Background(), BillInput{BillNumber: "BILL-002", Amount: 100})
+	if err == nil {
+		t.Fatal("Expected error for 502 response")
+	}
+	if !retry.IsRetryable(err) {
+		t.Errorf("Expected 502 to be reported as transient, got %v", err)
+	}
+	if calls != 1 {
+		t.Errorf("Expected 1 call, got %d", calls)
+	}
+}
+
+func TestUpdatePayout_RetriesServerError(t *testing.T) {
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if calls == 1 {
+			http.Error(w, "temporarily unavailable", http.StatusBadGateway)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(Response[Payout]{Data: Payout{ID: 40}})
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL, "user", "pass")
+	client.SetRetryPolicy(retry.Policy{MaxAttempts: 3})
+	if err := client.UpdatePayout(context.Background(), 40, PayoutInput{UtrNumber: "UTR123"}); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if calls != 2 {
+		t.Errorf("Expected 2 calls, got %d", calls)
+	}
+}
+
+func TestFindVendor_CancelStopsRetries(t *testing.T) {
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		http.Error(w, "temporarily unavailable", http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL, "user", "pass")
+	client.SetRetryPolicy(retry.Policy{MaxAttempts: 5, InitialBackoff: time.Minute})
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	_, _, err := client.FindVendor(ctx, "Acme Corp")
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected the context error, got %v", err)
+	}
+	if calls != 1 || time.Since(start) > 5*time.Second {
+		t.Errorf("Expected 1 call before the context ended, got %d calls in %s", calls, time.Since(start))
+	}
+}
+
+func TestCreateBill_ValidationErrorNotRetried(t *testing.T) {
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		http.Error(w, "amount must be positive", http.StatusUnprocessableEntity)
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL, "user", "pass")
+	client.SetRetryPolicy(retry.Policy{MaxAttempts: 3})
+	_, err := client.CreateBill(context.Background(), BillInput{BillNumber: "BILL-003"})
+	if err == nil {
+		t.Fatal("Expected error for 422 response")
+	}
+	if retry.IsRetryable(err) {
+		t.Errorf("Expected 422 to be permanent, got retryable: %v", err)
+	}
+	if calls != 1 {
+		t.Errorf("Expected 1 call, got %d", calls)
+	}
+}
+
+func TestUpdatePayout(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "PUT" && r.URL.Path == "/api/v1/payouts/40" {
+			var input PayoutInput
+			json.NewDecoder(r.Body).Decode(&input)
+			if input.UtrNumber != "UTR123" {
+				t.Errorf("Expected UTR UTR123, got %s", input.UtrNumber)
+			}
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(Response[Payout]{Data: Payout{ID: 40}})
+			return
+		}
+		t.Errorf("Unexpected request: %s %s", r.Method, r.URL.Path)
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL, "user", "pass")
+	if err := client.UpdatePayout(context.Background(), 40, PayoutInput{UtrNumber: "UTR123"}); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+}
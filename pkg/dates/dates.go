@@ -0,0 +1,181 @@
+// Package dates normalizes the dates printed on invoices, bank statements
+// and payout reports ("03-DEC-2025", "3rd Dec '25", "03/12/2025") to
+// YYYY-MM-DD.
+package dates
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// ISO is the layout every date is normalized to.
+const ISO = "2006-01-02"
+
+// Order says how an all-numeric date such as 03/04/2025 is read.
+type Order string
+
+const (
+	DayFirst   Order = "DMY" // 03/04/2025 is 3 April, as in India
+	MonthFirst Order = "MDY" // 03/04/2025 is 4 March
+)
+
+// AmbiguousError describes an all-numeric date that reads as a valid but
+// different date day first and month first, e.g. 03/04/2025.  Parse takes
+// the reading in the parser's Order; Ambiguity reports the date so callers
+// can flag it for review.  Listing a layout in Parser.Layouts settles it.
+type AmbiguousError struct {
+	Value string
+	// Preferred is the reading in the parser's Order, Other the reading in
+	// the other order.
+	Preferred, Other time.Time
+}
+
+func (e *AmbiguousError) Error() string {
+	return fmt.Sprintf("date %q is ambiguous: read as %s, could be %s", e.Value, e.Preferred.Format(ISO), e.Other.Format(ISO))
+}
+
+// Parser reads dates.  The zero value reads day first with the built-in
+// layouts only.
+type Parser struct {
+	// Layouts are Go time layouts tried before the built-in ones.  A match
+	// is taken as is, so listing e.g. "02/01/2006" makes numeric dates day
+	// first without them being reported as ambiguous.
+	Layouts []string
+	// Order is how numeric dates are read; empty means DayFirst.  When only
+	// one reading is a valid date, that one is used whatever the order.
+	Order Order
+}
+
+// textLayouts cannot be misread: the year comes first or the month is
+// spelled out.
+var textLayouts = []string{
+	ISO,
+	time.RFC3339,
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05",
+	"2006/01/02",
+	"2006.01.02",
+	"2 Jan 2006",
+	"2 January 2006",
+	"2-Jan-2006",
+	"2-January-2006",
+	"2/Jan/2006",
+	"2.Jan.2006",
+	"2 Jan 06",
+	"2-Jan-06",
+	"2/Jan/06",
+	"Jan 2 2006",
+	"Jan 2, 2006",
+	"January 2 2006",
+	"January 2, 2006",
+	"Mon, 2 Jan 2006",
+	"Monday, 2 January 2006",
+	"Monday, January 2, 2006",
+}
+
+// dayFirstLayouts are the numeric layouts read day first; monthFirst
+// derives their month-first twins.
+var dayFirstLayouts = []string{
+	"2/1/2006", "2-1-2006", "2.1.2006",
+	"2/1/06", "2-1-06", "2.1.06",
+}
+
+var (
+	ordinalRe    = regexp.MustCompile(`(?i)\b(\d{1,2})(st|nd|rd|th)\b`)
+	apostropheRe = regexp.MustCompile(`['’](\d{2})\b`)
+	timeRe       = regexp.MustCompile(`[ T]\d{1,2}:\d{2}(:\d{2})?(\s*[AaPp][Mm])?$`)
+	sept         = regexp.MustCompile(`(?i)\bsept\b`)
+)
+
+// Parse reads s as a date.  A numeric date that reads validly both day
+// first and month first is read in p.Order; see Ambiguity.
+func (p Parser) Parse(s string) (time.Time, error) {
+	t, _, err := p.read(s)
+	return t, err
+}
+
+// Ambiguity returns the *AmbiguousError for a numeric date s that Parse
+// read in p.Order although the other order also gives a valid date, and
+// nil otherwise.
+func (p Parser) Ambiguity(s string) *AmbiguousError {
+	_, amb, _ := p.read(s)
+	return amb
+}
+
+// read parses s, also returning the ambiguity resolved by p.Order.
+func (p Parser) read(s string) (time.Time, *AmbiguousError, error) {
+	value := clean(s)
+	if value == "" {
+		return time.Time{}, nil, fmt.Errorf("empty date")
+	}
+	for _, layout := range p.Layouts {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t, nil, nil
+		}
+	}
+	for _, layout := range textLayouts {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t, nil, nil
+		}
+	}
+
+	for _, dmy := range dayFirstLayouts {
+		day, dayErr := time.Parse(dmy, value)
+		month, monthErr := time.Parse(monthFirst(dmy), value)
+		switch {
+		case dayErr == nil && monthErr == nil && !day.Equal(month):
+			if p.Order == MonthFirst {
+				return month, &AmbiguousError{Value: s, Preferred: month, Other: day}, nil
+			}
+			return day, &AmbiguousError{Value: s, Preferred: day, Other: month}, nil
+		case dayErr == nil:
+			return day, nil, nil
+		case monthErr == nil:
+			return month, nil, nil
+		}
+	}
+	return time.Time{}, nil, fmt.Errorf("unrecognised date %q", s)
+}
+
+// Normalize returns s as YYYY-MM-DD.
+func (p Parser) Normalize(s string) (string, error) {
+	t, err := p.Parse(s)
+	if err != nil {
+		return "", err
+	}
+	return t.Format(ISO), nil
+}
+
+// clean strips what the layouts cannot express: ordinal suffixes ("3rd"),
+// apostrophe years ("'25"), a trailing time of day, "Sept" and repeated
+// spaces.
+func clean(s string) string {
+	s = strings.Join(strings.Fields(s), " ")
+	s = timeRe.ReplaceAllString(s, "")
+	s = ordinalRe.ReplaceAllString(s, "$1")
+	s = apostropheRe.ReplaceAllString(s, "$1")
+	s = sept.ReplaceAllString(s, "Sep")
+	return strings.TrimSpace(s)
+}
+
+// monthFirst swaps the day and month of a numeric layout: "2/1/2006"
+// becomes "1/2/2006".
+func monthFirst(layout string) string {
+	return strings.NewReplacer("2", "1", "1", "2").Replace(layout[:3]) + layout[3:]
+}
+
+// FromParts builds a date from year, month and day numbers such as those of
+// Document AI's DateValue.  ok is false when any part is missing or the
+// date does not exist.
+func FromParts(year, month, day int) (t time.Time, ok bool) {
+	if year <= 0 || month <= 0 || day <= 0 {
+		return time.Time{}, false
+	}
+	t = time.Date(year, time.Month(month), day, 0, 0, 0, 0, time.UTC)
+	if t.Year() != year || int(t.Month()) != month || t.Day() != day {
+		return time.Time{}, false
+	}
+	return t, true
+}
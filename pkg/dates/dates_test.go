@@ -0,0 +1,85 @@
+package dates
+
+import "testing"
+
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"2025-12-03", "2025-12-03"},
+		{"2025-12-03T10:15:00Z", "2025-12-03"},
+		{"03-DEC-2025", "2025-12-03"},
+		{"3rd Dec '25", "2025-12-03"},
+		{"3 December 2025", "2025-12-03"},
+		{"Dec 3, 2025", "2025-12-03"},
+		{"21st Sept 2025", "2025-09-21"},
+		{"13/04/2025", "2025-04-13"},
+		{"04/13/2025", "2025-04-13"}, // only valid month first
+		{"13.04.25", "2025-04-13"},
+		{"05/05/2025", "2025-05-05"}, // same either way
+		{"13-04-2025 14:30", "2025-04-13"},
+	}
+	for _, tt := range tests {
+		got, err := Parser{}.Normalize(tt.in)
+		if err != nil {
+			t.Errorf("Normalize(%q): %v", tt.in, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("Normalize(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestAmbiguous(t *testing.T) {
+	p := Parser{Order: DayFirst}
+	if got, err := p.Normalize("03/04/2025"); err != nil || got != "2025-04-03" {
+		t.Errorf("DMY Normalize(03/04/2025) = %q, %v; want 3 April", got, err)
+	}
+	amb := p.Ambiguity("03/04/2025")
+	if amb == nil || amb.Preferred.Format(ISO) != "2025-04-03" || amb.Other.Format(ISO) != "2025-03-04" {
+		t.Errorf("DMY Ambiguity(03/04/2025) = %v", amb)
+	}
+
+	p = Parser{Order: MonthFirst}
+	if got, err := p.Normalize("03/04/2025"); err != nil || got != "2025-03-04" {
+		t.Errorf("MDY Normalize(03/04/2025) = %q, %v; want 4 March", got, err)
+	}
+	if amb := p.Ambiguity("03/04/2025"); amb == nil || amb.Preferred.Format(ISO) != "2025-03-04" {
+		t.Errorf("MDY Ambiguity(03/04/2025) = %v", amb)
+	}
+
+	for _, in := range []string{"13/04/2025", "05/05/2025", "2025-04-03"} {
+		if amb := p.Ambiguity(in); amb != nil {
+			t.Errorf("Ambiguity(%q) = %v, want nil", in, amb)
+		}
+	}
+}
+
+func TestConfiguredLayouts(t *testing.T) {
+	p := Parser{Layouts: []string{"02/01/2006", "20060102"}}
+	for in, want := range map[string]string{"03/04/2025": "2025-04-03", "20251203": "2025-12-03"} {
+		if got, err := p.Normalize(in); err != nil || got != want {
+			t.Errorf("Normalize(%q) = %q, %v; want %q", in, got, err, want)
+		}
+	}
+}
+
+func TestInvalid(t *testing.T) {
+	for _, in := range []string{"", "sometime", "31/02/2025", "32/01/2025"} {
+		if got, err := (Parser{}).Normalize(in); err == nil {
+			t.Errorf("Normalize(%q) = %q, want error", in, got)
+		}
+	}
+}
+
+func TestFromParts(t *testing.T) {
+	if d, ok := FromParts(2025, 12, 3); !ok || d.Format(ISO) != "2025-12-03" {
+		t.Errorf("FromParts(2025, 12, 3) = %v, %v", d, ok)
+	}
+	for _, p := range [][3]int{{0, 12, 3}, {2025, 0, 3}, {2025, 2, 30}} {
+		if _, ok := FromParts(p[0], p[1], p[2]); ok {
+			t.Errorf("FromParts(%v) ok, want not ok", p)
+		}
+	}
+}
@@ -6,6 +6,10 @@ import (
 	"log/slog"
 	"strings"
 
+	"paperless-document-processor/pkg/dates"
+	"paperless-document-processor/pkg/money"
+	"paperless-document-processor/pkg/retry"
+
 	documentai "cloud.google.com/go/documentai/apiv1"
 	"cloud.google.com/go/documentai/apiv1/documentaipb"
 	"google.golang.org/api/option"
@@ -17,14 +21,51 @@ type Client struct {
 	projectID   string
 	location    string
 	processorID string
+	retry       retry.Policy
 }
 
 type ExtractedData struct {
-	Text        string
-	ExampleDate string // Just a placeholder, actual extraction depends on entities
-	TotalAmount string
+	Text string
+	// ExampleDate is the invoice_date entity: DocAI's date value as
+	// YYYY-MM-DD when it has one, else the date as printed.
+	ExampleDate string
+	// DueDate is the due_date entity, read like ExampleDate; empty when
+	// the invoice prints none.
+	DueDate string
+	// TotalAmount is the total_amount entity; zero when missing or
+	// unreadable.  Its currency comes from the amount or the currency entity.
+	TotalAmount money.Money
 	Supplier    string
 	Entities    map[string]string
+	// Normalized holds DocAI's normalized value (ISO date, plain number,
+	// currency code) for entities that have one, keyed like Entities.
+	Normalized map[string]string
+	// LineItems are the invoice's line_item entities in document order.
+	// Entities only keeps the last of them.
+	LineItems []LineItem
+	// TaxItems are the invoice's vat entities (one per tax line, e.g. CGST
+	// and SGST) in document order.
+	TaxItems []TaxItem
+}
+
+// TaxItem holds the properties of a vat entity as text.  CategoryCode is
+// the tax name as printed (e.g. "CGST").
+type TaxItem struct {
+	CategoryCode string
+	TaxRate      string
+	TaxAmount    string
+}
+
+// LineItem holds the properties of a line_item entity as text, using DocAI's
+// normalized value when there is one.  Properties the processor did not
+// find are empty.
+type LineItem struct {
+	Description string
+	Quantity    string
+	Unit        string
+	UnitPrice   string
+	Amount      string
+	ProductCode string
 }
 
 func NewClient(ctx context.Context, projectID, location, processorID, credentialsPath string) (*Client, error) {
@@ -54,7 +95,31 @@ func NewClient(ctx context.Context, projectID, location, processorID, credential
 	}, nil
 }
 
+// SetRetryPolicy configures how transient gRPC failures (Unavailable,
+// ResourceExhausted, DeadlineExceeded) are retried.  The default is a single
+// attempt.
+func (c *Client) SetRetryPolicy(p retry.Policy) {
+	c.retry = p
+}
+
 func (c *Client) ProcessDocument(ctx context.Context, processorID string, fileContent []byte, mimeType string) (*documentaipb.Document, error) {
+	return c.process(ctx, processorID, fileContent, mimeType, "text", "entities")
+}
+
+// ProcessTables runs the document through processorID (a Form Parser or
+// Layout Parser) and returns its tables as cell text grids, one [][]string
+// per table in page order with the header rows first.
+func (c *Client) ProcessTables(ctx context.Context, processorID string, fileContent []byte, mimeType string) ([][][]string, error) {
+	doc, err := c.process(ctx, processorID, fileContent, mimeType, "text", "pages.tables")
+	if err != nil {
+		return nil, err
+	}
+	return c.ExtractTables(doc), nil
+}
+
+// process sends the document to the processor, asking only for the given
+// Document fields.
+func (c *Client) process(ctx context.Context, processorID string, fileContent []byte, mimeType string, fields ...string) (*documentaipb.Document, error) {
 	if len(fileContent) == 0 {
 		slog.Error("Document AI: attempt to process empty file content")
 		return nil, fmt.Errorf("file content is empty")
@@ -71,7 +136,7 @@ func (c *Client) ProcessDocument(ctx context.Context, processorID string, fileCo
 	req := &documentaipb.ProcessRequest{
 		Name: name,
 		FieldMask: &fieldmaskpb.FieldMask{
-			Paths: []string{"text", "entities"},
+			Paths: fields,
 		},
 		Source: &documentaipb.ProcessRequest_RawDocument{
 			RawDocument: &documentaipb.RawDocument{
@@ -82,7 +147,12 @@ func (c *Client) ProcessDocument(ctx context.Context, processorID string, fileCo
 	}
 
 	slog.Info("Sending document to Google Cloud Document AI", "processor_id", c.processorID)
-	resp, err := c.client.ProcessDocument(ctx, req)
+	var resp *documentaipb.ProcessResponse
+	err := retry.Do(ctx, c.retry, "docai ProcessDocument", func() error {
+		var err error
+		resp, err = c.client.ProcessDocument(ctx, req)
+		return err
+	})
 	if err != nil {
 		slog.Error("Document AI processing failed", "error", err)
 		return nil, fmt.Errorf("failed to process document: %w", err)
@@ -120,6 +190,10 @@ func (c *Client) ExtractBankStatementData(doc *documentaipb.Document) []map[stri
 				val = prop.TextAnchor.Content
 			}
 
+			if d := isoDate(prop.NormalizedValue); d != "" {
+				val = d
+			}
+
 			switch pType {
 			case "transaction_withdrawal_date", "transaction_deposit_date":
 				if _, exists := tx["date"]; !exists { // first date wins
@@ -187,8 +261,9 @@ func (c *Client) ExtractBankStatementData(doc *documentaipb.Document) []map[stri
 
 func (c *Client) ExtractData(doc *documentaipb.Document) *ExtractedData {
 	data := &ExtractedData{
-		Text:     doc.Text,
-		Entities: make(map[string]string),
+		Text:       doc.Text,
+		Entities:   make(map[string]string),
+		Normalized: make(map[string]string),
 	}
 
 	// Iterate specific entities for Invoice Parser
@@ -205,43 +280,184 @@ func (c *Client) ExtractData(doc *documentaipb.Document) *ExtractedData {
 
 		// Normalize key if necessary (e.g. remove "invoice_" prefix)
 		data.Entities[key] = val
+		if entity.NormalizedValue != nil && entity.NormalizedValue.Text != "" {
+			data.Normalized[key] = entity.NormalizedValue.Text
+		}
 
 		// Quick access fields
 		switch key {
+		case "line_item":
+			data.LineItems = append(data.LineItems, lineItem(entity))
+		case "vat":
+			data.TaxItems = append(data.TaxItems, taxItem(entity))
 		case "invoice_date":
 			data.ExampleDate = val
-		case "total_amount":
-			data.TotalAmount = val
-			// Check for normalized value if available
-			if entity.NormalizedValue != nil {
-				data.TotalAmount = entity.NormalizedValue.Text
-				slog.Debug("Using normalized amount", "amount", data.TotalAmount)
+			if d := isoDate(entity.NormalizedValue); d != "" {
+				data.ExampleDate = d
 			}
-			//a number with exactly two decimals
-			parts := strings.Split(data.TotalAmount, ".")
-			switch len(parts) {
-			case 1:
-				data.TotalAmount += ".00"
-			case 2:
-				if len(parts[1]) > 2 {
-					parts[1] = parts[1][:2]
-				} else if len(parts[1]) < 2 {
-					for len(parts[1]) < 2 {
-						parts[1] += "0"
-					}
-				}
-				data.TotalAmount = strings.Join(parts, ".")
+		case "due_date":
+			data.DueDate = val
+			if d := isoDate(entity.NormalizedValue); d != "" {
+				data.DueDate = d
 			}
+		case "total_amount":
+			total, err := amount(entity, val)
+			if err != nil {
+				slog.Warn("Unreadable total amount", "amount", val, "error", err)
+			}
+			data.TotalAmount = total
 
 		case "supplier_name":
 			data.Supplier = val
 		}
 	}
 
-	slog.Info("Entity extraction completed", "entities_count", len(doc.Entities))
+	if cur := strings.ToUpper(data.Normalized["currency"]); data.TotalAmount.Currency == "" && len(cur) == 3 {
+		data.TotalAmount.Currency = cur
+	}
+
+	slog.Info("Entity extraction completed", "entities_count", len(doc.Entities), "line_items", len(data.LineItems))
 	return data
 }
 
+// amount reads a money entity: DocAI's structured money value when it has
+// one, else the normalized text, else text, the amount as printed.
+func amount(entity *documentaipb.Document_Entity, text string) (money.Money, error) {
+	if mv := entity.NormalizedValue.GetMoneyValue(); mv != nil {
+		return money.FromUnits(mv.GetUnits(), mv.GetNanos(), mv.GetCurrencyCode()), nil
+	}
+	if norm := entity.NormalizedValue.GetText(); norm != "" {
+		if m, err := money.Parse(norm); err == nil {
+			return m, nil
+		}
+	}
+	return money.Parse(text)
+}
+
+// isoDate returns DocAI's structured date value as YYYY-MM-DD, or "" when
+// there is none or it is incomplete.
+func isoDate(nv *documentaipb.Document_Entity_NormalizedValue) string {
+	dv := nv.GetDateValue()
+	if dv == nil {
+		return ""
+	}
+	t, ok := dates.FromParts(int(dv.GetYear()), int(dv.GetMonth()), int(dv.GetDay()))
+	if !ok {
+		return ""
+	}
+	return t.Format(dates.ISO)
+}
+
+// property returns a child entity's name without its parent prefix
+// ("line_item/amount" is "amount") and its value, normalized when DocAI
+// provides one, with whitespace collapsed.
+func property(prop *documentaipb.Document_Entity) (name, value string) {
+	name = prop.Type
+	if i := strings.LastIndex(name, "/"); i >= 0 {
+		name = name[i+1:]
+	}
+	value = prop.MentionText
+	if prop.NormalizedValue != nil && prop.NormalizedValue.Text != "" {
+		value = prop.NormalizedValue.Text
+	}
+	return name, strings.Join(strings.Fields(value), " ")
+}
+
+// lineItem reads the "line_item/<property>" properties of a line_item
+// entity.  A line item without properties keeps its text as description.
+func lineItem(entity *documentaipb.Document_Entity) LineItem {
+	var item LineItem
+	for _, prop := range entity.Properties {
+		name, val := property(prop)
+		switch name {
+		case "description":
+			// Keep the text as printed; descriptions have no normalized
+			// form and may span lines.
+			item.Description = strings.Join(strings.Fields(prop.MentionText), " ")
+		case "quantity":
+			item.Quantity = val
+		case "unit":
+			item.Unit = val
+		case "unit_price":
+			item.UnitPrice = val
+		case "amount":
+			item.Amount = val
+		case "product_code":
+			item.ProductCode = val
+		}
+	}
+	if len(entity.Properties) == 0 {
+		item.Description = strings.Join(strings.Fields(entity.MentionText), " ")
+	}
+	return item
+}
+
+// taxItem reads the "vat/<property>" properties of a vat entity.
+func taxItem(entity *documentaipb.Document_Entity) TaxItem {
+	var item TaxItem
+	for _, prop := range entity.Properties {
+		name, val := property(prop)
+		switch name {
+		case "category_code":
+			item.CategoryCode = val
+		case "tax_rate":
+			item.TaxRate = val
+		case "tax_amount":
+			item.TaxAmount = val
+		}
+	}
+	return item
+}
+
+// ExtractTables returns the text of every table cell, one grid per table
+// with the header rows first.  Rows keep their own cell count; tables split
+// across pages come back as separate tables.
+func (c *Client) ExtractTables(doc *documentaipb.Document) [][][]string {
+	text := []rune(doc.Text)
+	var tables [][][]string
+	for _, page := range doc.GetPages() {
+		for _, t := range page.GetTables() {
+			rows := make([]*documentaipb.Document_Page_Table_TableRow, 0, len(t.GetHeaderRows())+len(t.GetBodyRows()))
+			rows = append(rows, t.GetHeaderRows()...)
+			rows = append(rows, t.GetBodyRows()...)
+			var grid [][]string
+			for _, row := range rows {
+				cells := make([]string, 0, len(row.GetCells()))
+				for _, cell := range row.GetCells() {
+					cells = append(cells, anchorText(text, cell.GetLayout().GetTextAnchor()))
+				}
+				grid = append(grid, cells)
+			}
+			if len(grid) > 0 {
+				tables = append(tables, grid)
+			}
+		}
+	}
+	slog.Info("Table extraction completed", "tables_count", len(tables))
+	return tables
+}
+
+// anchorText joins the document text the anchor's segments point at,
+// collapsing whitespace.  Segment indexes count characters, not bytes.
+func anchorText(text []rune, anchor *documentaipb.Document_TextAnchor) string {
+	if anchor == nil {
+		return ""
+	}
+	if len(anchor.TextSegments) == 0 {
+		return strings.Join(strings.Fields(anchor.Content), " ")
+	}
+	var b strings.Builder
+	for _, seg := range anchor.TextSegments {
+		start, end := int(seg.StartIndex), int(seg.EndIndex)
+		if start < 0 || end > len(text) || start > end {
+			continue
+		}
+		b.WriteString(string(text[start:end]))
+		b.WriteByte(' ')
+	}
+	return strings.Join(strings.Fields(b.String()), " ")
+}
+
 func (c *Client) Close() error {
 	return c.client.Close()
 }
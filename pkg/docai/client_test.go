@@ -3,7 +3,10 @@ package docai
 import (
 	"testing"
 
+	"paperless-document-processor/pkg/money"
+
 	"cloud.google.com/go/documentai/apiv1/documentaipb"
+	"google.golang.org/genproto/googleapis/type/date"
 )
 
 // Helper to create a fake document entity
@@ -40,8 +43,8 @@ func TestExtractData(t *testing.T) {
 		t.Errorf("Expected date '2023-10-25', got '%s'", extracted.ExampleDate)
 	}
 
-	if extracted.TotalAmount != "100.50" {
-		t.Errorf("Expected total '100.50', got '%s'", extracted.TotalAmount)
+	if want := money.New(10050, "USD"); extracted.TotalAmount != want {
+		t.Errorf("Expected total %+v, got %+v", want, extracted.TotalAmount)
 	}
 
 	if extracted.Supplier != "Acme Corp" {
@@ -51,6 +54,13 @@ func TestExtractData(t *testing.T) {
 	if val, ok := extracted.Entities["currency"]; !ok || val != "$" {
 		t.Errorf("Expected currency entity '$', got '%v'", val)
 	}
+
+	if val := extracted.Normalized["currency"]; val != "USD" {
+		t.Errorf("Expected normalized currency 'USD', got '%v'", val)
+	}
+	if _, ok := extracted.Normalized["supplier_name"]; ok {
+		t.Errorf("Expected no normalized value for supplier_name")
+	}
 }
 
 func TestExtractData_Fallback(t *testing.T) {
@@ -72,3 +82,141 @@ func TestExtractData_Fallback(t *testing.T) {
 		t.Errorf("Expected date '2023-01-01', got '%s'", extracted.ExampleDate)
 	}
 }
+
+func TestExtractData_DateValue(t *testing.T) {
+	doc := &documentaipb.Document{
+		Entities: []*documentaipb.Document_Entity{
+			createEntity("invoice_date", "03/04/2025", "03/04/2025", &documentaipb.Document_Entity_NormalizedValue{
+				StructuredValue: &documentaipb.Document_Entity_NormalizedValue_DateValue{
+					DateValue: &date.Date{Year: 2025, Month: 4, Day: 3},
+				},
+			}),
+			createEntity("due_date", "3rd May '25", "3rd May '25", &documentaipb.Document_Entity_NormalizedValue{
+				StructuredValue: &documentaipb.Document_Entity_NormalizedValue_DateValue{
+					DateValue: &date.Date{Year: 2025, Month: 5, Day: 3},
+				},
+			}),
+		},
+	}
+
+	extracted := (&Client{}).ExtractData(doc)
+
+	if extracted.ExampleDate != "2025-04-03" {
+		t.Errorf("Expected date '2025-04-03', got '%s'", extracted.ExampleDate)
+	}
+	if extracted.DueDate != "2025-05-03" {
+		t.Errorf("Expected due date '2025-05-03', got '%s'", extracted.DueDate)
+	}
+}
+
+func TestExtractData_TotalAmountAsPrinted(t *testing.T) {
+	doc := &documentaipb.Document{
+		Entities: []*documentaipb.Document_Entity{
+			createEntity("total_amount", "₹1,23,456.78", "₹1,23,456.78", nil),
+		},
+	}
+
+	extracted := (&Client{}).ExtractData(doc)
+
+	if want := money.New(12345678, "INR"); extracted.TotalAmount != want {
+		t.Errorf("Expected total %+v, got %+v", want, extracted.TotalAmount)
+	}
+}
+
+func TestExtractTables(t *testing.T) {
+	text := "Order ID Amount\n101 ₹250.00\n"
+	seg := func(start, end int64) *documentaipb.Document_Page_Table_TableCell {
+		return &documentaipb.Document_Page_Table_TableCell{Layout: &documentaipb.Document_Page_Layout{
+			TextAnchor: &documentaipb.Document_TextAnchor{TextSegments: []*documentaipb.Document_TextAnchor_TextSegment{{StartIndex: start, EndIndex: end}}},
+		}}
+	}
+	doc := &documentaipb.Document{
+		Text: text,
+		Pages: []*documentaipb.Document_Page{{Tables: []*documentaipb.Document_Page_Table{{
+			HeaderRows: []*documentaipb.Document_Page_Table_TableRow{{Cells: []*documentaipb.Document_Page_Table_TableCell{seg(0, 8), seg(9, 16)}}},
+			// Indexes count characters, so the cell after the rupee sign
+			// still lines up.
+			BodyRows: []*documentaipb.Document_Page_Table_TableRow{{Cells: []*documentaipb.Document_Page_Table_TableCell{seg(16, 20), seg(20, 27)}}},
+		}}}},
+	}
+
+	got := (&Client{}).ExtractTables(doc)
+	if len(got) != 1 || len(got[0]) != 2 {
+		t.Fatalf("expected one table with two rows, got %q", got)
+	}
+	if got[0][0][0] != "Order ID" || got[0][0][1] != "Amount" {
+		t.Errorf("header row = %q", got[0][0])
+	}
+	if got[0][1][0] != "101" || got[0][1][1] != "₹250.00" {
+		t.Errorf("body row = %q", got[0][1])
+	}
+}
+
+func TestExtractDataLineItems(t *testing.T) {
+	prop := func(typ, mention string, normalized string) *documentaipb.Document_Entity {
+		var nv *documentaipb.Document_Entity_NormalizedValue
+		if normalized != "" {
+			nv = &documentaipb.Document_Entity_NormalizedValue{Text: normalized}
+		}
+		return createEntity(typ, mention, mention, nv)
+	}
+	item := func(props ...*documentaipb.Document_Entity) *documentaipb.Document_Entity {
+		e := createEntity("line_item", "", "", nil)
+		e.Properties = props
+		return e
+	}
+	doc := &documentaipb.Document{Entities: []*documentaipb.Document_Entity{
+		item(
+			prop("line_item/description", "Basmati Rice\n25 kg bag", ""),
+			prop("line_item/quantity", "2", "2"),
+			prop("line_item/unit_price", "₹1,800.00", "1800"),
+			prop("line_item/amount", "₹3,600.00", "3600"),
+			prop("line_item/product_code", "1006", ""),
+		),
+		item(prop("line_item/description", "Delivery", ""), prop("line_item/amount", "150", "")),
+		createEntity("line_item", "Packing charges 40", "Packing charges 40", nil),
+	}}
+
+	got := (&Client{}).ExtractData(doc).LineItems
+	want := []LineItem{
+		{Description: "Basmati Rice 25 kg bag", Quantity: "2", UnitPrice: "1800", Amount: "3600", ProductCode: "1006"},
+		{Description: "Delivery", Amount: "150"},
+		{Description: "Packing charges 40"},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("got %d line items, want %d: %+v", len(got), len(want), got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("line item %d = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+}
+
+func TestExtractDataTaxItems(t *testing.T) {
+	vat := func(category, rate, amount string) *documentaipb.Document_Entity {
+		e := createEntity("vat", "", "", nil)
+		e.Properties = []*documentaipb.Document_Entity{
+			createEntity("vat/category_code", category, category, nil),
+			createEntity("vat/tax_rate", rate, rate, nil),
+			createEntity("vat/tax_amount", amount, amount, &documentaipb.Document_Entity_NormalizedValue{Text: "450"}),
+		}
+		return e
+	}
+	doc := &documentaipb.Document{Entities: []*documentaipb.Document_Entity{
+		vat("CGST", "9%", "₹450.00"),
+		vat("SGST", "9%", "₹450.00"),
+	}}
+
+	got := (&Client{}).ExtractData(doc).TaxItems
+	want := TaxItem{CategoryCode: "CGST", TaxRate: "9%", TaxAmount: "450"}
+	if len(got) != 2 {
+		t.Fatalf("got %d tax items, want 2: %+v", len(got), got)
+	}
+	if got[0] != want {
+		t.Errorf("tax item 0 = %+v, want %+v", got[0], want)
+	}
+	if got[1].CategoryCode != "SGST" {
+		t.Errorf("tax item 1 category = %q, want SGST", got[1].CategoryCode)
+	}
+}
@@ -0,0 +1,260 @@
+package excel
+
+import (
+	"archive/zip"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"path"
+	"strconv"
+	"strings"
+)
+
+// Workbook reads sheet names and single cell values from an .xlsx file.  It
+// is meant for recognising a workbook, not for importing it: sheets are
+// streamed and only the requested cells are kept.  Legacy .xls files are not
+// supported.
+type Workbook struct {
+	zr     *zip.Reader
+	closer io.Closer
+	sheets []sheetRef
+	shared []string
+}
+
+// richText is a shared or inline string: plain text or formatted runs.
+type richText struct {
+	Text string `xml:"t"`
+	Runs []struct {
+		Text string `xml:"t"`
+	} `xml:"r"`
+}
+
+func (t richText) String() string {
+	text := t.Text
+	for _, r := range t.Runs {
+		text += r.Text
+	}
+	return text
+}
+
+type sheetRef struct {
+	name string
+	path string // zip entry of the worksheet part
+}
+
+// OpenWorkbook opens an .xlsx file.  The caller must Close it.
+func OpenWorkbook(filePath string) (*Workbook, error) {
+	rc, err := zip.OpenReader(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open workbook: %w", err)
+	}
+	wb, err := newWorkbook(&rc.Reader)
+	if err != nil {
+		rc.Close()
+		return nil, err
+	}
+	wb.closer = rc
+	return wb, nil
+}
+
+// ReadWorkbook reads an .xlsx file from r.
+func ReadWorkbook(r io.ReaderAt, size int64) (*Workbook, error) {
+	zr, err := zip.NewReader(r, size)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open workbook: %w", err)
+	}
+	return newWorkbook(zr)
+}
+
+func (w *Workbook) Close() error {
+	if w.closer == nil {
+		return nil
+	}
+	return w.closer.Close()
+}
+
+func newWorkbook(zr *zip.Reader) (*Workbook, error) {
+	w := &Workbook{zr: zr}
+
+	var book struct {
+		Sheets []struct {
+			Name string `xml:"name,attr"`
+			RID  string `xml:"id,attr"`
+		} `xml:"sheets>sheet"`
+	}
+	if err := w.decode("xl/workbook.xml", &book); err != nil {
+		return nil, err
+	}
+
+	var rels struct {
+		Relationships []struct {
+			ID     string `xml:"Id,attr"`
+			Target string `xml:"Target,attr"`
+		} `xml:"Relationship"`
+	}
+	if err := w.decode("xl/_rels/workbook.xml.rels", &rels); err != nil {
+		return nil, err
+	}
+	targets := make(map[string]string, len(rels.Relationships))
+	for _, r := range rels.Relationships {
+		if strings.HasPrefix(r.Target, "/") {
+			targets[r.ID] = strings.TrimPrefix(r.Target, "/")
+		} else {
+			targets[r.ID] = path.Join("xl", r.Target)
+		}
+	}
+	for _, s := range book.Sheets {
+		w.sheets = append(w.sheets, sheetRef{name: s.Name, path: targets[s.RID]})
+	}
+
+	// Workbooks without any text cells have no shared strings part.
+	if w.file("xl/sharedStrings.xml") != nil {
+		var sst struct {
+			Items []richText `xml:"si"`
+		}
+		if err := w.decode("xl/sharedStrings.xml", &sst); err != nil {
+			return nil, err
+		}
+		w.shared = make([]string, len(sst.Items))
+		for i, si := range sst.Items {
+			w.shared[i] = si.String()
+		}
+	}
+	return w, nil
+}
+
+// SheetNames lists the sheets in workbook order.
+func (w *Workbook) SheetNames() []string {
+	names := make([]string, len(w.sheets))
+	for i, s := range w.sheets {
+		names[i] = s.name
+	}
+	return names
+}
+
+// Cells returns the values of the given cells (e.g. "A1", "$C$4") of a
+// sheet, keyed by the references as given.  An empty sheet name selects the
+// first sheet; sheet names are matched ignoring case.  Empty cells are
+// missing from the result.  Numbers are returned as stored, booleans as
+// "TRUE"/"FALSE".
+func (w *Workbook) Cells(sheet string, refs []string) (map[string]string, error) {
+	ref, ok := w.sheet(sheet)
+	if !ok {
+		return nil, fmt.Errorf("sheet %q not found", sheet)
+	}
+	want := make(map[string][]string, len(refs))
+	for _, r := range refs {
+		norm := strings.ToUpper(strings.ReplaceAll(r, "$", ""))
+		want[norm] = append(want[norm], r)
+	}
+
+	f := w.file(ref.path)
+	if f == nil {
+		return nil, fmt.Errorf("sheet %q: part %s is missing", ref.name, ref.path)
+	}
+	rc, err := f.Open()
+	if err != nil {
+		return nil, fmt.Errorf("failed to open sheet %q: %w", ref.name, err)
+	}
+	defer rc.Close()
+
+	values := make(map[string]string, len(refs))
+	dec := xml.NewDecoder(rc)
+	for len(want) > 0 {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read sheet %q: %w", ref.name, err)
+		}
+		start, ok := tok.(xml.StartElement)
+		if !ok || start.Name.Local != "c" {
+			continue
+		}
+		var c struct {
+			Ref    string   `xml:"r,attr"`
+			Type   string   `xml:"t,attr"`
+			Value  string   `xml:"v"`
+			Inline richText `xml:"is"`
+		}
+		if err := dec.DecodeElement(&c, &start); err != nil {
+			return nil, fmt.Errorf("failed to read sheet %q: %w", ref.name, err)
+		}
+		asked, ok := want[c.Ref]
+		if !ok {
+			continue
+		}
+		delete(want, c.Ref)
+		v, err := w.cellValue(c.Type, c.Value, c.Inline)
+		if err != nil {
+			return nil, fmt.Errorf("sheet %q cell %s: %w", ref.name, c.Ref, err)
+		}
+		if v == "" {
+			continue
+		}
+		for _, r := range asked {
+			values[r] = v
+		}
+	}
+	return values, nil
+}
+
+func (w *Workbook) cellValue(typ, v string, inline richText) (string, error) {
+	switch typ {
+	case "s":
+		i, err := strconv.Atoi(strings.TrimSpace(v))
+		if err != nil || i < 0 || i >= len(w.shared) {
+			return "", fmt.Errorf("invalid shared string index %q", v)
+		}
+		return w.shared[i], nil
+	case "inlineStr":
+		return inline.String(), nil
+	case "b":
+		if v == "1" {
+			return "TRUE", nil
+		}
+		return "FALSE", nil
+	}
+	return v, nil
+}
+
+func (w *Workbook) sheet(name string) (sheetRef, bool) {
+	if name == "" {
+		if len(w.sheets) == 0 {
+			return sheetRef{}, false
+		}
+		return w.sheets[0], true
+	}
+	for _, s := range w.sheets {
+		if strings.EqualFold(s.name, name) {
+			return s, true
+		}
+	}
+	return sheetRef{}, false
+}
+
+func (w *Workbook) file(name string) *zip.File {
+	for _, f := range w.zr.File {
+		if f.Name == name {
+			return f
+		}
+	}
+	return nil
+}
+
+func (w *Workbook) decode(name string, v interface{}) error {
+	f := w.file(name)
+	if f == nil {
+		return fmt.Errorf("not an xlsx workbook: %s is missing", name)
+	}
+	rc, err := f.Open()
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %w", name, err)
+	}
+	defer rc.Close()
+	if err := xml.NewDecoder(rc).Decode(v); err != nil {
+		return fmt.Errorf("failed to parse %s: %w", name, err)
+	}
+	return nil
+}
@@ -0,0 +1,85 @@
+package excel
+
+import (
+	"archive/zip"
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+// buildWorkbook writes a minimal .xlsx with the given parts besides the
+// workbook relationships.
+func buildWorkbook(t *testing.T, parts map[string]string) *Workbook {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for name, content := range parts {
+		f, err := zw.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		f.Write([]byte(content))
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	wb, err := ReadWorkbook(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return wb
+}
+
+func TestWorkbookCells(t *testing.T) {
+	wb := buildWorkbook(t, map[string]string{
+		"xl/workbook.xml": `<?xml version="1.0" encoding="UTF-8"?>
+<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">
+<sheets><sheet name="Summary" sheetId="1" r:id="rId2"/><sheet name="Order Level" sheetId="2" r:id="rId1"/></sheets></workbook>`,
+		"xl/_rels/workbook.xml.rels": `<?xml version="1.0" encoding="UTF-8"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">
+<Relationship Id="rId1" Type="worksheet" Target="worksheets/sheet2.xml"/>
+<Relationship Id="rId2" Type="worksheet" Target="/xl/worksheets/sheet1.xml"/></Relationships>`,
+		"xl/sharedStrings.xml": `<sst xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">
+<si><t>Payout Summary</t></si><si><r><t>Order </t></r><r><t>ID</t></r></si></sst>`,
+		"xl/worksheets/sheet1.xml": `<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>
+<row r="1"><c r="A1" t="s"><v>0</v></c><c r="B1"><v>1250.5</v></c></row>
+<row r="2"><c r="A2" t="inlineStr"><is><t>Res id</t></is></c><c r="B2" t="b"><v>1</v></c><c r="C2"/></row></sheetData></worksheet>`,
+		"xl/worksheets/sheet2.xml": `<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>
+<row r="3"><c r="A3" t="s"><v>1</v></c></row></sheetData></worksheet>`,
+	})
+
+	if got, want := wb.SheetNames(), []string{"Summary", "Order Level"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("SheetNames() = %v, want %v", got, want)
+	}
+
+	got, err := wb.Cells("", []string{"A1", "$B$1", "A2", "B2", "C2", "Z9"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{"A1": "Payout Summary", "$B$1": "1250.5", "A2": "Res id", "B2": "TRUE"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Cells(first sheet) = %v, want %v", got, want)
+	}
+
+	got, err = wb.Cells("order level", []string{"A3"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got["A3"] != "Order ID" {
+		t.Errorf("rich text cell = %q, want %q", got["A3"], "Order ID")
+	}
+
+	if _, err := wb.Cells("Missing", []string{"A1"}); err == nil {
+		t.Errorf("expected an error for a missing sheet")
+	}
+}
+
+func TestReadWorkbookRejectsNonWorkbook(t *testing.T) {
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	zw.Create("word/document.xml")
+	zw.Close()
+	if _, err := ReadWorkbook(bytes.NewReader(buf.Bytes()), int64(buf.Len())); err == nil {
+		t.Errorf("expected an error for a zip without xl/workbook.xml")
+	}
+}
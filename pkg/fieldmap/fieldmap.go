@@ -0,0 +1,206 @@
+// Package fieldmap maps Document AI entity types to Paperless custom fields.
+// A mapping file lists, for each custom field, the entity it is filled from
+// and the text transforms applied before the value is coerced to the field's
+// data type.
+package fieldmap
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// Config is the contents of a mapping file, e.g.
+//
+//	{
+//	  "default_currency": "INR",
+//	  "mappings": [
+//	    {"entity": "invoice_id", "field": "Invoice Number", "transforms": ["trim", "upper"]},
+//	    {"entity": "total_amount", "field": "Total"}
+//	  ]
+//	}
+type Config struct {
+	// DefaultCurrency is used for monetary fields when neither the mapping,
+	// the document's currency entity, the currency printed with the amount
+	// nor the field's own default provides an ISO code.
+	DefaultCurrency string    `json:"default_currency,omitempty"`
+	Mappings        []Mapping `json:"mappings"`
+}
+
+// Mapping fills one custom field from one entity type.
+type Mapping struct {
+	Entity string `json:"entity"`
+	Field  string `json:"field"`
+	// Source selects the entity text: "normalized" (default) uses DocAI's
+	// normalized value when there is one, "mention" always uses the text as
+	// it appears in the document.
+	Source string `json:"source,omitempty"`
+	// Transforms are applied in order.  Supported: trim, upper, lower,
+	// single_line, digits, and regex:<pattern> which keeps the first capture
+	// group (or the whole match).
+	Transforms []string `json:"transforms,omitempty"`
+	// Currency overrides the currency of a monetary field (ISO code, or
+	// "entity:<type>" to read it from another entity).
+	Currency string `json:"currency,omitempty"`
+	// DataType is the Paperless data type the field is created with when it
+	// is missing and auto-creation is enabled.  Defaults to "string".
+	DataType string `json:"data_type,omitempty"`
+}
+
+const (
+	SourceNormalized = "normalized"
+	SourceMention    = "mention"
+)
+
+// Default reproduces the mapping that used to be hard-coded in the bill
+// pipeline.
+func Default() Config {
+	return Config{
+		DefaultCurrency: "INR",
+		Mappings: []Mapping{
+			{Entity: "invoice_date", Field: "Invoice Date", DataType: "date"},
+			{Entity: "total_amount", Field: "Total", Currency: "entity:currency", DataType: "monetary"},
+			{Entity: "total_amount", Field: "Amount", Currency: "entity:currency", DataType: "monetary"},
+			{Entity: "invoice_id", Field: "Invoice Number", Source: SourceMention, Transforms: []string{"trim", "single_line"}, DataType: "string"},
+			{Entity: "currency", Field: "Currency", DataType: "string"},
+			{Entity: "net_amount", Field: "Net Amount", Currency: "entity:currency", DataType: "monetary"},
+		},
+	}
+}
+
+// creatableTypes are the Paperless data types a missing field can be created
+// with; select and document link fields need settings a mapping cannot give.
+var creatableTypes = map[string]bool{
+	"string": true, "longtext": true, "url": true, "date": true, "boolean": true,
+	"integer": true, "float": true, "monetary": true,
+}
+
+// FieldDataType is the data type m's field is created with.
+func (m Mapping) FieldDataType() string {
+	if m.DataType == "" {
+		return "string"
+	}
+	return m.DataType
+}
+
+// Load reads a mapping file.  An empty path returns Default().
+func Load(path string) (Config, error) {
+	if path == "" {
+		return Default(), nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Config{}, fmt.Errorf("failed to read field mapping file: %w", err)
+	}
+	var cfg Config
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&cfg); err != nil {
+		return Config{}, fmt.Errorf("failed to parse field mapping file %s: %w", path, err)
+	}
+	if err := cfg.Validate(); err != nil {
+		return Config{}, fmt.Errorf("invalid field mapping file %s: %w", path, err)
+	}
+	return cfg, nil
+}
+
+// Validate checks that every mapping names an entity and field and uses
+// known sources and transforms.
+func (c Config) Validate() error {
+	for i, m := range c.Mappings {
+		if m.Entity == "" || m.Field == "" {
+			return fmt.Errorf("mapping %d: entity and field are required", i)
+		}
+		switch m.Source {
+		case "", SourceNormalized, SourceMention:
+		default:
+			return fmt.Errorf("mapping %d (%s): unknown source %q", i, m.Field, m.Source)
+		}
+		for _, t := range m.Transforms {
+			if _, err := Transform("", t); err != nil {
+				return fmt.Errorf("mapping %d (%s): %w", i, m.Field, err)
+			}
+		}
+		if m.DataType != "" && !creatableTypes[m.DataType] {
+			return fmt.Errorf("mapping %d (%s): data_type %q cannot be auto-created", i, m.Field, m.DataType)
+		}
+	}
+	return nil
+}
+
+// Value returns the entity text for m from the extracted entities and their
+// normalized values, with m's transforms applied.  ok is false when the
+// entity was not extracted or is empty after transforming.
+func (m Mapping) Value(entities, normalized map[string]string) (value string, ok bool, err error) {
+	value, found := entities[m.Entity]
+	if m.Source != SourceMention {
+		if n, has := normalized[m.Entity]; has && n != "" {
+			value, found = n, true
+		}
+	}
+	if !found {
+		return "", false, nil
+	}
+	for _, t := range m.Transforms {
+		if value, err = Transform(value, t); err != nil {
+			return "", false, err
+		}
+	}
+	return value, value != "", nil
+}
+
+// CurrencyFor resolves the currency of a monetary mapping from the
+// mapping's own code or referenced entity.  It is empty when neither gives
+// an ISO code, leaving paperless.CustomField.Coerce to fall back to the
+// printed currency, the field's default and then DefaultCurrency.
+func (c Config) CurrencyFor(m Mapping, entities, normalized map[string]string) string {
+	cur := m.Currency
+	if entity, ok := strings.CutPrefix(cur, "entity:"); ok {
+		cur = normalized[entity]
+		if cur == "" {
+			cur = entities[entity]
+		}
+	}
+	cur = strings.ToUpper(strings.TrimSpace(cur))
+	if len(cur) == 3 {
+		return cur
+	}
+	return ""
+}
+
+var digitsRe = regexp.MustCompile(`[^0-9]`)
+
+// Transform applies a single named transform to v.
+func Transform(v, name string) (string, error) {
+	switch name {
+	case "trim":
+		return strings.TrimSpace(v), nil
+	case "upper":
+		return strings.ToUpper(v), nil
+	case "lower":
+		return strings.ToLower(v), nil
+	case "single_line":
+		return strings.Join(strings.Fields(v), " "), nil
+	case "digits":
+		return digitsRe.ReplaceAllString(v, ""), nil
+	}
+	if pattern, ok := strings.CutPrefix(name, "regex:"); ok {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return "", fmt.Errorf("invalid regex transform %q: %w", pattern, err)
+		}
+		match := re.FindStringSubmatch(v)
+		switch {
+		case match == nil:
+			return "", nil
+		case len(match) > 1:
+			return match[1], nil
+		default:
+			return match[0], nil
+		}
+	}
+	return "", fmt.Errorf("unknown transform %q", name)
+}
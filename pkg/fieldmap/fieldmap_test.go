@@ -0,0 +1,89 @@
+package fieldmap
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestMappingValue(t *testing.T) {
+	entities := map[string]string{"invoice_id": " inv-\n0042 ", "invoice_date": "05 Mar 2024", "gstin": "GSTIN: 29ABCDE1234F1Z5"}
+	normalized := map[string]string{"invoice_date": "2024-03-05"}
+
+	cases := []struct {
+		name   string
+		m      Mapping
+		want   string
+		wantOK bool
+	}{
+		{"normalized preferred", Mapping{Entity: "invoice_date"}, "2024-03-05", true},
+		{"mention source", Mapping{Entity: "invoice_date", Source: SourceMention}, "05 Mar 2024", true},
+		{"transforms in order", Mapping{Entity: "invoice_id", Transforms: []string{"single_line", "upper"}}, "INV- 0042", true},
+		{"digits", Mapping{Entity: "invoice_id", Transforms: []string{"digits"}}, "0042", true},
+		{"regex group", Mapping{Entity: "gstin", Transforms: []string{`regex:([0-9A-Z]{15})`}}, "29ABCDE1234F1Z5", true},
+		{"regex no match", Mapping{Entity: "gstin", Transforms: []string{`regex:PAN(\d+)`}}, "", false},
+		{"missing entity", Mapping{Entity: "due_date"}, "", false},
+	}
+	for _, tc := range cases {
+		got, ok, err := tc.m.Value(entities, normalized)
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", tc.name, err)
+			continue
+		}
+		if got != tc.want || ok != tc.wantOK {
+			t.Errorf("%s: got (%q, %v), want (%q, %v)", tc.name, got, ok, tc.want, tc.wantOK)
+		}
+	}
+}
+
+func TestCurrencyFor(t *testing.T) {
+	cfg := Config{DefaultCurrency: "INR"}
+	entities := map[string]string{"currency": "$"}
+	normalized := map[string]string{"currency": "USD"}
+
+	if got := cfg.CurrencyFor(Mapping{Currency: "entity:currency"}, entities, normalized); got != "USD" {
+		t.Errorf("expected currency from normalized entity, got %q", got)
+	}
+	if got := cfg.CurrencyFor(Mapping{Currency: "entity:currency"}, entities, nil); got != "" {
+		t.Errorf("expected no currency for a non-ISO currency symbol, got %q", got)
+	}
+	if got := cfg.CurrencyFor(Mapping{}, entities, normalized); got != "" {
+		t.Errorf("expected no currency without a mapping currency, got %q", got)
+	}
+	if got := cfg.CurrencyFor(Mapping{Currency: "eur"}, entities, normalized); got != "EUR" {
+		t.Errorf("expected explicit currency, got %q", got)
+	}
+}
+
+func TestLoad(t *testing.T) {
+	cfg, err := Load("")
+	if err != nil || len(cfg.Mappings) == 0 {
+		t.Fatalf("expected default mappings, got %v (err %v)", cfg, err)
+	}
+
+	dir := t.TempDir()
+	good := filepath.Join(dir, "good.json")
+	os.WriteFile(good, []byte(`{"mappings": [{"entity": "invoice_id", "field": "Invoice Number", "transforms": ["trim"]}]}`), 0o644)
+	cfg, err = Load(good)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(cfg.Mappings) != 1 || cfg.Mappings[0].Field != "Invoice Number" {
+		t.Errorf("unexpected mappings: %+v", cfg.Mappings)
+	}
+
+	for name, body := range map[string]string{
+		"unknown transform": `{"mappings": [{"entity": "a", "field": "A", "transforms": ["titlecase"]}]}`,
+		"bad regex":         `{"mappings": [{"entity": "a", "field": "A", "transforms": ["regex:("]}]}`,
+		"missing field":     `{"mappings": [{"entity": "a"}]}`,
+		"unknown key":       `{"mappings": [{"entity": "a", "field": "A", "target": "B"}]}`,
+		"unknown source":    `{"mappings": [{"entity": "a", "field": "A", "source": "raw"}]}`,
+		"select data type":  `{"mappings": [{"entity": "a", "field": "A", "data_type": "select"}]}`,
+	} {
+		path := filepath.Join(dir, "bad.json")
+		os.WriteFile(path, []byte(body), 0o644)
+		if _, err := Load(path); err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+}
@@ -0,0 +1,268 @@
+// Package gst reads the Indian GST details of an invoice: the supplier and
+// buyer GSTINs, place of supply, CGST/SGST/UTGST/IGST/cess lines and HSN/SAC
+// codes, and checks them for consistency.
+package gst
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"paperless-document-processor/pkg/docai"
+	"paperless-document-processor/pkg/money"
+)
+
+// Tax types.
+const (
+	CGST  = "CGST"
+	SGST  = "SGST"
+	UTGST = "UTGST"
+	IGST  = "IGST"
+	Cess  = "CESS"
+)
+
+// Tax is one tax line of an invoice.
+type Tax struct {
+	Type   string
+	Rate   float64 // percent; 0 when not printed
+	Amount money.Money
+}
+
+// Breakdown is the GST detail of an invoice.
+type Breakdown struct {
+	SupplierGSTIN string
+	BuyerGSTIN    string
+	// PlaceOfSupply is the two-digit state code, e.g. "29" for Karnataka.
+	PlaceOfSupply string
+	TaxableAmount money.Money
+	Taxes         []Tax
+	HSNCodes      []string
+	// Problems lists failed consistency checks: invalid GSTINs, taxes that
+	// do not add up to the total, CGST without matching SGST and the like.
+	Problems []string
+}
+
+// Empty reports whether no GST detail was found.
+func (b Breakdown) Empty() bool {
+	return b.SupplierGSTIN == "" && b.BuyerGSTIN == "" && b.PlaceOfSupply == "" && len(b.Taxes) == 0 && len(b.HSNCodes) == 0
+}
+
+// TaxTotal sums the tax lines of the given type, or of all types when typ is
+// empty.
+func (b Breakdown) TaxTotal(typ string) money.Money {
+	var sum money.Money
+	for _, t := range b.Taxes {
+		if typ == "" || t.Type == typ {
+			sum = sum.Add(t.Amount)
+		}
+	}
+	return sum
+}
+
+// tolerance absorbs the round-off line most invoices print, in paise.
+const tolerance = 100
+
+var (
+	gstinRe = regexp.MustCompile(`\b\d{2}[A-Z]{5}\d{4}[A-Z][0-9A-Z]Z[0-9A-Z]\b`)
+	taxRe   = regexp.MustCompile(`(?i)\b(CGST|SGST|UTGST|IGST|CESS)\b`)
+	rateRe  = regexp.MustCompile(`(\d{1,2}(?:\.\d+)?)\s*%`)
+	moneyRe = regexp.MustCompile(`\d[\d,]*\.\d{2}\b`)
+	hsnRe   = regexp.MustCompile(`(?i)\b(?:HSN|SAC)(?:\s*/\s*SAC)?(?:\s*code)?\s*[:.\-]?\s*(\d{4,8})\b`)
+	posRe   = regexp.MustCompile(`(?i)place\s+of\s+supply\s*[:\-]?\s*([^\n]*)`)
+	codeRe  = regexp.MustCompile(`^\d{4,8}$`)
+)
+
+// Extract builds the GST breakdown of an invoice from the DocAI entities
+// (supplier_tax_id, receiver_tax_id, vat, net_amount, total_amount and the
+// line items' product codes), falling back to the invoice text for what the
+// processor did not find.  ownGSTINs are the buyer's registrations; they
+// tell the buyer's GSTIN apart from the supplier's when both are only found
+// in the text.
+func Extract(data *docai.ExtractedData, ownGSTINs []string) Breakdown {
+	var b Breakdown
+	b.extractGSTINs(data, ownGSTINs)
+	b.PlaceOfSupply = placeOfSupply(data.Text)
+
+	for _, item := range data.TaxItems {
+		typ := taxType(item.CategoryCode)
+		amount, err := money.Parse(item.TaxAmount)
+		if typ == "" || err != nil {
+			continue
+		}
+		rate, _ := parseRate(item.TaxRate)
+		b.Taxes = append(b.Taxes, Tax{Type: typ, Rate: rate, Amount: amount})
+	}
+	if len(b.Taxes) == 0 {
+		b.Taxes = textTaxes(data.Text)
+	}
+
+	for _, li := range data.LineItems {
+		if codeRe.MatchString(li.ProductCode) && !slices.Contains(b.HSNCodes, li.ProductCode) {
+			b.HSNCodes = append(b.HSNCodes, li.ProductCode)
+		}
+	}
+	if len(b.HSNCodes) == 0 {
+		for _, m := range hsnRe.FindAllStringSubmatch(data.Text, -1) {
+			if !slices.Contains(b.HSNCodes, m[1]) {
+				b.HSNCodes = append(b.HSNCodes, m[1])
+			}
+		}
+	}
+
+	if net, ok := amountEntity(data, "net_amount"); ok {
+		b.TaxableAmount = net
+	}
+	total, _ := amountEntity(data, "total_amount")
+	b.check(total)
+	return b
+}
+
+// extractGSTINs takes the GSTINs from the tax ID entities, then assigns
+// those found in the text: the buyer's own registration is the buyer, the
+// first other one is the supplier.
+func (b *Breakdown) extractGSTINs(data *docai.ExtractedData, ownGSTINs []string) {
+	own := make(map[string]bool, len(ownGSTINs))
+	for _, g := range ownGSTINs {
+		own[strings.ToUpper(strings.TrimSpace(g))] = true
+	}
+	for _, e := range []struct {
+		entity string
+		dst    *string
+	}{
+		{"supplier_tax_id", &b.SupplierGSTIN},
+		{"receiver_tax_id", &b.BuyerGSTIN},
+	} {
+		entity, dst := e.entity, e.dst
+		v := strings.ToUpper(strings.Join(strings.Fields(data.Entities[entity]), ""))
+		if v == "" {
+			continue
+		}
+		if !ValidGSTIN(v) {
+			b.Problems = append(b.Problems, fmt.Sprintf("%s %q is not a valid GSTIN", entity, v))
+			continue
+		}
+		*dst = v
+	}
+
+	for _, g := range gstinRe.FindAllString(strings.ToUpper(data.Text), -1) {
+		if !ValidGSTIN(g) || g == b.SupplierGSTIN || g == b.BuyerGSTIN {
+			continue
+		}
+		switch {
+		case own[g] && b.BuyerGSTIN == "":
+			b.BuyerGSTIN = g
+		case !own[g] && b.SupplierGSTIN == "":
+			b.SupplierGSTIN = g
+		}
+	}
+	if b.SupplierGSTIN != "" && own[b.SupplierGSTIN] {
+		b.Problems = append(b.Problems, fmt.Sprintf("supplier GSTIN %s is one of our own registrations", b.SupplierGSTIN))
+	}
+	if b.BuyerGSTIN != "" && len(own) > 0 && !own[b.BuyerGSTIN] {
+		b.Problems = append(b.Problems, fmt.Sprintf("buyer GSTIN %s is not one of our registrations", b.BuyerGSTIN))
+	}
+}
+
+// check records the consistency problems of the tax lines.  total is the
+// invoice total, zero when unknown.
+func (b *Breakdown) check(total money.Money) {
+	cgst, sgst := b.TaxTotal(CGST), b.TaxTotal(SGST).Add(b.TaxTotal(UTGST))
+	igst := b.TaxTotal(IGST)
+	if abs(cgst.Minor-sgst.Minor) > tolerance {
+		b.Problems = append(b.Problems, fmt.Sprintf("CGST %s and SGST/UTGST %s differ", cgst, sgst))
+	}
+	if !igst.IsZero() && !cgst.Add(sgst).IsZero() {
+		b.Problems = append(b.Problems, "invoice charges both IGST and CGST/SGST")
+	}
+
+	// Supplies within the supplier's state carry CGST and SGST, others IGST.
+	if b.SupplierGSTIN != "" && b.PlaceOfSupply != "" && len(b.Taxes) > 0 {
+		intraState := b.SupplierGSTIN[:2] == b.PlaceOfSupply
+		switch {
+		case intraState && !igst.IsZero():
+			b.Problems = append(b.Problems, fmt.Sprintf("IGST charged on a supply within state %s", b.PlaceOfSupply))
+		case !intraState && !cgst.Add(sgst).IsZero():
+			b.Problems = append(b.Problems, fmt.Sprintf("CGST/SGST charged on a supply from state %s to %s", b.SupplierGSTIN[:2], b.PlaceOfSupply))
+		}
+	}
+
+	taxes := b.TaxTotal("")
+	gross := b.TaxableAmount.Add(taxes)
+	if !total.IsZero() && !b.TaxableAmount.IsZero() && len(b.Taxes) > 0 && abs(gross.Minor-total.Minor) > tolerance {
+		b.Problems = append(b.Problems, fmt.Sprintf("net %s + taxes %s = %s but total is %s", b.TaxableAmount, taxes, gross, total))
+	}
+}
+
+// textTaxes reads tax lines such as "CGST @ 9% 450.00" from the invoice
+// text, one per line.  Lines without an amount (table headers) are skipped.
+// Identical lines are all kept, as invoices print one per rate slab or item;
+// a summary printed twice shows up as a mismatch with the total instead.
+func textTaxes(text string) []Tax {
+	var taxes []Tax
+	for _, line := range strings.Split(text, "\n") {
+		m := taxRe.FindStringSubmatch(line)
+		if m == nil {
+			continue
+		}
+		amounts := moneyRe.FindAllString(line, -1)
+		if len(amounts) == 0 {
+			continue
+		}
+		t := Tax{Type: strings.ToUpper(m[1])}
+		t.Amount, _ = money.Parse(amounts[len(amounts)-1])
+		if r := rateRe.FindStringSubmatch(line); r != nil {
+			t.Rate, _ = strconv.ParseFloat(r[1], 64)
+		}
+		if !t.Amount.IsZero() {
+			taxes = append(taxes, t)
+		}
+	}
+	return taxes
+}
+
+func taxType(category string) string {
+	m := taxRe.FindStringSubmatch(category)
+	if m == nil {
+		return ""
+	}
+	return strings.ToUpper(m[1])
+}
+
+// placeOfSupply finds "Place of Supply: 29-Karnataka" (or just the code or
+// the state name) in the text and returns the state code.
+func placeOfSupply(text string) string {
+	m := posRe.FindStringSubmatch(text)
+	if m == nil {
+		return ""
+	}
+	v := strings.TrimSpace(m[1])
+	if len(v) >= 2 && v[0] >= '0' && v[0] <= '9' && v[1] >= '0' && v[1] <= '9' {
+		if _, ok := stateNames[v[:2]]; ok {
+			return v[:2]
+		}
+	}
+	return StateCode(v)
+}
+
+func amountEntity(data *docai.ExtractedData, entity string) (money.Money, bool) {
+	if v, err := money.Parse(data.Normalized[entity]); err == nil {
+		return v, true
+	}
+	v, err := money.Parse(data.Entities[entity])
+	return v, err == nil
+}
+
+// parseRate reads a tax rate such as "9%" or "2.5".
+func parseRate(val string) (float64, bool) {
+	f, err := money.ParseNumber(val)
+	return f, err == nil
+}
+
+func abs(n int64) int64 {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
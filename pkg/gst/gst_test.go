@@ -0,0 +1,170 @@
+package gst
+
+import (
+	"slices"
+	"strings"
+	"testing"
+
+	"paperless-document-processor/pkg/docai"
+	"paperless-document-processor/pkg/money"
+)
+
+func TestValidGSTIN(t *testing.T) {
+	tests := []struct {
+		gstin string
+		want  bool
+	}{
+		{"27AAPFU0939F1ZV", true},
+		{"29AAGCB7383J1Z4", true},
+		{"33AAACH7409R1Z8", true},
+		{"27AAPFU0939F1ZW", false}, // wrong check character
+		{"28AAPFU0939F1ZV", false}, // unused state code
+		{"27AAPFU0939F1Z", false},
+		{"27aapfu0939f1zv", false},
+	}
+	for _, tt := range tests {
+		if got := ValidGSTIN(tt.gstin); got != tt.want {
+			t.Errorf("ValidGSTIN(%q) = %v, want %v", tt.gstin, got, tt.want)
+		}
+	}
+}
+
+func TestStateCode(t *testing.T) {
+	tests := map[string]string{
+		"Karnataka":                            "29",
+		"TAMIL NADU (33)":                      "33",
+		"Dadra & Nagar Haveli and Daman & Diu": "26",
+		"Daman and Diu":                        "25",
+		"Atlantis":                             "",
+	}
+	for name, want := range tests {
+		if got := StateCode(name); got != want {
+			t.Errorf("StateCode(%q) = %q, want %q", name, got, want)
+		}
+	}
+}
+
+const intraStateInvoice = `TAX INVOICE
+Fresh Farms Pvt Ltd
+GSTIN: 29AAGCB7383J1Z4
+Bill To: Our Kitchen
+GSTIN: 33AAACH7409R1Z8
+Place of Supply: 29-Karnataka
+Description          HSN      Qty   Amount
+Basmati Rice         1006     2     5,000.00
+Taxable Value                       5,000.00
+CGST @ 2.5%                         125.00
+SGST @ 2.5%                         125.00
+Total                               5,250.00
+`
+
+func TestExtractFromText(t *testing.T) {
+	data := &docai.ExtractedData{
+		Text:       intraStateInvoice,
+		Entities:   map[string]string{"net_amount": "5,000.00", "total_amount": "5,250.00"},
+		Normalized: map[string]string{},
+	}
+	b := Extract(data, []string{"33AAACH7409R1Z8"})
+
+	if b.SupplierGSTIN != "29AAGCB7383J1Z4" || b.BuyerGSTIN != "33AAACH7409R1Z8" {
+		t.Errorf("GSTINs = %q, %q", b.SupplierGSTIN, b.BuyerGSTIN)
+	}
+	if b.PlaceOfSupply != "29" {
+		t.Errorf("PlaceOfSupply = %q, want 29", b.PlaceOfSupply)
+	}
+	want := []Tax{{CGST, 2.5, money.New(12500, "")}, {SGST, 2.5, money.New(12500, "")}}
+	if !slices.Equal(b.Taxes, want) {
+		t.Errorf("Taxes = %+v, want %+v", b.Taxes, want)
+	}
+	if b.TaxableAmount.Minor != 500000 {
+		t.Errorf("TaxableAmount = %v, want 5000", b.TaxableAmount)
+	}
+	if len(b.Problems) != 0 {
+		t.Errorf("unexpected problems: %q", b.Problems)
+	}
+}
+
+func TestExtractKeepsRepeatedTaxLines(t *testing.T) {
+	// Two items in the same slab print identical tax lines.
+	data := &docai.ExtractedData{
+		Text: `Item A   1,000.00
+CGST @ 9%   90.00
+SGST @ 9%   90.00
+Item B   1,000.00
+CGST @ 9%   90.00
+SGST @ 9%   90.00
+`,
+		Entities:   map[string]string{"net_amount": "2,000.00", "total_amount": "2,360.00"},
+		Normalized: map[string]string{},
+	}
+	b := Extract(data, nil)
+	if len(b.Taxes) != 4 || b.TaxTotal("").Minor != 36000 {
+		t.Errorf("Taxes = %+v, want four lines totalling 360.00", b.Taxes)
+	}
+	if len(b.Problems) != 0 {
+		t.Errorf("unexpected problems: %q", b.Problems)
+	}
+}
+
+func TestExtractFromEntities(t *testing.T) {
+	data := &docai.ExtractedData{
+		Entities: map[string]string{
+			"supplier_tax_id": "27AAPFU0939F1ZV",
+			"net_amount":      "1000",
+			"total_amount":    "1180",
+		},
+		Normalized: map[string]string{},
+		Text:       "Place of Supply: Maharashtra\nHSN 9963",
+		TaxItems:   []docai.TaxItem{{CategoryCode: "IGST", TaxRate: "18%", TaxAmount: "180"}},
+		LineItems:  []docai.LineItem{{Description: "Catering", ProductCode: "996331"}},
+	}
+	b := Extract(data, nil)
+
+	if b.SupplierGSTIN != "27AAPFU0939F1ZV" {
+		t.Errorf("SupplierGSTIN = %q", b.SupplierGSTIN)
+	}
+	if len(b.Taxes) != 1 || b.Taxes[0] != (Tax{IGST, 18, money.New(18000, "")}) {
+		t.Errorf("Taxes = %+v", b.Taxes)
+	}
+	// Line item product codes win over codes found in the text.
+	if !slices.Equal(b.HSNCodes, []string{"996331"}) {
+		t.Errorf("HSNCodes = %q", b.HSNCodes)
+	}
+	// IGST on a supply within Maharashtra.
+	if len(b.Problems) != 1 || !strings.Contains(b.Problems[0], "IGST charged on a supply within state 27") {
+		t.Errorf("Problems = %q", b.Problems)
+	}
+}
+
+func TestExtractProblems(t *testing.T) {
+	data := &docai.ExtractedData{
+		Entities: map[string]string{
+			"supplier_tax_id": "29AAGCB7383J1Z5",
+			"net_amount":      "1000",
+			"total_amount":    "1200",
+		},
+		Normalized: map[string]string{},
+		Text:       "CGST 9% 90.00\nSGST 9% 80.00\n",
+	}
+	b := Extract(data, nil)
+
+	want := []string{
+		`supplier_tax_id "29AAGCB7383J1Z5" is not a valid GSTIN`,
+		"CGST 90.00 and SGST/UTGST 80.00 differ",
+		"net 1000.00 + taxes 170.00 = 1170.00 but total is 1200.00",
+	}
+	if !slices.Equal(b.Problems, want) {
+		t.Errorf("Problems = %q, want %q", b.Problems, want)
+	}
+}
+
+func TestExtractNoGST(t *testing.T) {
+	data := &docai.ExtractedData{
+		Entities:   map[string]string{"total_amount": "99"},
+		Normalized: map[string]string{},
+		Text:       "Receipt\nTotal 99.00",
+	}
+	if b := Extract(data, nil); !b.Empty() || len(b.Problems) != 0 {
+		t.Errorf("Extract = %+v, want empty", b)
+	}
+}
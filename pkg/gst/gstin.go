@@ -0,0 +1,96 @@
+package gst
+
+import "strings"
+
+const gstinAlphabet = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZ"
+
+// ValidGSTIN reports whether g is a well-formed GSTIN with a known state
+// code and a correct check character.  The check character is computed
+// over the first 14 characters in base 36, doubling every second value and
+// adding the digits of each product.
+func ValidGSTIN(g string) bool {
+	if len(g) != 15 || !gstinRe.MatchString(g) {
+		return false
+	}
+	if _, ok := stateNames[g[:2]]; !ok {
+		return false
+	}
+	sum := 0
+	for i := 0; i < 14; i++ {
+		v := strings.IndexByte(gstinAlphabet, g[i])
+		if i%2 == 1 {
+			v *= 2
+		}
+		sum += v/36 + v%36
+	}
+	return gstinAlphabet[(36-sum%36)%36] == g[14]
+}
+
+// stateNames maps GST state codes to state and union territory names.
+var stateNames = map[string]string{
+	"01": "Jammu and Kashmir",
+	"02": "Himachal Pradesh",
+	"03": "Punjab",
+	"04": "Chandigarh",
+	"05": "Uttarakhand",
+	"06": "Haryana",
+	"07": "Delhi",
+	"08": "Rajasthan",
+	"09": "Uttar Pradesh",
+	"10": "Bihar",
+	"11": "Sikkim",
+	"12": "Arunachal Pradesh",
+	"13": "Nagaland",
+	"14": "Manipur",
+	"15": "Mizoram",
+	"16": "Tripura",
+	"17": "Meghalaya",
+	"18": "Assam",
+	"19": "West Bengal",
+	"20": "Jharkhand",
+	"21": "Odisha",
+	"22": "Chhattisgarh",
+	"23": "Madhya Pradesh",
+	"24": "Gujarat",
+	"25": "Daman and Diu",
+	"26": "Dadra and Nagar Haveli and Daman and Diu",
+	"27": "Maharashtra",
+	"29": "Karnataka",
+	"30": "Goa",
+	"31": "Lakshadweep",
+	"32": "Kerala",
+	"33": "Tamil Nadu",
+	"34": "Puducherry",
+	"35": "Andaman and Nicobar Islands",
+	"36": "Telangana",
+	"37": "Andhra Pradesh",
+	"38": "Ladakh",
+	"97": "Other Territory",
+}
+
+// StateName returns the name of a GST state code.
+func StateName(code string) string {
+	return stateNames[code]
+}
+
+// StateCode returns the GST state code of a state or union territory name,
+// or "" when it is not recognised.  Case, "&" for "and" and extra spaces are
+// ignored, and the name may be followed by other text ("Karnataka (29)").
+func StateCode(name string) string {
+	norm := normalizeState(name)
+	best := ""
+	for code, state := range stateNames {
+		s := normalizeState(state)
+		// Prefer the longest match so "Dadra and Nagar Haveli and Daman
+		// and Diu" wins over "Daman and Diu".
+		if strings.HasPrefix(norm, s) && len(s) > len(normalizeState(stateNames[best])) {
+			best = code
+		}
+	}
+	return best
+}
+
+func normalizeState(s string) string {
+	s = strings.ToLower(strings.ReplaceAll(s, "&", " and "))
+	return strings.Join(strings.Fields(s), " ")
+}
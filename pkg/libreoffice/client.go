@@ -2,6 +2,7 @@ package libreoffice
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -9,6 +10,8 @@ import (
 	"net/http"
 	"net/url"
 	"strings"
+
+	"paperless-document-processor/pkg/retry"
 )
 
 // Client calls the LibreOffice-based spreadsheet parser service.
@@ -16,6 +19,7 @@ type Client struct {
 	baseURL    string
 	dataPath   string
 	httpClient *http.Client
+	retry      retry.Policy
 }
 
 // ParseResult holds an ordered list of column headers and data rows returned by
@@ -188,12 +192,19 @@ func NewClient(baseURL, dataPath string) *Client {
 	}
 }
 
+// SetRetryPolicy configures how transient failures (timeouts, 429, 5xx) are
+// retried.  The default is a single attempt.
+func (c *Client) SetRetryPolicy(p retry.Policy) {
+	c.retry = p
+}
+
 // Parse fetches spreadsheet rows from the LibreOffice parser service.
 //
 // filePath is relative to the Paperless media root
 // (e.g. "documents/originals/2022/01/invoice.xlsx").  The client prepends its
-// configured dataPath so the service receives an absolute path.
-func (c *Client) Parse(filePath, sheetName, rangeStr string, hasHeader, stopAtEmpty bool) (*ParseResult, error) {
+// configured dataPath so the service receives an absolute path.  Transient
+// failures are retried per the client's policy until ctx is done.
+func (c *Client) Parse(ctx context.Context, filePath, sheetName, rangeStr string, hasHeader, stopAtEmpty bool) (*ParseResult, error) {
 	if c.baseURL == "" {
 		return nil, fmt.Errorf("libreoffice client: baseURL is not configured (set LIBREOFFICE_URL)")
 	}
@@ -215,20 +226,31 @@ func (c *Client) Parse(filePath, sheetName, rangeStr string, hasHeader, stopAtEm
 	reqURL := fmt.Sprintf("%s/parse?%s", c.baseURL, params.Encode())
 	slog.Debug("Calling LibreOffice parser", "url", reqURL)
 
-	resp, err := c.httpClient.Get(reqURL)
-	if err != nil {
-		return nil, fmt.Errorf("libreoffice parse request failed: %w", err)
-	}
-	defer resp.Body.Close()
+	var body []byte
+	err := retry.Do(ctx, c.retry, "libreoffice parse", func() error {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, reqURL, nil)
+		if err != nil {
+			return err
+		}
+		resp, err := c.httpClient.Do(req)
+		if err != nil {
+			return fmt.Errorf("libreoffice parse request failed: %w", err)
+		}
+		defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("libreoffice returned status %d: %s", resp.StatusCode, string(body))
-	}
+		if resp.StatusCode != http.StatusOK {
+			errBody, _ := io.ReadAll(resp.Body)
+			return retry.NewStatusError(resp, fmt.Errorf("libreoffice returned status %d: %s", resp.StatusCode, string(errBody)))
+		}
 
-	body, err := io.ReadAll(resp.Body)
+		body, err = io.ReadAll(resp.Body)
+		if err != nil {
+			return fmt.Errorf("failed to read libreoffice response: %w", err)
+		}
+		return nil
+	})
 	if err != nil {
-		return nil, fmt.Errorf("failed to read libreoffice response: %w", err)
+		return nil, err
 	}
 
 	// Try wrapped object format: {"data": [{...}, ...]}
@@ -1,11 +1,14 @@
 package libreoffice
 
 import (
+	"context"
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
 	"strings"
 	"testing"
+
+	"paperless-document-processor/pkg/retry"
 )
 
 func TestParse_WrappedFormat(t *testing.T) {
@@ -39,7 +42,7 @@ func TestParse_WrappedFormat(t *testing.T) {
 	defer server.Close()
 
 	client := NewClient(server.URL, "/data")
-	result, err := client.Parse("documents/originals/invoice.xlsx", "Order Level", "A7:BH", true, true)
+	result, err := client.Parse(context.Background(), "documents/originals/invoice.xlsx", "Order Level", "A7:BH", true, true)
 	if err != nil {
 		t.Fatalf("expected no error, got %v", err)
 	}
@@ -65,7 +68,7 @@ func TestParse_PlainArrayFormat(t *testing.T) {
 	defer server.Close()
 
 	client := NewClient(server.URL, "/data")
-	result, err := client.Parse("documents/originals/invoice.xlsx", "", "", true, false)
+	result, err := client.Parse(context.Background(), "documents/originals/invoice.xlsx", "", "", true, false)
 	if err != nil {
 		t.Fatalf("expected no error, got %v", err)
 	}
@@ -81,7 +84,7 @@ func TestParse_ServiceError(t *testing.T) {
 	defer server.Close()
 
 	client := NewClient(server.URL, "/data")
-	_, err := client.Parse("documents/originals/missing.xlsx", "", "", true, false)
+	_, err := client.Parse(context.Background(), "documents/originals/missing.xlsx", "", "", true, false)
 	if err == nil {
 		t.Fatal("expected error for non-200 response")
 	}
@@ -102,7 +105,7 @@ func TestParse_DataOnlyFormat_SanitizesColumnNames(t *testing.T) {
 	defer server.Close()
 
 	client := NewClient(server.URL, "/data")
-	result, err := client.Parse("documents/originals/invoice.xlsx", "Order Level", "A7:BH", true, true)
+	result, err := client.Parse(context.Background(), "documents/originals/invoice.xlsx", "Order Level", "A7:BH", true, true)
 	if err != nil {
 		t.Fatalf("expected no error, got %v", err)
 	}
@@ -130,7 +133,7 @@ func keys(m map[string]interface{}) []string {
 
 func TestParse_EmptyBaseURL_ReturnsError(t *testing.T) {
 	client := NewClient("", "/data")
-	_, err := client.Parse("documents/originals/test.xlsx", "", "", false, false)
+	_, err := client.Parse(context.Background(), "documents/originals/test.xlsx", "", "", false, false)
 	if err == nil {
 		t.Fatal("expected error when baseURL is empty")
 	}
@@ -149,7 +152,7 @@ func TestParse_DataPathPrepended(t *testing.T) {
 	defer server.Close()
 
 	client := NewClient(server.URL, "/mnt/media")
-	result, err := client.Parse("documents/originals/test.xlsx", "", "", false, false)
+	result, err := client.Parse(context.Background(), "documents/originals/test.xlsx", "", "", false, false)
 	if err != nil {
 		t.Fatalf("expected no error, got %v", err)
 	}
@@ -177,7 +180,7 @@ func TestParse_FiltersRefRows_WrappedFormat(t *testing.T) {
 	defer server.Close()
 
 	client := NewClient(server.URL, "/data")
-	result, err := client.Parse("documents/originals/invoice.xlsx", "Order Level", "A7:BH", true, true)
+	result, err := client.Parse(context.Background(), "documents/originals/invoice.xlsx", "Order Level", "A7:BH", true, true)
 	if err != nil {
 		t.Fatalf("expected no error, got %v", err)
 	}
@@ -202,7 +205,7 @@ func TestParse_PreservesColumnOrder_WrappedFormat(t *testing.T) {
 	defer server.Close()
 
 	client := NewClient(server.URL, "/data")
-	result, err := client.Parse("documents/originals/invoice.xlsx", "", "", true, false)
+	result, err := client.Parse(context.Background(), "documents/originals/invoice.xlsx", "", "", true, false)
 	if err != nil {
 		t.Fatalf("expected no error, got %v", err)
 	}
@@ -227,7 +230,7 @@ func TestParse_PreservesColumnOrder_PlainArrayFormat(t *testing.T) {
 	defer server.Close()
 
 	client := NewClient(server.URL, "/data")
-	result, err := client.Parse("documents/originals/invoice.xlsx", "", "", true, false)
+	result, err := client.Parse(context.Background(), "documents/originals/invoice.xlsx", "", "", true, false)
 	if err != nil {
 		t.Fatalf("expected no error, got %v", err)
 	}
@@ -265,7 +268,7 @@ w.Write([]byte(payload))
 defer server.Close()
 
 client := NewClient(server.URL, "/data")
-result, err := client.Parse("documents/originals/invoice.xlsx", "Summary", "B4:C18", false, true)
+result, err := client.Parse(context.Background(), "documents/originals/invoice.xlsx", "Summary", "B4:C18", false, true)
 if err != nil {
 t.Fatalf("expected no error, got %v", err)
 }
@@ -305,3 +308,30 @@ if row["Net Payout (Settled + Unsettled)"] != 8115.91 {
 t.Errorf("unexpected Net Payout: %v", row["Net Payout (Settled + Unsettled)"])
 }
 }
+
+func TestParse_RetriesTransientStatus(t *testing.T) {
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if calls == 1 {
+			http.Error(w, "busy", http.StatusServiceUnavailable)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode([]map[string]interface{}{{"Date": "2022-01-01"}})
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL, "/data")
+	client.SetRetryPolicy(retry.Policy{MaxAttempts: 3})
+	result, err := client.Parse(context.Background(), "documents/originals/invoice.xlsx", "", "", true, false)
+	if err != nil {
+		t.Fatalf("expected no error after retry, got %v", err)
+	}
+	if calls != 2 {
+		t.Errorf("expected 2 calls, got %d", calls)
+	}
+	if len(result.Rows) != 1 {
+		t.Errorf("expected 1 row, got %d", len(result.Rows))
+	}
+}
@@ -0,0 +1,209 @@
+// Package money represents amounts exactly, as an integer number of minor
+// units (paise, cents) and a currency, and parses them as printed on Indian
+// invoices and reports.
+package money
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// DefaultCurrency is the currency of amounts that do not name one.
+const DefaultCurrency = "INR"
+
+// scale is the number of minor units per major unit.  Every currency the
+// pipeline sees (INR, USD, EUR, GBP) has two decimals.
+const scale = 100
+
+// Money is an amount in minor units.  An empty Currency means
+// DefaultCurrency.  The zero value is zero rupees.
+type Money struct {
+	Minor    int64
+	Currency string
+}
+
+// New returns minor units of currency.
+func New(minor int64, currency string) Money {
+	return Money{Minor: minor, Currency: currency}
+}
+
+// FromFloat converts a float, such as a DuckDB DOUBLE, rounding half away
+// from zero to the nearest minor unit.  The float's shortest decimal form is
+// rounded rather than the binary value, so 0.285 becomes 0.29.  Floats
+// beyond the range of int64 minor units saturate.
+func FromFloat(f float64, currency string) Money {
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		return Money{Currency: currency}
+	}
+	m, err := parseDecimal(strconv.FormatFloat(f, 'f', -1, 64))
+	if err != nil {
+		// Out of range for int64 minor units, which no real amount is.
+		if f < 0 {
+			return Money{Minor: math.MinInt64, Currency: currency}
+		}
+		return Money{Minor: math.MaxInt64, Currency: currency}
+	}
+	m.Currency = currency
+	return m
+}
+
+// FromUnits converts a google.type.Money (whole units and nanos), as found
+// in Document AI's normalized values.
+func FromUnits(units int64, nanos int32, currency string) Money {
+	const nanosPerMinor = 1e9 / scale
+	minor := int64(nanos) / nanosPerMinor
+	if rem := int64(nanos) % nanosPerMinor; rem*2 >= nanosPerMinor {
+		minor++
+	} else if rem*2 <= -nanosPerMinor {
+		minor--
+	}
+	return Money{Minor: units*scale + minor, Currency: currency}
+}
+
+// FromBigInt converts a whole number of major units, such as a DuckDB
+// HUGEINT.  It fails when the amount does not fit in int64 minor units.
+func FromBigInt(v *big.Int, currency string) (Money, error) {
+	minor := new(big.Int).Mul(v, big.NewInt(scale))
+	if !minor.IsInt64() {
+		return Money{}, fmt.Errorf("amount %s is out of range", v)
+	}
+	return Money{Minor: minor.Int64(), Currency: currency}, nil
+}
+
+// FromBigFloat converts a big.Float, rounding to the nearest minor unit.  It
+// fails when the amount does not fit in int64 minor units.
+func FromBigFloat(v *big.Float, currency string) (Money, error) {
+	m, err := parseDecimal(v.Text('f', 3))
+	if err != nil {
+		return Money{}, fmt.Errorf("amount %s is out of range", v.Text('g', 10))
+	}
+	m.Currency = currency
+	return m, nil
+}
+
+// IsZero reports whether m is zero in any currency.
+func (m Money) IsZero() bool {
+	return m.Minor == 0
+}
+
+// Neg returns -m.
+func (m Money) Neg() Money {
+	return Money{Minor: -m.Minor, Currency: m.Currency}
+}
+
+// Add returns m + o in m's currency.  Amounts are not converted between
+// currencies.
+func (m Money) Add(o Money) Money {
+	if m.Currency == "" {
+		m.Currency = o.Currency
+	}
+	return Money{Minor: m.Minor + o.Minor, Currency: m.Currency}
+}
+
+// Mul returns m times a quantity, rounded half away from zero to the
+// nearest minor unit.
+func (m Money) Mul(q float64) Money {
+	return Money{Minor: int64(math.Round(float64(m.Minor) * q)), Currency: m.Currency}
+}
+
+// CurrencyCode returns the currency, DefaultCurrency when unset.
+func (m Money) CurrencyCode() string {
+	if m.Currency == "" {
+		return DefaultCurrency
+	}
+	return m.Currency
+}
+
+// Float64 returns m in major units.  Use it for display and tolerances
+// only; arithmetic should stay in minor units.
+func (m Money) Float64() float64 {
+	return float64(m.Minor) / scale
+}
+
+// String formats m in major units with two decimals and no grouping or
+// currency, e.g. "-1234.50".
+func (m Money) String() string {
+	sign := ""
+	minor := m.Minor
+	if minor < 0 {
+		sign = "-"
+		minor = -minor
+	}
+	return fmt.Sprintf("%s%d.%02d", sign, minor/scale, minor%scale)
+}
+
+// MarshalJSON writes m as a decimal number in major units, e.g. 1234.50.
+// The currency is not written.
+func (m Money) MarshalJSON() ([]byte, error) {
+	return []byte(m.String()), nil
+}
+
+// UnmarshalJSON reads a number in major units or a string Parse accepts.
+func (m *Money) UnmarshalJSON(data []byte) error {
+	if string(data) == "null" {
+		*m = Money{}
+		return nil
+	}
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		s = string(data)
+	}
+	v, err := Parse(s)
+	if err != nil {
+		return err
+	}
+	*m = v
+	return nil
+}
+
+// Value stores m as its decimal string, which DuckDB casts to the column's
+// DECIMAL type exactly.
+func (m Money) Value() (driver.Value, error) {
+	return m.String(), nil
+}
+
+// Scan reads a DECIMAL, DOUBLE, integer or text column.  NULL is zero.
+func (m *Money) Scan(src interface{}) error {
+	switch v := src.(type) {
+	case nil:
+		*m = Money{}
+	case int64:
+		*m = Money{Minor: v * scale}
+	case float64:
+		*m = FromFloat(v, "")
+	case *big.Int:
+		b, err := FromBigInt(v, "")
+		if err != nil {
+			return err
+		}
+		*m = b
+	case *big.Float:
+		b, err := FromBigFloat(v, "")
+		if err != nil {
+			return err
+		}
+		*m = b
+	case []byte:
+		return m.Scan(string(v))
+	case string:
+		if strings.TrimSpace(v) == "" {
+			*m = Money{}
+			return nil
+		}
+		p, err := Parse(v)
+		if err != nil {
+			return err
+		}
+		*m = p
+	case fmt.Stringer:
+		return m.Scan(v.String())
+	default:
+		return fmt.Errorf("cannot scan %T into money", src)
+	}
+	return nil
+}
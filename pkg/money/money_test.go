@@ -0,0 +1,185 @@
+package money
+
+import (
+	"encoding/json"
+	"math"
+	"math/big"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		in   string
+		want Money
+	}{
+		{"1,23,456.78", Money{12345678, ""}},
+		{"123,456.78", Money{12345678, ""}},
+		{"12,34,56,789", Money{123456789 * 100, ""}},
+		{"₹1,250.00", Money{125000, "INR"}},
+		{"₹ 1,250", Money{125000, "INR"}},
+		{"Rs. 99.5", Money{9950, "INR"}},
+		{"Rs.150", Money{15000, "INR"}},
+		{"INR 10", Money{1000, "INR"}},
+		{"500/-", Money{50000, ""}},
+		{"Rs. 500/-", Money{50000, "INR"}},
+		{"$12.34", Money{1234, "USD"}},
+		{"12.34 EUR", Money{1234, "EUR"}},
+		{"-42.00", Money{-4200, ""}},
+		{"42.00-", Money{-4200, ""}},
+		{"-₹42", Money{-4200, "INR"}},
+		{"₹-42", Money{-4200, "INR"}},
+		{"(1,250.00)", Money{-125000, ""}},
+		{"(₹1,250.00)", Money{-125000, "INR"}},
+		{"[$₹-4009] 1,250.00", Money{125000, "INR"}},
+		{"0.285", Money{29, ""}},
+		{"-0.285", Money{-29, ""}},
+		{"0.284", Money{28, ""}},
+		{".5", Money{50, ""}},
+		{"3400.4999999999995", Money{340050, ""}},
+		{"1e3", Money{100000, ""}},
+	}
+	for _, tt := range tests {
+		got, err := Parse(tt.in)
+		if err != nil {
+			t.Errorf("Parse(%q): %v", tt.in, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("Parse(%q) = %+v, want %+v", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestParseInvalid(t *testing.T) {
+	for _, in := range []string{"", "₹", "abc", "1,2345.00", "12,34", "1.234,56", "1,,234", "12 34", "1.2.3"} {
+		if m, err := Parse(in); err == nil {
+			t.Errorf("Parse(%q) = %+v, want error", in, m)
+		}
+	}
+}
+
+func TestFromFloat(t *testing.T) {
+	tests := []struct {
+		in   float64
+		want int64
+	}{
+		{0.285, 29},
+		{1.005, 101},
+		{19.99, 1999},
+		{-19.99, -1999},
+		{0.1 + 0.2, 30},
+		{3400.5, 340050},
+	}
+	for _, tt := range tests {
+		if got := FromFloat(tt.in, ""); got.Minor != tt.want {
+			t.Errorf("FromFloat(%v) = %d, want %d", tt.in, got.Minor, tt.want)
+		}
+	}
+}
+
+func TestFromUnits(t *testing.T) {
+	if got := FromUnits(1250, 500000000, "INR"); got != (Money{125050, "INR"}) {
+		t.Errorf("FromUnits = %+v", got)
+	}
+	if got := FromUnits(-3, -755000000, "INR"); got.Minor != -376 {
+		t.Errorf("FromUnits negative = %d, want -376", got.Minor)
+	}
+}
+
+func TestString(t *testing.T) {
+	for minor, want := range map[int64]string{0: "0.00", 5: "0.05", -5: "-0.05", 125050: "1250.50", -125000: "-1250.00"} {
+		if got := New(minor, "").String(); got != want {
+			t.Errorf("New(%d).String() = %q, want %q", minor, got, want)
+		}
+	}
+}
+
+func TestJSON(t *testing.T) {
+	var v struct {
+		A Money `json:"a"`
+		B Money `json:"b"`
+		C Money `json:"c"`
+	}
+	if err := json.Unmarshal([]byte(`{"a": 3400.5, "b": "₹1,23,456.78", "c": null}`), &v); err != nil {
+		t.Fatal(err)
+	}
+	if v.A.Minor != 340050 || v.B.Minor != 12345678 || v.C.Minor != 0 {
+		t.Errorf("decoded %+v", v)
+	}
+	out, err := json.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `{"a":3400.50,"b":123456.78,"c":0.00}`; string(out) != want {
+		t.Errorf("encoded %s, want %s", out, want)
+	}
+}
+
+func TestScan(t *testing.T) {
+	tests := []struct {
+		src  interface{}
+		want int64
+	}{
+		{nil, 0},
+		{int64(12), 1200},
+		{12.345, 1235},
+		{"1234.56", 123456},
+		{[]byte("-0.50"), -50},
+		{big.NewInt(7), 700},
+		{big.NewFloat(2.5), 250},
+	}
+	for _, tt := range tests {
+		var m Money
+		if err := m.Scan(tt.src); err != nil {
+			t.Errorf("Scan(%v): %v", tt.src, err)
+			continue
+		}
+		if m.Minor != tt.want {
+			t.Errorf("Scan(%v) = %d, want %d", tt.src, m.Minor, tt.want)
+		}
+	}
+}
+
+func TestOutOfRange(t *testing.T) {
+	huge := new(big.Int).Lsh(big.NewInt(1), 62)
+	if m, err := FromBigInt(huge, ""); err == nil {
+		t.Errorf("FromBigInt(2^62) = %+v, want error", m)
+	}
+	var m Money
+	if err := m.Scan(huge); err == nil {
+		t.Errorf("Scan(2^62) = %+v, want error", m)
+	}
+	if err := m.Scan(new(big.Float).SetInt(huge)); err == nil {
+		t.Errorf("Scan(2^62 as float) = %+v, want error", m)
+	}
+	if got := FromFloat(1e30, ""); got.Minor != math.MaxInt64 {
+		t.Errorf("FromFloat(1e30) = %d, want saturation", got.Minor)
+	}
+	if got := FromFloat(-1e30, ""); got.Minor != math.MinInt64 {
+		t.Errorf("FromFloat(-1e30) = %d, want saturation", got.Minor)
+	}
+}
+
+func TestParseNumber(t *testing.T) {
+	tests := map[string]float64{
+		"2":         2,
+		"2 kg":      2,
+		"1,000 pcs": 1000,
+		"0.125":     0.125,
+		"9%":        9,
+		"₹99":       99,
+		"Rs. 12.5":  12.5,
+		"-3":        -3,
+	}
+	for in, want := range tests {
+		got, err := ParseNumber(in)
+		if err != nil || got != want {
+			t.Errorf("ParseNumber(%q) = %v, %v; want %v", in, got, err, want)
+		}
+	}
+	for _, in := range []string{"", "N/A", "kg"} {
+		if got, err := ParseNumber(in); err == nil {
+			t.Errorf("ParseNumber(%q) = %v, want error", in, got)
+		}
+	}
+}
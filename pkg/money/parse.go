@@ -0,0 +1,211 @@
+package money
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// currencyMarkers map the symbols and codes that may precede or follow an
+// amount to currency codes.  Longer markers come first so "Rs." is not
+// read as "Rs" followed by a stray dot.
+var currencyMarkers = []struct {
+	marker, code string
+}{
+	{"INR", "INR"},
+	{"USD", "USD"},
+	{"EUR", "EUR"},
+	{"GBP", "GBP"},
+	{"Rs.", "INR"},
+	{"Rs", "INR"},
+	{"₹", "INR"},
+	{"$", "USD"},
+	{"€", "EUR"},
+	{"£", "GBP"},
+}
+
+// Parse reads an amount as printed: "1,23,456.78" (Indian grouping),
+// "123,456.78", "₹ 1,250", "Rs. 99.5", "Rs. 500/-", "INR 10", "-42.00",
+// "42.00-" and "(1,250.00)" (a negative).  Spreadsheet currency wrappers
+// such as "[$₹-4009]" are ignored apart from their symbol.  A currency symbol or
+// code sets Currency; without one Currency is empty.  More than two
+// decimals are rounded half away from zero.
+func Parse(s string) (Money, error) {
+	orig := s
+	s = strings.TrimSpace(s)
+	neg := false
+	if strings.HasPrefix(s, "(") && strings.HasSuffix(s, ")") {
+		neg = true
+		s = strings.TrimSpace(s[1 : len(s)-1])
+	}
+
+	currency := ""
+	// Spreadsheet number formats: "[$₹-4009] 1,250.00".
+	for {
+		i := strings.Index(s, "[$")
+		if i < 0 {
+			break
+		}
+		j := strings.Index(s[i:], "]")
+		if j < 0 {
+			break
+		}
+		inner := strings.SplitN(s[i+2:i+j], "-", 2)[0]
+		if code := currencyCode(inner); code != "" {
+			currency = code
+		}
+		s = strings.TrimSpace(s[:i] + s[i+j+1:])
+	}
+
+	// Signs and currency markers may come in either order on both ends.
+	for changed := true; changed; {
+		changed = false
+		switch {
+		case strings.HasSuffix(s, "/-"):
+			// "Rs. 500/-": the amount is in whole rupees.
+			s, changed = strings.TrimSpace(s[:len(s)-2]), true
+		case strings.HasPrefix(s, "-"):
+			neg, s, changed = !neg, strings.TrimSpace(s[1:]), true
+		case strings.HasSuffix(s, "-"):
+			neg, s, changed = !neg, strings.TrimSpace(s[:len(s)-1]), true
+		case strings.HasPrefix(s, "+"):
+			s, changed = strings.TrimSpace(s[1:]), true
+		}
+		for _, cm := range currencyMarkers {
+			if hasPrefixFold(s, cm.marker) {
+				currency, s, changed = cm.code, strings.TrimSpace(s[len(cm.marker):]), true
+				break
+			}
+			if hasSuffixFold(s, cm.marker) && !strings.HasSuffix(cm.marker, ".") {
+				currency, s, changed = cm.code, strings.TrimSpace(s[:len(s)-len(cm.marker)]), true
+				break
+			}
+		}
+	}
+
+	if err := checkGrouping(s); err != nil {
+		return Money{}, fmt.Errorf("%q is not an amount: %w", orig, err)
+	}
+	m, err := parseDecimal(strings.ReplaceAll(s, ",", ""))
+	if err != nil {
+		return Money{}, fmt.Errorf("%q is not an amount: %w", orig, err)
+	}
+	if neg {
+		m.Minor = -m.Minor
+	}
+	m.Currency = currency
+	return m, nil
+}
+
+// MustParse is Parse for constants in tests and defaults; it panics on
+// error.
+func MustParse(s string) Money {
+	m, err := Parse(s)
+	if err != nil {
+		panic(err)
+	}
+	return m
+}
+
+func currencyCode(marker string) string {
+	marker = strings.TrimSpace(marker)
+	for _, cm := range currencyMarkers {
+		if strings.EqualFold(cm.marker, marker) {
+			return cm.code
+		}
+	}
+	return ""
+}
+
+func hasPrefixFold(s, prefix string) bool {
+	return len(s) >= len(prefix) && strings.EqualFold(s[:len(prefix)], prefix)
+}
+
+func hasSuffixFold(s, suffix string) bool {
+	return len(s) >= len(suffix) && strings.EqualFold(s[len(s)-len(suffix):], suffix)
+}
+
+// checkGrouping accepts digits grouped by commas in threes (123,456.78) or,
+// above the thousands, in twos (1,23,456.78).
+func checkGrouping(s string) error {
+	whole, frac, _ := strings.Cut(s, ".")
+	if strings.Contains(frac, ",") {
+		return fmt.Errorf("comma in the decimals")
+	}
+	if !strings.Contains(whole, ",") {
+		return nil
+	}
+	groups := strings.Split(whole, ",")
+	for i, g := range groups {
+		switch {
+		case g == "":
+			return fmt.Errorf("misplaced comma")
+		case i == 0 && len(g) > 3:
+			return fmt.Errorf("misplaced comma")
+		case i == len(groups)-1 && len(g) != 3:
+			return fmt.Errorf("misplaced comma")
+		case i > 0 && i < len(groups)-1 && len(g) != 2 && len(g) != 3:
+			return fmt.Errorf("misplaced comma")
+		}
+	}
+	return nil
+}
+
+// parseDecimal reads a plain decimal number ("-1234.565", "1e3") into
+// minor units.
+func parseDecimal(s string) (Money, error) {
+	if strings.ContainsAny(s, "eE") {
+		f, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return Money{}, fmt.Errorf("invalid number")
+		}
+		return parseDecimal(strconv.FormatFloat(f, 'f', -1, 64))
+	}
+	neg := strings.HasPrefix(s, "-")
+	s = strings.TrimPrefix(s, "-")
+	whole, frac, _ := strings.Cut(s, ".")
+	if whole == "" && frac == "" {
+		return Money{}, fmt.Errorf("no digits")
+	}
+	if whole == "" {
+		whole = "0"
+	}
+	for _, part := range []string{whole, frac} {
+		for _, r := range part {
+			if r < '0' || r > '9' {
+				return Money{}, fmt.Errorf("unexpected %q", r)
+			}
+		}
+	}
+
+	units, err := strconv.ParseInt(whole, 10, 64)
+	if err != nil || units > (1<<63-1)/scale-1 {
+		return Money{}, fmt.Errorf("out of range")
+	}
+	frac += "000"
+	minor, _ := strconv.ParseInt(frac[:2], 10, 64)
+	if frac[2] >= '5' {
+		minor++
+	}
+	m := Money{Minor: units*scale + minor}
+	if neg {
+		m.Minor = -m.Minor
+	}
+	return m, nil
+}
+
+// numberRe finds a plain number, optionally grouped with commas.
+var numberRe = regexp.MustCompile(`-?\d[\d,]*(?:\.\d+)?`)
+
+// ParseNumber reads the first number in s, such as a quantity ("2 kg",
+// "1,000 pcs"), a rate ("9%") or a count ("₹99" reads as 99).  Commas are
+// grouping separators.  Unlike Parse it keeps every decimal, so it is meant
+// for values that are not amounts.
+func ParseNumber(s string) (float64, error) {
+	num := numberRe.FindString(s)
+	if num == "" {
+		return 0, fmt.Errorf("%q is not a number", s)
+	}
+	return strconv.ParseFloat(strings.ReplaceAll(num, ",", ""), 64)
+}
@@ -2,6 +2,7 @@ package paperless
 
 import (
 	"bytes"
+	"context"
 	"encoding/hex"
 	"encoding/json"
 	"fmt"
@@ -9,13 +10,18 @@ import (
 	"log/slog"
 	"net/http"
 	"net/url"
+	"strconv"
 	"strings"
+	"time"
+
+	"paperless-document-processor/pkg/retry"
 )
 
 type Client struct {
 	baseURL string
 	token   string
 	client  *http.Client
+	retry   retry.Policy
 }
 
 type Document struct {
@@ -29,6 +35,7 @@ type Document struct {
 	ArchivedFileName string                `json:"archived_file_name"`
 	CustomFields     []CustomFieldInstance `json:"custom_fields"`
 	Tags             []int                 `json:"tags"`
+	StoragePath      *int                  `json:"storage_path"`
 }
 
 type Metadata struct {
@@ -47,9 +54,10 @@ type CustomFieldInstance struct {
 }
 
 type CustomField struct {
-	ID       int    `json:"id"`
-	Name     string `json:"name"`
-	DataType string `json:"data_type"` // e.g., "date", "monetary", "string"
+	ID        int                  `json:"id"`
+	Name      string               `json:"name"`
+	DataType  string               `json:"data_type"` // e.g., "date", "monetary", "string"
+	ExtraData CustomFieldExtraData `json:"extra_data,omitempty"`
 }
 
 type Correspondent struct {
@@ -58,6 +66,12 @@ type Correspondent struct {
 	Slug string `json:"slug"`
 }
 
+type StoragePath struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+	Path string `json:"path"`
+}
+
 type Tag struct {
 	ID   int    `json:"id"`
 	Name string `json:"name"`
@@ -79,54 +93,83 @@ func NewClient(baseURL, token string) *Client {
 	}
 }
 
-func (c *Client) request(method, path string, body interface{}) (*http.Response, error) {
+// SetRetryPolicy configures how transient API failures (timeouts, 429, 5xx)
+// are retried.  The default is a single attempt.
+func (c *Client) SetRetryPolicy(p retry.Policy) {
+	c.retry = p
+}
+
+// request performs an API call.  Error responses are returned as a
+// *retry.StatusError and, for GET, PUT, PATCH and DELETE, transient ones are
+// retried per the client's policy until ctx is done.  POSTs (notes,
+// correspondents, custom fields, bulk edits) are never retried: one that
+// timed out may still have been committed, and sending it again would
+// duplicate it.
+func (c *Client) request(ctx context.Context, method, path string, body interface{}) (*http.Response, error) {
 	u := fmt.Sprintf("%s/api/%s", c.baseURL, path)
 	slog.Debug("Paperless API request", "method", method, "url", u)
 
-	var buf io.Reader
+	var jsonBody []byte
 	if body != nil {
-		jsonBody, err := json.Marshal(body)
+		var err error
+		jsonBody, err = json.Marshal(body)
 		if err != nil {
 			slog.Error("Failed to marshal request body", "error", err)
 			return nil, err
 		}
-		buf = bytes.NewBuffer(jsonBody)
 	}
 
-	req, err := http.NewRequest(method, u, buf)
-	if err != nil {
-		slog.Error("Failed to create request", "error", err)
-		return nil, err
+	policy := c.retry
+	if method == http.MethodPost {
+		policy = retry.Policy{}
 	}
 
-	req.Header.Set("Authorization", fmt.Sprintf("Token %s", c.token))
-	if body != nil {
-		req.Header.Set("Content-Type", "application/json")
-	}
+	var resp *http.Response
+	err := retry.Do(ctx, policy, "paperless "+method+" "+path, func() error {
+		var buf io.Reader
+		if jsonBody != nil {
+			buf = bytes.NewReader(jsonBody)
+		}
 
-	slog.Debug("Executing Paperless request", "method", method, "url", u)
-	resp, err := c.client.Do(req)
-	if err != nil {
-		slog.Error("API request execution failed", "method", method, "url", u, "error", err)
-		return nil, err
-	}
+		req, err := http.NewRequestWithContext(ctx, method, u, buf)
+		if err != nil {
+			slog.Error("Failed to create request", "error", err)
+			return err
+		}
 
-	slog.Debug("Paperless response received", "method", method, "url", u, "status", resp.Status)
+		req.Header.Set("Authorization", fmt.Sprintf("Token %s", c.token))
+		if body != nil {
+			req.Header.Set("Content-Type", "application/json")
+		}
 
-	if resp.StatusCode >= 400 {
-		respBody, _ := io.ReadAll(resp.Body)
-		resp.Body.Close()
-		err := fmt.Errorf("api request failed: %s %s: status %d body: %s", method, u, resp.StatusCode, string(respBody))
-		slog.Error("Paperless API error", "status", resp.StatusCode, "method", method, "url", u, "response", string(respBody))
+		slog.Debug("Executing Paperless request", "method", method, "url", u)
+		r, err := c.client.Do(req)
+		if err != nil {
+			slog.Error("API request execution failed", "method", method, "url", u, "error", err)
+			return err
+		}
+
+		slog.Debug("Paperless response received", "method", method, "url", u, "status", r.Status)
+
+		if r.StatusCode >= 400 {
+			respBody, _ := io.ReadAll(r.Body)
+			r.Body.Close()
+			slog.Error("Paperless API error", "status", r.StatusCode, "method", method, "url", u, "response", string(respBody))
+			return retry.NewStatusError(r, fmt.Errorf("api request failed: %s %s: status %d body: %s", method, u, r.StatusCode, string(respBody)))
+		}
+
+		resp = r
+		return nil
+	})
+	if err != nil {
 		return nil, err
 	}
-
 	return resp, nil
 }
 
-func (c *Client) GetDocument(id int) (*Document, error) {
+func (c *Client) GetDocument(ctx context.Context, id int) (*Document, error) {
 	slog.Info("Fetching document from Paperless", "id", id)
-	resp, err := c.request("GET", fmt.Sprintf("documents/%d/", id), nil)
+	resp, err := c.request(ctx, "GET", fmt.Sprintf("documents/%d/", id), nil)
 	if err != nil {
 		return nil, err
 	}
@@ -140,9 +183,80 @@ func (c *Client) GetDocument(id int) (*Document, error) {
 	return &doc, nil
 }
 
-func (c *Client) GetMetadata(id int) (*Metadata, error) {
+// DocumentQuery filters a document list.  Zero-valued fields are not sent.
+type DocumentQuery struct {
+	TagsAll       []int     // documents carrying every one of these tags (tags__id__all)
+	ModifiedAfter time.Time // documents changed strictly after this time (modified__gt)
+	Query         string    // full-text search (query)
+	Ordering      string    // e.g. "modified" or "-created"
+	PageSize      int       // results per page; the server default applies when 0
+}
+
+func (q DocumentQuery) values() url.Values {
+	v := url.Values{}
+	if len(q.TagsAll) > 0 {
+		ids := make([]string, len(q.TagsAll))
+		for i, id := range q.TagsAll {
+			ids[i] = strconv.Itoa(id)
+		}
+		v.Set("tags__id__all", strings.Join(ids, ","))
+	}
+	if !q.ModifiedAfter.IsZero() {
+		v.Set("modified__gt", q.ModifiedAfter.UTC().Format(time.RFC3339Nano))
+	}
+	if q.Query != "" {
+		v.Set("query", q.Query)
+	}
+	if q.Ordering != "" {
+		v.Set("ordering", q.Ordering)
+	}
+	if q.PageSize > 0 {
+		v.Set("page_size", strconv.Itoa(q.PageSize))
+	}
+	return v
+}
+
+// ListDocuments returns every document matching q, following pagination.
+func (c *Client) ListDocuments(ctx context.Context, q DocumentQuery) ([]Document, error) {
+	var allDocs []Document
+	nextURL := "documents/"
+	if params := q.values().Encode(); params != "" {
+		nextURL += "?" + params
+	}
+
+	for nextURL != "" {
+		resp, err := c.request(ctx, "GET", nextURL, nil)
+		if err != nil {
+			return nil, err
+		}
+
+		var page PaginatedResponse[Document]
+		err = json.NewDecoder(resp.Body).Decode(&page)
+		resp.Body.Close()
+		if err != nil {
+			slog.Error("Failed to decode document list response", "error", err)
+			return nil, err
+		}
+
+		allDocs = append(allDocs, page.Results...)
+		nextURL = nextPagePath(page.Next)
+	}
+	slog.Debug("Listed documents", "count", len(allDocs), "query", q.values().Encode())
+	return allDocs, nil
+}
+
+// nextPagePath converts the absolute "next" link of a paginated response
+// into a path relative to /api/, or "" on the last page.
+func nextPagePath(next string) string {
+	if _, path, ok := strings.Cut(next, "/api/"); ok {
+		return path
+	}
+	return ""
+}
+
+func (c *Client) GetMetadata(ctx context.Context, id int) (*Metadata, error) {
 	slog.Debug("Fetching document metadata", "id", id)
-	resp, err := c.request("GET", fmt.Sprintf("documents/%d/metadata/", id), nil)
+	resp, err := c.request(ctx, "GET", fmt.Sprintf("documents/%d/metadata/", id), nil)
 	if err != nil {
 		return nil, err
 	}
@@ -156,35 +270,42 @@ func (c *Client) GetMetadata(id int) (*Metadata, error) {
 	return &meta, nil
 }
 
-func (c *Client) DownloadDocument(id int, original bool) ([]byte, error) {
+func (c *Client) DownloadDocument(ctx context.Context, id int, original bool) ([]byte, error) {
 	slog.Info("Downloading document content", "id", id, "original", original)
 	u := fmt.Sprintf("%s/api/documents/%d/download/", c.baseURL, id)
 	if original {
 		u += "?original=true"
 	}
 
-	req, err := http.NewRequest("GET", u, nil)
-	if err != nil {
-		slog.Error("Failed to create download request", "id", id, "error", err)
-		return nil, err
-	}
-	req.Header.Set("Authorization", fmt.Sprintf("Token %s", c.token))
+	var data []byte
+	err := retry.Do(ctx, c.retry, fmt.Sprintf("paperless download %d", id), func() error {
+		req, err := http.NewRequestWithContext(ctx, "GET", u, nil)
+		if err != nil {
+			slog.Error("Failed to create download request", "id", id, "error", err)
+			return err
+		}
+		req.Header.Set("Authorization", fmt.Sprintf("Token %s", c.token))
 
-	resp, err := c.client.Do(req)
-	if err != nil {
-		slog.Error("Download request error", "id", id, "error", err)
-		return nil, err
-	}
-	defer resp.Body.Close()
+		resp, err := c.client.Do(req)
+		if err != nil {
+			slog.Error("Download request error", "id", id, "error", err)
+			return err
+		}
+		defer resp.Body.Close()
 
-	if resp.StatusCode != 200 {
-		slog.Error("Failed to download document", "id", id, "status", resp.StatusCode)
-		return nil, fmt.Errorf("failed to download document: status %d", resp.StatusCode)
-	}
+		if resp.StatusCode != 200 {
+			slog.Error("Failed to download document", "id", id, "status", resp.StatusCode)
+			return retry.NewStatusError(resp, fmt.Errorf("failed to download document: status %d", resp.StatusCode))
+		}
 
-	data, err := io.ReadAll(resp.Body)
+		data, err = io.ReadAll(resp.Body)
+		if err != nil {
+			slog.Error("Failed to read response body", "id", id, "error", err)
+			return err
+		}
+		return nil
+	})
 	if err != nil {
-		slog.Error("Failed to read response body", "id", id, "error", err)
 		return nil, err
 	}
 
@@ -205,12 +326,12 @@ func (c *Client) DownloadDocument(id int, original bool) ([]byte, error) {
 	return data, nil
 }
 
-func (c *Client) GetCustomFields() ([]CustomField, error) {
+func (c *Client) GetCustomFields(ctx context.Context) ([]CustomField, error) {
 	var allFields []CustomField
 	nextURL := "custom_fields/"
 
 	for nextURL != "" {
-		resp, err := c.request("GET", nextURL, nil)
+		resp, err := c.request(ctx, "GET", nextURL, nil)
 		if err != nil {
 			return nil, err
 		}
@@ -241,12 +362,46 @@ func (c *Client) GetCustomFields() ([]CustomField, error) {
 	return allFields, nil
 }
 
-func (c *Client) GetTags() ([]Tag, error) {
+// CreateCustomField creates a custom field with the given data type.
+func (c *Client) CreateCustomField(ctx context.Context, name, dataType string) (*CustomField, error) {
+	slog.Info("Creating custom field in Paperless", "name", name, "data_type", dataType)
+	body := map[string]string{"name": name, "data_type": dataType}
+	resp, err := c.request(ctx, "POST", "custom_fields/", body)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var field CustomField
+	if err := json.NewDecoder(resp.Body).Decode(&field); err != nil {
+		slog.Error("Failed to decode custom field response", "name", name, "error", err)
+		return nil, err
+	}
+	return &field, nil
+}
+
+// GetStoragePath returns a storage path by ID.
+func (c *Client) GetStoragePath(ctx context.Context, id int) (*StoragePath, error) {
+	resp, err := c.request(ctx, "GET", fmt.Sprintf("storage_paths/%d/", id), nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var sp StoragePath
+	if err := json.NewDecoder(resp.Body).Decode(&sp); err != nil {
+		slog.Error("Failed to decode storage path response", "id", id, "error", err)
+		return nil, err
+	}
+	return &sp, nil
+}
+
+func (c *Client) GetTags(ctx context.Context) ([]Tag, error) {
 	var allTags []Tag
 	nextURL := "tags/"
 
 	for nextURL != "" {
-		resp, err := c.request("GET", nextURL, nil)
+		resp, err := c.request(ctx, "GET", nextURL, nil)
 		if err != nil {
 			return nil, err
 		}
@@ -277,7 +432,7 @@ func (c *Client) GetTags() ([]Tag, error) {
 	return allTags, nil
 }
 
-func (c *Client) GetCorrespondent(name string) (*Correspondent, error) {
+func (c *Client) GetCorrespondent(ctx context.Context, name string) (*Correspondent, error) {
 	// Search by name (slug search is better if we can normalize, but name search via list with query param)
 	// paperless api allows filtering correspondents? yes: /api/correspondents/?name__icontains=...
 	// but exact match is harder. Let's fetch all (cached maybe?) or search.
@@ -286,7 +441,7 @@ func (c *Client) GetCorrespondent(name string) (*Correspondent, error) {
 	q.Set("name__iexact", name) // Case insensitive exact match
 	path := fmt.Sprintf("correspondents/?%s", q.Encode())
 
-	resp, err := c.request("GET", path, nil)
+	resp, err := c.request(ctx, "GET", path, nil)
 	if err != nil {
 		return nil, err
 	}
@@ -303,10 +458,35 @@ func (c *Client) GetCorrespondent(name string) (*Correspondent, error) {
 	return nil, nil
 }
 
-func (c *Client) CreateCorrespondent(name string) (*Correspondent, error) {
+// GetCorrespondents returns every correspondent, following pagination.
+func (c *Client) GetCorrespondents(ctx context.Context) ([]Correspondent, error) {
+	var all []Correspondent
+	nextURL := "correspondents/"
+
+	for nextURL != "" {
+		resp, err := c.request(ctx, "GET", nextURL, nil)
+		if err != nil {
+			return nil, err
+		}
+
+		var page PaginatedResponse[Correspondent]
+		err = json.NewDecoder(resp.Body).Decode(&page)
+		resp.Body.Close()
+		if err != nil {
+			slog.Error("Failed to decode correspondent list response", "error", err)
+			return nil, err
+		}
+
+		all = append(all, page.Results...)
+		nextURL = nextPagePath(page.Next)
+	}
+	return all, nil
+}
+
+func (c *Client) CreateCorrespondent(ctx context.Context, name string) (*Correspondent, error) {
 	slog.Info("Creating correspondent in Paperless", "name", name)
 	body := map[string]string{"name": name, "match": "", "matching_algorithm": "1", "is_insensitive": "true"}
-	resp, err := c.request("POST", "correspondents/", body)
+	resp, err := c.request(ctx, "POST", "correspondents/", body)
 	if err != nil {
 		return nil, err
 	}
@@ -325,15 +505,86 @@ type DocumentUpdate struct {
 	Content       *string               `json:"content,omitempty"`
 	Correspondent *int                  `json:"correspondent,omitempty"`
 	CustomFields  []CustomFieldInstance `json:"custom_fields,omitempty"`
+	// AddTags and RemoveTags change the document's tags relative to its
+	// current ones; tags not listed are left alone.
+	AddTags    []int `json:"add_tags,omitempty"`
+	RemoveTags []int `json:"remove_tags,omitempty"`
 }
 
-func (c *Client) UpdateDocument(id int, update DocumentUpdate) error {
-	slog.Info("Updating document metadata", "id", id)
-	resp, err := c.request("PATCH", fmt.Sprintf("documents/%d/", id), update)
-	if err != nil {
-		return err
+// documentPatch is the PATCH body for the fields of DocumentUpdate that the
+// document endpoint sets directly.
+type documentPatch struct {
+	Title         *string               `json:"title,omitempty"`
+	Content       *string               `json:"content,omitempty"`
+	Correspondent *int                  `json:"correspondent,omitempty"`
+	CustomFields  []CustomFieldInstance `json:"custom_fields,omitempty"`
+}
+
+// UpdateDocument PATCHes the document's fields and then applies any tag
+// changes with a bulk edit.  Either step is skipped when it has nothing to do.
+func (c *Client) UpdateDocument(ctx context.Context, id int, update DocumentUpdate) error {
+	patch := documentPatch{
+		Title:         update.Title,
+		Content:       update.Content,
+		Correspondent: update.Correspondent,
+		CustomFields:  update.CustomFields,
+	}
+	if patch.Title != nil || patch.Content != nil || patch.Correspondent != nil || len(patch.CustomFields) > 0 {
+		slog.Info("Updating document metadata", "id", id)
+		resp, err := c.request(ctx, "PATCH", fmt.Sprintf("documents/%d/", id), patch)
+		if err != nil {
+			return err
+		}
+		resp.Body.Close()
+	}
+
+	if len(update.AddTags) > 0 || len(update.RemoveTags) > 0 {
+		if err := c.ModifyTags(ctx, id, update.AddTags, update.RemoveTags); err != nil {
+			return err
+		}
 	}
-	defer resp.Body.Close()
 	slog.Info("Successfully updated document in Paperless", "id", id)
 	return nil
 }
+
+// AddNote attaches a note to the document, shown in the Paperless UI's
+// Notes tab.
+func (c *Client) AddNote(ctx context.Context, id int, note string) error {
+	slog.Info("Adding note to document", "id", id)
+	resp, err := c.request(ctx, "POST", fmt.Sprintf("documents/%d/notes/", id), map[string]string{"note": note})
+	if err != nil {
+		return fmt.Errorf("failed to add note: %w", err)
+	}
+	resp.Body.Close()
+	return nil
+}
+
+type bulkEditRequest struct {
+	Documents  []int          `json:"documents"`
+	Method     string         `json:"method"`
+	Parameters map[string]any `json:"parameters"`
+}
+
+// ModifyTags adds and removes tags on a document in a single bulk edit,
+// which avoids racing other tag changes the way a PATCH of the full tag
+// list would.
+func (c *Client) ModifyTags(ctx context.Context, id int, add, remove []int) error {
+	slog.Info("Modifying document tags", "id", id, "add", add, "remove", remove)
+	if add == nil {
+		add = []int{}
+	}
+	if remove == nil {
+		remove = []int{}
+	}
+	body := bulkEditRequest{
+		Documents:  []int{id},
+		Method:     "modify_tags",
+		Parameters: map[string]any{"add_tags": add, "remove_tags": remove},
+	}
+	resp, err := c.request(ctx, "POST", "documents/bulk_edit/", body)
+	if err != nil {
+		return fmt.Errorf("failed to modify tags: %w", err)
+	}
+	resp.Body.Close()
+	return nil
+}
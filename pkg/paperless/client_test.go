@@ -0,0 +1,218 @@
+package paperless
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"paperless-document-processor/pkg/retry"
+)
+
+func TestListDocuments_FiltersAndPagination(t *testing.T) {
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/documents/" {
+			t.Errorf("Unexpected request: %s %s", r.Method, r.URL.Path)
+			return
+		}
+		if got := r.Header.Get("Authorization"); got != "Token tok" {
+			t.Errorf("Expected token auth, got %q", got)
+		}
+		q := r.URL.Query()
+		if got := q.Get("tags__id__all"); got != "3,7" {
+			t.Errorf("Expected tags__id__all=3,7, got %q", got)
+		}
+		if got := q.Get("modified__gt"); got != "2024-05-01T10:00:00.5Z" {
+			t.Errorf("Expected modified__gt=2024-05-01T10:00:00.5Z, got %q", got)
+		}
+		if got := q.Get("ordering"); got != "modified" {
+			t.Errorf("Expected ordering=modified, got %q", got)
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if q.Get("page") == "2" {
+			json.NewEncoder(w).Encode(PaginatedResponse[Document]{
+				Count:   3,
+				Results: []Document{{ID: 3, Modified: "2024-05-03T00:00:00Z"}},
+			})
+			return
+		}
+		next := fmt.Sprintf("%s/api/documents/?%s&page=2", server.URL, r.URL.RawQuery)
+		json.NewEncoder(w).Encode(PaginatedResponse[Document]{
+			Count:   3,
+			Next:    next,
+			Results: []Document{{ID: 1, Modified: "2024-05-01T11:00:00Z"}, {ID: 2, Modified: "2024-05-02T00:00:00Z"}},
+		})
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL, "tok")
+	docs, err := client.ListDocuments(context.Background(), DocumentQuery{
+		TagsAll:       []int{3, 7},
+		ModifiedAfter: time.Date(2024, 5, 1, 15, 30, 0, 500_000_000, time.FixedZone("IST", 5*3600+1800)),
+		Ordering:      "modified",
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(docs) != 3 {
+		t.Fatalf("Expected 3 documents across pages, got %d", len(docs))
+	}
+	for i, doc := range docs {
+		if doc.ID != i+1 {
+			t.Errorf("Expected document %d at index %d, got %d", i+1, i, doc.ID)
+		}
+	}
+}
+
+func TestListDocuments_NoFilters(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.RawQuery != "" {
+			t.Errorf("Expected no query parameters, got %q", r.URL.RawQuery)
+		}
+		json.NewEncoder(w).Encode(PaginatedResponse[Document]{Results: []Document{}})
+	}))
+	defer server.Close()
+
+	docs, err := NewClient(server.URL, "tok").ListDocuments(context.Background(), DocumentQuery{})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(docs) != 0 {
+		t.Errorf("Expected no documents, got %d", len(docs))
+	}
+}
+
+func TestUpdateDocument_FieldsAndTags(t *testing.T) {
+	var patched, bulkEdited bool
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == "PATCH" && r.URL.Path == "/api/documents/73/":
+			var body map[string]any
+			json.NewDecoder(r.Body).Decode(&body)
+			if body["content"] != "text" {
+				t.Errorf("Expected content in PATCH body, got %v", body)
+			}
+			if _, ok := body["add_tags"]; ok {
+				t.Errorf("Tag changes must not be sent in the PATCH body: %v", body)
+			}
+			patched = true
+			w.Write([]byte(`{}`))
+		case r.Method == "POST" && r.URL.Path == "/api/documents/bulk_edit/":
+			var body bulkEditRequest
+			json.NewDecoder(r.Body).Decode(&body)
+			if body.Method != "modify_tags" || len(body.Documents) != 1 || body.Documents[0] != 73 {
+				t.Errorf("Unexpected bulk edit request: %+v", body)
+			}
+			if fmt.Sprint(body.Parameters["add_tags"]) != "[5]" || fmt.Sprint(body.Parameters["remove_tags"]) != "[2 3]" {
+				t.Errorf("Unexpected tag parameters: %v", body.Parameters)
+			}
+			bulkEdited = true
+			w.Write([]byte(`{"result": "OK"}`))
+		default:
+			t.Errorf("Unexpected request: %s %s", r.Method, r.URL.Path)
+		}
+	}))
+	defer server.Close()
+
+	content := "text"
+	err := NewClient(server.URL, "tok").UpdateDocument(context.Background(), 73, DocumentUpdate{
+		Content:    &content,
+		AddTags:    []int{5},
+		RemoveTags: []int{2, 3},
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if !patched || !bulkEdited {
+		t.Errorf("Expected both PATCH and bulk edit, got patched=%v bulkEdited=%v", patched, bulkEdited)
+	}
+}
+
+func TestUpdateDocument_TagsOnlySkipsPatch(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "POST" || r.URL.Path != "/api/documents/bulk_edit/" {
+			t.Errorf("Unexpected request: %s %s", r.Method, r.URL.Path)
+		}
+		w.Write([]byte(`{"result": "OK"}`))
+	}))
+	defer server.Close()
+
+	if err := NewClient(server.URL, "tok").UpdateDocument(context.Background(), 73, DocumentUpdate{AddTags: []int{1}}); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+}
+
+func TestCreateCustomField(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "POST" || r.URL.Path != "/api/custom_fields/" {
+			t.Errorf("Unexpected request: %s %s", r.Method, r.URL.Path)
+		}
+		var body map[string]string
+		json.NewDecoder(r.Body).Decode(&body)
+		if body["name"] != "Net Amount" || body["data_type"] != "monetary" {
+			t.Errorf("Unexpected create body: %v", body)
+		}
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`{"id": 12, "name": "Net Amount", "data_type": "monetary"}`))
+	}))
+	defer server.Close()
+
+	field, err := NewClient(server.URL, "tok").CreateCustomField(context.Background(), "Net Amount", DataTypeMonetary)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if field.ID != 12 || field.DataType != DataTypeMonetary {
+		t.Errorf("Unexpected field: %+v", field)
+	}
+}
+
+func TestRequest_RetriesOnlyIdempotentMethods(t *testing.T) {
+	var gets, posts int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case "GET":
+			gets++
+		case "POST":
+			posts++
+		}
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL, "tok")
+	client.SetRetryPolicy(retry.Policy{MaxAttempts: 3, InitialBackoff: time.Millisecond})
+	if _, err := client.GetDocument(context.Background(), 73); err == nil {
+		t.Errorf("Expected GetDocument to fail")
+	}
+	if err := client.AddNote(context.Background(), 73, "note"); err == nil {
+		t.Errorf("Expected AddNote to fail")
+	}
+	if gets != 3 || posts != 1 {
+		t.Errorf("Expected 3 GET attempts and 1 POST attempt, got %d and %d", gets, posts)
+	}
+}
+
+func TestRequest_CancelStopsRetries(t *testing.T) {
+	var attempts int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL, "tok")
+	client.SetRetryPolicy(retry.Policy{MaxAttempts: 5, InitialBackoff: time.Hour})
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if _, err := client.GetTags(ctx); err == nil {
+		t.Errorf("Expected GetTags to fail")
+	}
+	if attempts != 1 {
+		t.Errorf("Expected a single attempt before cancellation, got %d", attempts)
+	}
+}
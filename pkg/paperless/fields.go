@@ -0,0 +1,139 @@
+package paperless
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"paperless-document-processor/pkg/dates"
+	"paperless-document-processor/pkg/money"
+)
+
+// Custom field data types as reported in CustomField.DataType.
+const (
+	DataTypeString       = "string"
+	DataTypeLongText     = "longtext"
+	DataTypeURL          = "url"
+	DataTypeDate         = "date"
+	DataTypeBoolean      = "boolean"
+	DataTypeInteger      = "integer"
+	DataTypeFloat        = "float"
+	DataTypeMonetary     = "monetary"
+	DataTypeSelect       = "select"
+	DataTypeDocumentLink = "documentlink"
+)
+
+// maxStringFieldLength is Paperless's limit, in characters, for "string"
+// custom fields.
+const maxStringFieldLength = 128
+
+// CustomFieldExtraData holds the type-specific settings of a custom field.
+type CustomFieldExtraData struct {
+	// SelectOptions are either plain labels (older Paperless, selected by
+	// index) or {"id", "label"} objects (selected by ID).
+	SelectOptions   []json.RawMessage `json:"select_options,omitempty"`
+	DefaultCurrency string            `json:"default_currency,omitempty"`
+}
+
+type selectOption struct {
+	ID    string `json:"id"`
+	Label string `json:"label"`
+}
+
+var currencyCodeRe = regexp.MustCompile(`^[A-Z]{3}$`)
+
+// Coerce converts raw text into the value Paperless expects for the field's
+// data type: monetary as "<CUR>123.45", dates as YYYY-MM-DD, integers and
+// floats as numbers, booleans as bools and select fields by option ID (or
+// index on older Paperless versions).  currency is used for monetary fields
+// and falls back to the currency printed with the amount, the field's
+// default currency, defaultCurrency, then INR.  Dates are read with dp.
+func (f CustomField) Coerce(raw, currency, defaultCurrency string, dp dates.Parser) (interface{}, error) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return nil, fmt.Errorf("empty value")
+	}
+
+	switch f.DataType {
+	case DataTypeString:
+		if r := []rune(raw); len(r) > maxStringFieldLength {
+			raw = string(r[:maxStringFieldLength])
+		}
+		return raw, nil
+	case DataTypeLongText, DataTypeURL, "":
+		return raw, nil
+	case DataTypeDate:
+		iso, err := dp.Normalize(raw)
+		if err != nil {
+			return nil, err
+		}
+		return iso, nil
+	case DataTypeInteger:
+		n, err := money.ParseNumber(raw)
+		if err != nil {
+			return nil, err
+		}
+		if n != float64(int64(n)) {
+			return nil, fmt.Errorf("%q is not a whole number", raw)
+		}
+		return int64(n), nil
+	case DataTypeFloat:
+		return money.ParseNumber(raw)
+	case DataTypeMonetary:
+		m, err := money.Parse(raw)
+		if err != nil {
+			return nil, err
+		}
+		cur := strings.ToUpper(strings.TrimSpace(currency))
+		if !currencyCodeRe.MatchString(cur) {
+			cur = m.Currency
+		}
+		if !currencyCodeRe.MatchString(cur) {
+			cur = strings.ToUpper(f.ExtraData.DefaultCurrency)
+		}
+		if !currencyCodeRe.MatchString(cur) {
+			cur = strings.ToUpper(strings.TrimSpace(defaultCurrency))
+		}
+		if !currencyCodeRe.MatchString(cur) {
+			cur = money.DefaultCurrency
+		}
+		return cur + m.String(), nil
+	case DataTypeBoolean:
+		switch strings.ToLower(raw) {
+		case "true", "yes", "y", "1":
+			return true, nil
+		case "false", "no", "n", "0":
+			return false, nil
+		}
+		return nil, fmt.Errorf("%q is not a boolean", raw)
+	case DataTypeSelect:
+		return f.selectOption(raw)
+	default:
+		return nil, fmt.Errorf("unsupported custom field data type %q", f.DataType)
+	}
+}
+
+// selectOption finds the option whose label matches raw case-insensitively.
+func (f CustomField) selectOption(raw string) (interface{}, error) {
+	labels := make([]string, 0, len(f.ExtraData.SelectOptions))
+	for i, rawOpt := range f.ExtraData.SelectOptions {
+		var label string
+		if err := json.Unmarshal(rawOpt, &label); err == nil {
+			if strings.EqualFold(label, raw) {
+				return i, nil
+			}
+			labels = append(labels, label)
+			continue
+		}
+		var opt selectOption
+		if err := json.Unmarshal(rawOpt, &opt); err != nil {
+			return nil, fmt.Errorf("unexpected select option %s", rawOpt)
+		}
+		if strings.EqualFold(opt.Label, raw) {
+			return opt.ID, nil
+		}
+		labels = append(labels, opt.Label)
+	}
+	return nil, fmt.Errorf("%q is not one of the options %q", raw, labels)
+}
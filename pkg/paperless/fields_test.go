@@ -0,0 +1,96 @@
+package paperless
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"paperless-document-processor/pkg/dates"
+)
+
+func TestCustomFieldCoerce(t *testing.T) {
+	legacySelect := CustomField{DataType: DataTypeSelect, ExtraData: CustomFieldExtraData{
+		SelectOptions: []json.RawMessage{json.RawMessage(`"Food"`), json.RawMessage(`"Rent"`)},
+	}}
+	idSelect := CustomField{DataType: DataTypeSelect, ExtraData: CustomFieldExtraData{
+		SelectOptions: []json.RawMessage{json.RawMessage(`{"id": "a1", "label": "Food"}`), json.RawMessage(`{"id": "b2", "label": "Rent"}`)},
+	}}
+
+	cases := []struct {
+		name     string
+		field    CustomField
+		raw      string
+		currency string
+		want     interface{}
+	}{
+		{"string", CustomField{DataType: DataTypeString}, " INV-12 ", "", "INV-12"},
+		{"string truncated by character", CustomField{DataType: DataTypeString}, strings.Repeat("é", 200), "", strings.Repeat("é", maxStringFieldLength)},
+		{"monetary", CustomField{DataType: DataTypeMonetary}, "123.456", "INR", "INR123.46"},
+		{"monetary grouped with symbol", CustomField{DataType: DataTypeMonetary}, "₹1,23,456.7", "", "INR123456.70"},
+		{"monetary rs prefix", CustomField{DataType: DataTypeMonetary}, "Rs. 100", "", "INR100.00"},
+		{"monetary explicit currency", CustomField{DataType: DataTypeMonetary}, "99", "usd", "USD99.00"},
+		{"monetary field default currency", CustomField{DataType: DataTypeMonetary, ExtraData: CustomFieldExtraData{DefaultCurrency: "EUR"}}, "5", "$", "EUR5.00"},
+		{"monetary printed currency", CustomField{DataType: DataTypeMonetary, ExtraData: CustomFieldExtraData{DefaultCurrency: "EUR"}}, "$12", "", "USD12.00"},
+		{"monetary foreign printed currency", CustomField{DataType: DataTypeMonetary}, "$1,250.00", "", "USD1250.00"},
+		{"monetary field default over config default", CustomField{DataType: DataTypeMonetary, ExtraData: CustomFieldExtraData{DefaultCurrency: "EUR"}}, "1,250.00", "", "EUR1250.00"},
+		{"monetary parenthesised negative", CustomField{DataType: DataTypeMonetary}, "(1,250.00)", "", "INR-1250.00"},
+		{"date iso", CustomField{DataType: DataTypeDate}, "2024-03-05", "", "2024-03-05"},
+		{"date day first", CustomField{DataType: DataTypeDate}, "05/03/2024", "", "2024-03-05"},
+		{"date month name", CustomField{DataType: DataTypeDate}, "5 Mar 2024", "", "2024-03-05"},
+		{"date ordinal", CustomField{DataType: DataTypeDate}, "5th Mar '24", "", "2024-03-05"},
+		{"integer", CustomField{DataType: DataTypeInteger}, "1,024", "", int64(1024)},
+		{"float", CustomField{DataType: DataTypeFloat}, "12.5", "", 12.5},
+		{"boolean", CustomField{DataType: DataTypeBoolean}, "Yes", "", true},
+		{"select legacy index", legacySelect, "rent", "", 1},
+		{"select by id", idSelect, "Food", "", "a1"},
+	}
+	for _, tc := range cases {
+		got, err := tc.field.Coerce(tc.raw, tc.currency, "INR", dates.Parser{})
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", tc.name, err)
+			continue
+		}
+		if got != tc.want {
+			t.Errorf("%s: Coerce(%q) = %#v, want %#v", tc.name, tc.raw, got, tc.want)
+		}
+	}
+}
+
+func TestCustomFieldCoerceDateOrder(t *testing.T) {
+	field := CustomField{DataType: DataTypeDate}
+	for _, tc := range []struct {
+		order dates.Order
+		want  string
+	}{
+		{dates.DayFirst, "2024-03-05"},
+		{dates.MonthFirst, "2024-05-03"},
+	} {
+		got, err := field.Coerce("05/03/2024", "", "INR", dates.Parser{Order: tc.order})
+		if err != nil || got != tc.want {
+			t.Errorf("%s: Coerce(05/03/2024) = %v, %v; want %s", tc.order, got, err, tc.want)
+		}
+	}
+}
+
+func TestCustomFieldCoerce_Errors(t *testing.T) {
+	idSelect := CustomField{DataType: DataTypeSelect, ExtraData: CustomFieldExtraData{
+		SelectOptions: []json.RawMessage{json.RawMessage(`{"id": "a1", "label": "Food"}`)},
+	}}
+	cases := []struct {
+		name  string
+		field CustomField
+		raw   string
+	}{
+		{"empty", CustomField{DataType: DataTypeString}, "  "},
+		{"bad date", CustomField{DataType: DataTypeDate}, "sometime"},
+		{"fractional integer", CustomField{DataType: DataTypeInteger}, "1.5"},
+		{"not a number", CustomField{DataType: DataTypeMonetary}, "N/A"},
+		{"unknown option", idSelect, "Travel"},
+		{"document link", CustomField{DataType: DataTypeDocumentLink}, "12"},
+	}
+	for _, tc := range cases {
+		if _, err := tc.field.Coerce(tc.raw, "", "INR", dates.Parser{}); err == nil {
+			t.Errorf("%s: expected error", tc.name)
+		}
+	}
+}
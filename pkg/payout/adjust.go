@@ -0,0 +1,130 @@
+package payout
+
+import (
+	"fmt"
+	"log/slog"
+	"math"
+	"reflect"
+	"strconv"
+
+	"paperless-document-processor/config"
+	"paperless-document-processor/pkg/accounting"
+	"paperless-document-processor/pkg/money"
+	"paperless-document-processor/pkg/storage"
+)
+
+// Adjust applies the platform's adjustments to p in order, so each sees the
+// result of the ones before it.  db evaluates expressions and may be nil
+// when the platform has none.
+func Adjust(db *storage.DB, option config.PlatformConfig, p *accounting.PayoutInput) error {
+	for i, a := range option.Adjustments {
+		if err := adjust(db, a, p); err != nil {
+			return fmt.Errorf("adjustment %d (%s): %w", i, a.Field, err)
+		}
+	}
+	return nil
+}
+
+func adjust(db *storage.DB, a config.Adjustment, p *accounting.PayoutInput) error {
+	sf, ok := config.PayoutInputField(a.Field)
+	if !ok {
+		return fmt.Errorf("%q is not a PayoutInput field", a.Field)
+	}
+	v := reflect.ValueOf(p).Elem()
+	f := v.FieldByIndex(sf.Index)
+	before := fmt.Sprint(f.Interface())
+
+	switch {
+	case a.Negate:
+		if m, ok := f.Interface().(money.Money); ok {
+			f.Set(reflect.ValueOf(m.Neg()))
+			break
+		}
+		switch f.Kind() {
+		case reflect.Int, reflect.Int64:
+			f.SetInt(-f.Int())
+		case reflect.Float32, reflect.Float64:
+			f.SetFloat(-f.Float())
+		default:
+			return fmt.Errorf("cannot negate %s", f.Kind())
+		}
+	case a.Default != "":
+		if !f.IsZero() {
+			return nil
+		}
+		if err := setField(f, a.Default); err != nil {
+			return err
+		}
+	default:
+		if db == nil {
+			return fmt.Errorf("no database to evaluate %q", a.Expression)
+		}
+		result, err := db.EvalExpression(payoutVars(v), a.Expression)
+		if err != nil {
+			return err
+		}
+		if err := setField(f, result); err != nil {
+			return err
+		}
+	}
+
+	slog.Debug("Applied payout adjustment", "field", sf.Name, "before", before, "after", fmt.Sprint(f.Interface()))
+	return nil
+}
+
+// payoutVars exposes the PayoutInput fields, by name, to expressions.
+func payoutVars(v reflect.Value) map[string]interface{} {
+	vars := make(map[string]interface{}, v.NumField())
+	for i := 0; i < v.NumField(); i++ {
+		f := v.Field(i)
+		if m, ok := f.Interface().(money.Money); ok {
+			vars[v.Type().Field(i).Name] = m
+			continue
+		}
+		switch f.Kind() {
+		case reflect.String:
+			vars[v.Type().Field(i).Name] = f.String()
+		case reflect.Int, reflect.Int64:
+			vars[v.Type().Field(i).Name] = f.Int()
+		case reflect.Float32, reflect.Float64:
+			vars[v.Type().Field(i).Name] = f.Float()
+		}
+	}
+	return vars
+}
+
+// setField parses s into a string, numeric or money field.  An empty s (a
+// NULL result) zeroes the field.
+func setField(f reflect.Value, s string) error {
+	if s == "" {
+		f.Set(reflect.Zero(f.Type()))
+		return nil
+	}
+	if f.Type() == reflect.TypeOf(money.Money{}) {
+		m, err := money.Parse(s)
+		if err != nil {
+			return err
+		}
+		f.Set(reflect.ValueOf(m))
+		return nil
+	}
+	switch f.Kind() {
+	case reflect.String:
+		f.SetString(s)
+	case reflect.Int, reflect.Int64:
+		n, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return fmt.Errorf("%q is not a number", s)
+		}
+		f.SetInt(int64(math.Round(n)))
+	case reflect.Float32, reflect.Float64:
+		n, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return fmt.Errorf("%q is not a number", s)
+		}
+		f.SetFloat(n)
+	default:
+		return fmt.Errorf("cannot set %s field", f.Kind())
+	}
+	return nil
+}
@@ -0,0 +1,33 @@
+package payout
+
+import (
+	"testing"
+
+	"paperless-document-processor/config"
+	"paperless-document-processor/pkg/accounting"
+	"paperless-document-processor/pkg/money"
+)
+
+func TestAdjust_NegateAndDefault(t *testing.T) {
+	option := config.PlatformConfig{Adjustments: []config.Adjustment{
+		{Field: "marketingadsamt", Negate: true},
+		{Field: "TotalOrders", Default: "1"},
+		{Field: "SettlementDate", Default: "2024-05-01"},
+		{Field: "UtrNumber", Default: "unused"},
+		{Field: "GrossSalesAmt", Default: "0.1"},
+	}}
+	p := accounting.PayoutInput{MarketingAdsAmt: money.MustParse("-150.50"), UtrNumber: "UTR1"}
+	if err := Adjust(nil, option, &p); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if p.MarketingAdsAmt.Minor != 15050 || p.GrossSalesAmt.Minor != 10 || p.TotalOrders != 1 || p.SettlementDate != "2024-05-01" || p.UtrNumber != "UTR1" {
+		t.Errorf("unexpected payout after adjustments: %+v", p)
+	}
+}
+
+func TestAdjust_ExpressionNeedsDB(t *testing.T) {
+	option := config.PlatformConfig{Adjustments: []config.Adjustment{{Field: "FinalPayoutAmt", Expression: "FinalPayoutAmt + MarketingAdsAmt"}}}
+	if err := Adjust(nil, option, &accounting.PayoutInput{}); err == nil {
+		t.Errorf("expected an error without a database")
+	}
+}
@@ -0,0 +1,132 @@
+package payout
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"paperless-document-processor/config"
+	"paperless-document-processor/pkg/accounting"
+	"paperless-document-processor/pkg/storage"
+)
+
+// CheckFailure describes one failed check with the numbers involved.
+type CheckFailure struct {
+	Name   string `json:"name"`
+	Detail string `json:"detail"`
+}
+
+// CheckError is returned by Check when any check fails.
+type CheckError struct {
+	Failures []CheckFailure
+}
+
+func (e *CheckError) Error() string {
+	parts := make([]string, len(e.Failures))
+	for i, f := range e.Failures {
+		parts[i] = fmt.Sprintf("%s: %s", f.Name, f.Detail)
+	}
+	return "payout checks failed: " + strings.Join(parts, "; ")
+}
+
+// Check runs the platform's checks against p and the tables imported for
+// docID.  Every check runs, so all failures are reported together in a
+// *CheckError.  Other errors mean a check could not be evaluated.
+func Check(db *storage.DB, docID int, option config.PlatformConfig, p accounting.PayoutInput) error {
+	if len(option.Checks) == 0 {
+		return nil
+	}
+	vars := payoutVars(reflect.ValueOf(p))
+
+	var failures []CheckFailure
+	for _, c := range option.Checks {
+		detail, err := runCheck(db, docID, c, vars)
+		if err != nil {
+			return fmt.Errorf("check %s: %w", c.Name, err)
+		}
+		if detail != "" {
+			failures = append(failures, CheckFailure{Name: c.Name, Detail: detail})
+		}
+	}
+	if len(failures) > 0 {
+		return &CheckError{Failures: failures}
+	}
+	return nil
+}
+
+// runCheck returns a description of the failure, or "" when c passes.
+func runCheck(db *storage.DB, docID int, c config.PayoutCheck, vars map[string]interface{}) (string, error) {
+	if c.Expression != "" {
+		result, err := db.EvalExpression(vars, c.Expression)
+		if err != nil {
+			return "", err
+		}
+		if ok, _ := strconv.ParseBool(result); ok {
+			return "", nil
+		}
+		return fmt.Sprintf("%s is not true (%s)", c.Expression, referencedValues(c.Expression, vars)), nil
+	}
+
+	left, err := checkValue(db, docID, c.Left, c.LeftTable, vars)
+	if err != nil {
+		return "", err
+	}
+	right, err := checkValue(db, docID, c.Right, c.RightTable, vars)
+	if err != nil {
+		return "", err
+	}
+	if diff := left - right; math.Abs(diff) > c.Tolerance {
+		return fmt.Sprintf("%s = %.2f but %s = %.2f (difference %.2f, tolerance %.2f)",
+			describe(c.Left, c.LeftTable), left, describe(c.Right, c.RightTable), right, diff, c.Tolerance), nil
+	}
+	return "", nil
+}
+
+// checkValue evaluates a numeric check expression over table's rows, or
+// over the PayoutInput fields when table is empty.  NULL counts as 0.
+func checkValue(db *storage.DB, docID int, expression, table string, vars map[string]interface{}) (float64, error) {
+	var (
+		result string
+		err    error
+	)
+	if table != "" {
+		result, err = db.QueryPlatformValue(docID, table, expression)
+	} else {
+		result, err = db.EvalExpression(vars, expression)
+	}
+	if err != nil {
+		return 0, err
+	}
+	if result == "" {
+		return 0, nil
+	}
+	n, err := strconv.ParseFloat(result, 64)
+	if err != nil {
+		return 0, fmt.Errorf("%s is not numeric: %q", expression, result)
+	}
+	return n, nil
+}
+
+func describe(expression, table string) string {
+	if table == "" {
+		return expression
+	}
+	return fmt.Sprintf("%s over %s", expression, table)
+}
+
+// referencedValues lists the PayoutInput fields named in expression with
+// their values, so a failure note shows the offending numbers.
+func referencedValues(expression string, vars map[string]interface{}) string {
+	lower := strings.ToLower(expression)
+	var parts []string
+	for name, v := range vars {
+		if strings.Contains(lower, strings.ToLower(name)) {
+			parts = append(parts, fmt.Sprintf("%s=%v", name, v))
+		}
+	}
+	sort.Strings(parts)
+	return strings.Join(parts, ", ")
+}
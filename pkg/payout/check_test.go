@@ -0,0 +1,131 @@
+package payout
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"paperless-document-processor/config"
+	"paperless-document-processor/pkg/accounting"
+	"paperless-document-processor/pkg/money"
+	"paperless-document-processor/pkg/storage"
+)
+
+func TestReferencedValues(t *testing.T) {
+	vars := map[string]interface{}{"TotalOrders": int64(0), "PeriodStart": "2024-05-07", "PeriodEnd": "2024-05-01", "UtrNumber": ""}
+	got := referencedValues("periodstart <= PeriodEnd AND TotalOrders > 0", vars)
+	want := "PeriodEnd=2024-05-01, PeriodStart=2024-05-07, TotalOrders=0"
+	if got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestCheckErrorListsEveryFailure(t *testing.T) {
+	err := &CheckError{Failures: []CheckFailure{
+		{Name: "has_orders", Detail: "TotalOrders > 0 is not true (TotalOrders=0)"},
+		{Name: "net", Detail: "x = 1.00 but y = 2.00 (difference -1.00, tolerance 0.50)"},
+	}}
+	want := "payout checks failed: has_orders: TotalOrders > 0 is not true (TotalOrders=0); net: x = 1.00 but y = 2.00 (difference -1.00, tolerance 0.50)"
+	if err.Error() != want {
+		t.Errorf("got %q", err.Error())
+	}
+}
+
+// checkDB opens an in-memory database holding two summary rows for document
+// 7 (net payouts 600.25 and 400.00) and one for another document.
+func checkDB(t *testing.T) *storage.DB {
+	t.Helper()
+	db, err := storage.InitDB("")
+	if err != nil {
+		t.Skipf("DuckDB unavailable: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	for _, stmt := range []string{
+		`CREATE TABLE demo_summary (document_id INTEGER, net_payout DECIMAL(18,2));`,
+		`INSERT INTO demo_summary VALUES (7, 600.25), (7, 400.00), (8, 5000);`,
+	} {
+		if _, err := db.Conn.Exec(stmt); err != nil {
+			t.Fatalf("%s: %v", stmt, err)
+		}
+	}
+	return db
+}
+
+func TestCheck(t *testing.T) {
+	db := checkDB(t)
+	p := accounting.PayoutInput{TotalOrders: 12, FinalPayoutAmt: money.MustParse("1000.00")}
+	checks := map[string]struct {
+		check  config.PayoutCheck
+		detail string // "" when the check passes
+	}{
+		"expression true": {
+			config.PayoutCheck{Name: "has_orders", Expression: "TotalOrders > 0"},
+			"",
+		},
+		"expression false": {
+			config.PayoutCheck{Name: "many_orders", Expression: "TotalOrders > 20"},
+			"TotalOrders > 20 is not true (TotalOrders=12)",
+		},
+		"within tolerance": {
+			config.PayoutCheck{Name: "net", Left: "FinalPayoutAmt", Right: "SUM(net_payout)", RightTable: "demo_summary", Tolerance: 0.5},
+			"",
+		},
+		"outside tolerance": {
+			config.PayoutCheck{Name: "net", Left: "FinalPayoutAmt", Right: "SUM(net_payout)", RightTable: "demo_summary", Tolerance: 0.1},
+			"FinalPayoutAmt = 1000.00 but SUM(net_payout) over demo_summary = 1000.25 (difference -0.25, tolerance 0.10)",
+		},
+		"no rows counts as zero": {
+			config.PayoutCheck{Name: "empty", Left: "SUM(net_payout) FILTER (WHERE net_payout < 0)", LeftTable: "demo_summary", Right: "0"},
+			"",
+		},
+	}
+	for name, tc := range checks {
+		err := Check(db, 7, config.PlatformConfig{Checks: []config.PayoutCheck{tc.check}}, p)
+		if tc.detail == "" {
+			if err != nil {
+				t.Errorf("%s: expected the check to pass, got %v", name, err)
+			}
+			continue
+		}
+		var checkErr *CheckError
+		if !errors.As(err, &checkErr) || len(checkErr.Failures) != 1 {
+			t.Errorf("%s: expected one failure, got %v", name, err)
+			continue
+		}
+		if f := checkErr.Failures[0]; f.Name != tc.check.Name || f.Detail != tc.detail {
+			t.Errorf("%s: got failure %+v, want detail %q", name, f, tc.detail)
+		}
+	}
+}
+
+func TestCheckReportsEveryFailure(t *testing.T) {
+	db := checkDB(t)
+	option := config.PlatformConfig{Checks: []config.PayoutCheck{
+		{Name: "has_orders", Expression: "TotalOrders > 0"},
+		{Name: "utr", Expression: "UtrNumber <> ''"},
+		{Name: "net", Left: "FinalPayoutAmt", Right: "SUM(net_payout)", RightTable: "demo_summary"},
+	}}
+	err := Check(db, 7, option, accounting.PayoutInput{TotalOrders: 3})
+	var checkErr *CheckError
+	if !errors.As(err, &checkErr) {
+		t.Fatalf("expected a CheckError, got %v", err)
+	}
+	want := []CheckFailure{
+		{Name: "utr", Detail: "UtrNumber <> '' is not true (UtrNumber=)"},
+		{Name: "net", Detail: "FinalPayoutAmt = 0.00 but SUM(net_payout) over demo_summary = 1000.25 (difference -1000.25, tolerance 0.00)"},
+	}
+	if !reflect.DeepEqual(checkErr.Failures, want) {
+		t.Errorf("got failures %+v, want %+v", checkErr.Failures, want)
+	}
+}
+
+func TestCheckNonNumericValue(t *testing.T) {
+	db := checkDB(t)
+	option := config.PlatformConfig{Checks: []config.PayoutCheck{{Name: "bad", Left: "'n/a'", Right: "0"}}}
+	err := Check(db, 7, option, accounting.PayoutInput{})
+	var checkErr *CheckError
+	if err == nil || errors.As(err, &checkErr) || !strings.Contains(err.Error(), "check bad: 'n/a' is not numeric") {
+		t.Errorf("expected an evaluation error, got %v", err)
+	}
+}
@@ -0,0 +1,100 @@
+package payout
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"paperless-document-processor/config"
+	"paperless-document-processor/pkg/excel"
+)
+
+// Detect returns the platforms whose fingerprint matches a report, sorted by
+// name.  filename is the report's original file name, used by filename
+// rules; path is where the file can be read.  Platforms without a
+// fingerprint never match.
+func Detect(platforms map[string]config.PlatformConfig, filename, path string) ([]string, error) {
+	var wb *excel.Workbook
+	if strings.EqualFold(filepath.Ext(path), ".xlsx") && needsWorkbook(platforms) {
+		var err error
+		if wb, err = excel.OpenWorkbook(path); err != nil {
+			return nil, err
+		}
+		defer wb.Close()
+	}
+
+	var matched []string
+	for name, option := range platforms {
+		if option.Fingerprint == nil {
+			continue
+		}
+		ok, err := matchFingerprint(*option.Fingerprint, filename, wb)
+		if err != nil {
+			return nil, fmt.Errorf("%s fingerprint: %w", name, err)
+		}
+		if ok {
+			matched = append(matched, name)
+		}
+	}
+	sort.Strings(matched)
+	return matched, nil
+}
+
+func needsWorkbook(platforms map[string]config.PlatformConfig) bool {
+	for _, option := range platforms {
+		if fp := option.Fingerprint; fp != nil && (len(fp.Sheets) > 0 || len(fp.Cells) > 0) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchFingerprint checks every rule of fp.  wb is nil when the report is
+// not an .xlsx workbook, so sheet and cell rules fail.
+func matchFingerprint(fp config.Fingerprint, filename string, wb *excel.Workbook) (bool, error) {
+	if fp.Filename != "" {
+		re, err := regexp.Compile(fp.Filename)
+		if err != nil {
+			return false, err
+		}
+		if !re.MatchString(filename) {
+			return false, nil
+		}
+	}
+	if len(fp.Sheets) == 0 && len(fp.Cells) == 0 {
+		return true, nil
+	}
+	if wb == nil {
+		return false, nil
+	}
+
+	sheets := make(map[string]bool)
+	for _, name := range wb.SheetNames() {
+		sheets[strings.ToLower(name)] = true
+	}
+	for _, want := range fp.Sheets {
+		if !sheets[strings.ToLower(want)] {
+			return false, nil
+		}
+	}
+
+	for _, c := range fp.Cells {
+		if c.Sheet != "" && !sheets[strings.ToLower(c.Sheet)] {
+			return false, nil
+		}
+		values, err := wb.Cells(c.Sheet, []string{c.Cell})
+		if err != nil {
+			return false, err
+		}
+		if normalizeCell(values[c.Cell]) != normalizeCell(c.Value) {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+func normalizeCell(v string) string {
+	return strings.Join(strings.Fields(strings.ToLower(v)), " ")
+}
@@ -0,0 +1,79 @@
+package payout
+
+import (
+	"archive/zip"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"paperless-document-processor/config"
+)
+
+// writeWorkbook writes an .xlsx with one sheet per name; cell A1 of each
+// sheet holds the sheet's title.
+func writeWorkbook(t *testing.T, sheets map[string]string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "report.xlsx")
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	zw := zip.NewWriter(f)
+	write := func(name, content string) {
+		w, err := zw.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		w.Write([]byte(content))
+	}
+
+	book, rels, i := "", "", 0
+	for name, title := range sheets {
+		i++
+		id := string(rune('0' + i))
+		book += `<sheet name="` + name + `" sheetId="` + id + `" r:id="rId` + id + `"/>`
+		rels += `<Relationship Id="rId` + id + `" Target="worksheets/sheet` + id + `.xml"/>`
+		write("xl/worksheets/sheet"+id+".xml", `<worksheet><sheetData><row r="1"><c r="A1" t="inlineStr"><is><t>`+title+`</t></is></c></row></sheetData></worksheet>`)
+	}
+	write("xl/workbook.xml", `<workbook xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships"><sheets>`+book+`</sheets></workbook>`)
+	write("xl/_rels/workbook.xml.rels", `<Relationships>`+rels+`</Relationships>`)
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestDetect(t *testing.T) {
+	platforms := map[string]config.PlatformConfig{
+		"swiggy": {Fingerprint: &config.Fingerprint{
+			Sheets: []string{"Order Level", "Summary"},
+		}},
+		"zomato": {Fingerprint: &config.Fingerprint{
+			Cells: []config.FingerprintCell{{Sheet: "Summary", Cell: "A1", Value: "zomato  payout summary"}},
+		}},
+		"swiggy-dineout": {Fingerprint: &config.Fingerprint{
+			Filename: `(?i)dineout.*\.csv$`,
+		}},
+		"manual": {},
+	}
+
+	path := writeWorkbook(t, map[string]string{"Summary": "Weekly Summary", "order level": "Orders"})
+	if got, err := Detect(platforms, "invoice.xlsx", path); err != nil || !reflect.DeepEqual(got, []string{"swiggy"}) {
+		t.Errorf("swiggy workbook: got (%v, %v)", got, err)
+	}
+
+	path = writeWorkbook(t, map[string]string{"Summary": "Zomato Payout Summary"})
+	if got, err := Detect(platforms, "invoice.xlsx", path); err != nil || !reflect.DeepEqual(got, []string{"zomato"}) {
+		t.Errorf("zomato workbook: got (%v, %v)", got, err)
+	}
+
+	// Sheet and cell rules never match other formats.
+	if got, err := Detect(platforms, "Dineout_March.CSV", "/nonexistent/0001.csv"); err != nil || !reflect.DeepEqual(got, []string{"swiggy-dineout"}) {
+		t.Errorf("dineout csv: got (%v, %v)", got, err)
+	}
+	if got, err := Detect(platforms, "bank.pdf", "/nonexistent/0002.pdf"); err != nil || len(got) != 0 {
+		t.Errorf("unrelated pdf: got (%v, %v)", got, err)
+	}
+}
@@ -0,0 +1,39 @@
+package payout
+
+import (
+	"fmt"
+	"strings"
+
+	"paperless-document-processor/config"
+	"paperless-document-processor/pkg/storage"
+)
+
+// Outlet evaluates the platform's outlet expression over the rows imported
+// for docID and maps the restaurant ID to an outlet name.  ok is false when
+// the platform has no outlet expression or it yields no value.
+func Outlet(db *storage.DB, docID int, option config.PlatformConfig) (outlet string, ok bool, err error) {
+	o := option.Outlet
+	if o == nil {
+		return "", false, nil
+	}
+	id, err := db.QueryPlatformValue(docID, o.TableName, o.Expression)
+	if err != nil {
+		return "", false, fmt.Errorf("failed to read restaurant id: %w", err)
+	}
+	return OutletForRestaurant(o, id)
+}
+
+// OutletForRestaurant maps a restaurant ID read from a spreadsheet to its
+// outlet.  An unmapped ID is an error so the payout is not booked to the
+// wrong outlet.
+func OutletForRestaurant(o *config.OutletExport, id string) (string, bool, error) {
+	id = strings.TrimSpace(id)
+	if id == "" {
+		return "", false, nil
+	}
+	outlet, found := o.RestaurantIDs[id]
+	if !found {
+		return "", false, fmt.Errorf("restaurant id %q has no entry in the platform's outlet.restaurant_ids", id)
+	}
+	return outlet, true, nil
+}
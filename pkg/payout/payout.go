@@ -0,0 +1,234 @@
+// Package payout imports platform payout reports (spreadsheets, CSV files and
+// PDFs) into DuckDB tables and evaluates the platform's export configs into
+// an accounting.PayoutInput.  It is shared by the server's payout pipeline and
+// the payoutcheck command, so a config tested locally behaves the same in
+// production.
+package payout
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"path/filepath"
+	"strings"
+
+	"paperless-document-processor/config"
+	"paperless-document-processor/pkg/accounting"
+	"paperless-document-processor/pkg/dates"
+	"paperless-document-processor/pkg/docai"
+	"paperless-document-processor/pkg/excel"
+	"paperless-document-processor/pkg/libreoffice"
+	"paperless-document-processor/pkg/storage"
+	"paperless-document-processor/pkg/tika"
+)
+
+// Report formats, by file extension.
+const (
+	FormatSpreadsheet = "spreadsheet" // .xlsx, .xls
+	FormatCSV         = "csv"         // .csv, .tsv
+	FormatPDF         = "pdf"         // .pdf
+)
+
+// FormatOf returns the report format of a file name.
+func FormatOf(filename string) (string, bool) {
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".xlsx", ".xls":
+		return FormatSpreadsheet, true
+	case ".csv", ".tsv":
+		return FormatCSV, true
+	case ".pdf":
+		return FormatPDF, true
+	}
+	return "", false
+}
+
+// Source locates a report for every import backend.
+type Source struct {
+	// Path is the file as DuckDB's read_xlsx and read_csv open it, and as
+	// it is read for PDF table extraction.
+	Path string
+	// MediaPath is the file relative to the LibreOffice parser's data path.
+	MediaPath string
+}
+
+// Backends are the services an import may call.  Only the ones needed by
+// the platform's methods and the report's format must be set.
+type Backends struct {
+	LibreOffice *libreoffice.Client
+	Tika        *tika.Client
+	DocAI       *docai.Client
+	// DocAIProcessorID is the processor used for PDF tables; empty uses
+	// the client's default processor.
+	DocAIProcessorID string
+}
+
+// Import loads the report into the platform's import tables, tagging every
+// row with docID.  The format is taken from the file extension of src.Path.
+// ctx bounds the calls to the parsing services.
+func Import(ctx context.Context, db *storage.DB, b Backends, docID int, platform string, option config.PlatformConfig, src Source) error {
+	format, ok := FormatOf(src.Path)
+	if !ok {
+		return fmt.Errorf("unsupported payout report %q: expected .xlsx, .xls, .csv or .pdf", filepath.Base(src.Path))
+	}
+	switch {
+	case format == FormatCSV:
+		if err := db.ProcessPlatformCSV(docID, src.Path, platform, option); err != nil {
+			slog.Error("DuckDB ProcessPlatformCSV failed", "document_id", docID, "error", err)
+			return err
+		}
+		return nil
+	case format == FormatPDF:
+		return importPDF(ctx, db, b, docID, platform, option, src)
+	case !option.UseLibreOffice():
+		slog.Info("Excel file detected in payout, storing via DuckDB", "path", src.Path, "platform", platform, "options", option)
+		if err := db.ProcessPlatformExcel(docID, src.Path, platform, option); err != nil {
+			slog.Error("DuckDB ProcessPlatformExcel failed", "document_id", docID, "error", err)
+			return err
+		}
+		return nil
+	}
+
+	lo := b.LibreOffice
+	if lo == nil {
+		slog.Error("LibreOffice import method requested but LIBREOFFICE_URL is not configured", "document_id", docID)
+		return fmt.Errorf("libreoffice import method requested but LIBREOFFICE_URL is not configured")
+	}
+	slog.Info("Excel file detected in payout, storing via LibreOffice parser", "path", src.MediaPath, "platform", platform)
+
+	// resultRowCounts tracks the number of data rows loaded for each
+	// import config so that subsequent configs with RelativeRange can
+	// compute their start row correctly.
+	resultRowCounts := make([]int, len(option.ImportConfigs))
+
+	for i, importConfig := range option.ImportConfigs {
+		// Resolve relative range if configured.
+		if importConfig.RelativeRange.RelativeConfigIndex > 0 {
+			refIdx := importConfig.RelativeRange.RelativeConfigIndex
+			if refIdx >= i {
+				slog.Error("LibreOffice relative range: RelativeConfigIndex must reference an earlier config", "document_id", docID, "config_index", i, "relative_config_index", refIdx)
+				return fmt.Errorf("import config %d: relative_config_index %d must reference an earlier config", i, refIdx)
+			}
+			relativeOption := option.ImportConfigs[refIdx]
+			refRowCount := resultRowCounts[refIdx]
+			// Mirror the DuckDB GetRangeEnd row-count adjustment:
+			// decrement only when the referenced config explicitly sets
+			// header=false.
+			if relativeOption.Header != nil && !*relativeOption.Header {
+				refRowCount--
+			}
+			// If the referenced config had a footer row, that row is
+			// physically present in the spreadsheet even though it was
+			// stripped from the loaded data.  Add it back so the computed
+			// start row accounts for the full physical extent of the table.
+			if relativeOption.Footer != nil && *relativeOption.Footer {
+				refRowCount++
+			}
+			if refRowCount < 0 {
+				refRowCount = 0
+			}
+			refRange, err := excel.NewRange(relativeOption.Range)
+			if err != nil {
+				slog.Error("LibreOffice relative range: failed to parse referenced range", "document_id", docID, "range", relativeOption.Range, "error", err)
+				return fmt.Errorf("failed to parse referenced range %q: %w", relativeOption.Range, err)
+			}
+			// Build the new range using the referenced config's columns
+			// but with a computed start row.
+			refRange.Start.Row = refRange.Start.Row + refRowCount + importConfig.RelativeRange.RowsOffset
+			importConfig.Range = refRange.String()
+			slog.Debug("LibreOffice relative range resolved", "config_index", i, "computed_range", importConfig.Range)
+		}
+
+		hasHeader := importConfig.Header == nil || *importConfig.Header
+		stopAtEmpty := importConfig.StopAtEmpty != nil && *importConfig.StopAtEmpty
+		result, err := lo.Parse(ctx, src.MediaPath, importConfig.Sheet, importConfig.Range, hasHeader, stopAtEmpty)
+		if err != nil {
+			slog.Error("LibreOffice parse failed", "document_id", docID, "sheet", importConfig.Sheet, "error", err)
+			return fmt.Errorf("libreoffice parse of sheet %q failed: %w", importConfig.Sheet, err)
+		}
+
+		// Drop the last row when the import config declares a footer
+		// row (i.e. a totals/summary row appended by Excel).
+		if importConfig.Footer != nil && *importConfig.Footer && len(result.Rows) > 0 {
+			slog.Debug("LibreOffice footer row dropped", "table", importConfig.GetTableName(platform), "rows_before", len(result.Rows))
+			result.Rows = result.Rows[:len(result.Rows)-1]
+		}
+
+		resultRowCounts[i] = len(result.Rows)
+
+		tableName := importConfig.GetTableName(platform)
+		if err := db.LoadRowsIntoTable(docID, tableName, result); err != nil {
+			slog.Error("Failed to load LibreOffice rows into table", "document_id", docID, "table", tableName, "error", err)
+			return err
+		}
+	}
+	return nil
+}
+
+// Export evaluates the platform's export configs over the rows imported for
+// docID, applies its adjustments and normalizes the period and settlement
+// dates with dp.
+func Export(db *storage.DB, docID int, platform string, option config.PlatformConfig, dp dates.Parser) (accounting.PayoutInput, error) {
+	payoutInput, err := db.GetPlatformExcelRows(docID, platform, option)
+	if err != nil {
+		slog.Error("Failed to get excel rows", "document_id", docID, "error", err)
+		return accounting.PayoutInput{}, err
+	}
+	payoutInput.Platform = accounting.Platform(platform)
+	if err := Adjust(db, option, &payoutInput); err != nil {
+		slog.Error("Failed to adjust payout", "document_id", docID, "platform", platform, "error", err)
+		return accounting.PayoutInput{}, err
+	}
+	if err := normalizeDates(dp, &payoutInput); err != nil {
+		slog.Error("Unreadable payout date", "document_id", docID, "platform", platform, "error", err)
+		return accounting.PayoutInput{}, err
+	}
+	return payoutInput, nil
+}
+
+// normalizeDates rewrites the period and settlement dates of p as
+// YYYY-MM-DD.  The period is required and an unreadable date is an error,
+// since a payout booked to the wrong period, or to none, is worse than no
+// payout.  An empty settlement date is left empty, and an ambiguous date is
+// read in dp's order with a warning.
+func normalizeDates(dp dates.Parser, p *accounting.PayoutInput) error {
+	for _, d := range []struct {
+		name     string
+		v        *string
+		required bool
+	}{
+		{"period_start", &p.PeriodStart, true},
+		{"period_end", &p.PeriodEnd, true},
+		{"settlement_date", &p.SettlementDate, false},
+	} {
+		if strings.TrimSpace(*d.v) == "" {
+			if d.required {
+				return fmt.Errorf("%s is empty after export", d.name)
+			}
+			continue
+		}
+		iso, err := dp.Normalize(*d.v)
+		if err != nil {
+			return fmt.Errorf("%s: %w", d.name, err)
+		}
+		if amb := dp.Ambiguity(*d.v); amb != nil {
+			slog.Warn("Ambiguous payout date read in DATE_ORDER", "field", d.name, "date", *d.v, "read_as", iso)
+		}
+		*d.v = iso
+	}
+	return nil
+}
+
+// Tables lists the tables the platform's import configs write to, in config
+// order and without duplicates.
+func Tables(platform string, option config.PlatformConfig) []string {
+	seen := make(map[string]bool)
+	var tables []string
+	for _, importConfig := range option.ImportConfigs {
+		table := importConfig.GetTableName(platform)
+		if !seen[table] {
+			seen[table] = true
+			tables = append(tables, table)
+		}
+	}
+	return tables
+}
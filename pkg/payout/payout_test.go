@@ -0,0 +1,110 @@
+package payout
+
+import (
+	"strings"
+	"testing"
+
+	"paperless-document-processor/config"
+	"paperless-document-processor/pkg/accounting"
+	"paperless-document-processor/pkg/dates"
+	"paperless-document-processor/pkg/storage"
+)
+
+func TestTables(t *testing.T) {
+	option := config.PlatformConfig{ImportConfigs: []config.ImportConfig{
+		{TableName: "swiggy_order_level", Sheet: "Order Level", Range: "A3:AR"},
+		{TableName: "swiggy_other_charges", Sheet: "Other charges", Range: "A5:H"},
+		{TableName: "swiggy_other_charges", Sheet: "Other charges", RelativeRange: config.RelativeRange{RelativeConfigIndex: 1}},
+		{Sheet: "Summary", Range: "B11:C16"},
+	}}
+	got := strings.Join(Tables("Swiggy", option), ",")
+	want := "swiggy_order_level,swiggy_other_charges,payout_swiggy_Summary_B11_C16"
+	if got != want {
+		t.Errorf("got %s, want %s", got, want)
+	}
+}
+
+func TestOutletForRestaurant(t *testing.T) {
+	o := &config.OutletExport{RestaurantIDs: map[string]string{"22244451": "Noodle House Indiranagar"}}
+
+	if outlet, ok, err := OutletForRestaurant(o, " 22244451 "); err != nil || !ok || outlet != "Noodle House Indiranagar" {
+		t.Errorf("got (%q, %v, %v)", outlet, ok, err)
+	}
+	if _, ok, err := OutletForRestaurant(o, ""); err != nil || ok {
+		t.Errorf("empty id should resolve nothing, got (%v, %v)", ok, err)
+	}
+	if _, _, err := OutletForRestaurant(o, "999"); err == nil {
+		t.Errorf("expected error for unmapped restaurant id")
+	}
+}
+
+func TestNormalizeDates(t *testing.T) {
+	p := accounting.PayoutInput{PeriodStart: "01-Dec-2025", PeriodEnd: "7th Dec '25", SettlementDate: "17/12/2025"}
+	if err := normalizeDates(dates.Parser{}, &p); err != nil {
+		t.Fatal(err)
+	}
+	if p.PeriodStart != "2025-12-01" || p.PeriodEnd != "2025-12-07" || p.SettlementDate != "2025-12-17" {
+		t.Errorf("got %s, %s, %s", p.PeriodStart, p.PeriodEnd, p.SettlementDate)
+	}
+
+	p = accounting.PayoutInput{PeriodStart: "01/12/2025", PeriodEnd: "2025-12-31"}
+	if err := normalizeDates(dates.Parser{Order: dates.DayFirst}, &p); err != nil || p.PeriodStart != "2025-12-01" {
+		t.Errorf("day first: got (%s, %v)", p.PeriodStart, err)
+	}
+	p = accounting.PayoutInput{PeriodStart: "01/12/2025", PeriodEnd: "2025-12-31"}
+	if err := normalizeDates(dates.Parser{Order: dates.MonthFirst}, &p); err != nil || p.PeriodStart != "2025-01-12" {
+		t.Errorf("month first: got (%s, %v)", p.PeriodStart, err)
+	}
+
+	p = accounting.PayoutInput{PeriodStart: "2025-12-01", PeriodEnd: "2025-12-07"}
+	if err := normalizeDates(dates.Parser{}, &p); err != nil || p.SettlementDate != "" {
+		t.Errorf("without a settlement date: got (%q, %v)", p.SettlementDate, err)
+	}
+	for _, p := range []accounting.PayoutInput{
+		{PeriodStart: "2025-12-01", PeriodEnd: "2025-12-07", SettlementDate: "sometime"},
+		{PeriodEnd: "2025-12-07", SettlementDate: "2025-12-10"},
+		{PeriodStart: "2025-12-01", SettlementDate: "2025-12-10"},
+	} {
+		if err := normalizeDates(dates.Parser{}, &p); err == nil {
+			t.Errorf("%+v: expected an error", p)
+		}
+	}
+}
+
+func TestExportDateColumns(t *testing.T) {
+	db, err := storage.InitDB("")
+	if err != nil {
+		t.Skipf("DuckDB unavailable: %v", err)
+	}
+	defer db.Close()
+	for _, stmt := range []string{
+		`CREATE TABLE demo_orders (document_id INTEGER, order_date DATE, settled_at TIMESTAMP, amount DECIMAL(18,2));`,
+		`INSERT INTO demo_orders VALUES (7, DATE '2025-03-01', TIMESTAMP '2025-03-10 09:30:00', 100.50), (7, DATE '2025-03-07', NULL, 200.00);`,
+	} {
+		if _, err := db.Conn.Exec(stmt); err != nil {
+			t.Fatalf("%s: %v", stmt, err)
+		}
+	}
+	option := config.PlatformConfig{ExportConfigs: []config.ExportConfig{{
+		TableName: "demo_orders",
+		ReaderConfigs: []config.DataReaderConfig{
+			{ColumnName: "PeriodStart", Expression: "MIN(order_date)"},
+			{ColumnName: "PeriodEnd", Expression: "MAX(order_date)"},
+			{ColumnName: "SettlementDate", Expression: "MAX(settled_at)"},
+			{ColumnName: "GrossSalesAmt", Expression: "SUM(amount)"},
+		},
+	}}}
+
+	p, err := Export(db, 7, "demo", option, dates.Parser{})
+	if err != nil {
+		t.Fatalf("Export: %v", err)
+	}
+	if p.PeriodStart != "2025-03-01" || p.PeriodEnd != "2025-03-07" || p.SettlementDate != "2025-03-10" {
+		t.Errorf("dates = %q, %q, %q", p.PeriodStart, p.PeriodEnd, p.SettlementDate)
+	}
+
+	// A document with no rows has no period and must not be posted.
+	if _, err := Export(db, 8, "demo", option, dates.Parser{}); err == nil {
+		t.Errorf("Export of a document without rows succeeded")
+	}
+}
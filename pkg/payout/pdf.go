@@ -0,0 +1,106 @@
+package payout
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+
+	"paperless-document-processor/config"
+	"paperless-document-processor/pkg/libreoffice"
+	"paperless-document-processor/pkg/storage"
+)
+
+// importPDF extracts the tables of a PDF settlement report with Tika or
+// Document AI and loads the table each import config selects, the same way
+// LibreOffice parser rows are loaded.
+func importPDF(ctx context.Context, db *storage.DB, b Backends, docID int, platform string, option config.PlatformConfig, src Source) error {
+	content, err := os.ReadFile(src.Path)
+	if err != nil {
+		return fmt.Errorf("failed to read payout PDF: %w", err)
+	}
+
+	var tables [][][]string
+	if option.UseDocAITables() {
+		if b.DocAI == nil {
+			return fmt.Errorf("docai pdf method requested but Document AI is not configured")
+		}
+		slog.Info("PDF file detected in payout, extracting tables via DocAI", "path", src.Path, "platform", platform)
+		tables, err = b.DocAI.ProcessTables(ctx, b.DocAIProcessorID, content, "application/pdf")
+	} else {
+		if b.Tika == nil {
+			return fmt.Errorf("tika pdf method requested but TIKA_URL is not configured")
+		}
+		slog.Info("PDF file detected in payout, extracting tables via Tika", "path", src.Path, "platform", platform)
+		tables, err = b.Tika.ParseTables(content)
+	}
+	if err != nil {
+		slog.Error("PDF table extraction failed", "document_id", docID, "error", err)
+		return fmt.Errorf("failed to extract tables from payout PDF: %w", err)
+	}
+	slog.Debug("Extracted PDF tables", "document_id", docID, "tables", len(tables))
+
+	for i, importConfig := range option.ImportConfigs {
+		if importConfig.TableIndex >= len(tables) {
+			return fmt.Errorf("import config %d: table_index %d but the PDF has %d tables", i, importConfig.TableIndex, len(tables))
+		}
+		result := tableResult(tables[importConfig.TableIndex], importConfig)
+		tableName := importConfig.GetTableName(platform)
+		if err := db.LoadRowsIntoTable(docID, tableName, result); err != nil {
+			slog.Error("Failed to load PDF table rows into table", "document_id", docID, "table", tableName, "error", err)
+			return err
+		}
+	}
+	return nil
+}
+
+// tableResult turns a cell grid into named rows.  The first row holds the
+// column names unless the import config sets header=false, in which case the
+// columns are named column0, column1, ... like read_csv does.  Empty and
+// repeated names are made unique the same way.  Empty cells become NULL.
+func tableResult(grid [][]string, ic config.ImportConfig) *libreoffice.ParseResult {
+	width := 0
+	for _, row := range grid {
+		width = max(width, len(row))
+	}
+
+	var names []string
+	if ic.Header == nil || *ic.Header {
+		if len(grid) > 0 {
+			names, grid = grid[0], grid[1:]
+		}
+	}
+	if ic.Footer != nil && *ic.Footer && len(grid) > 0 {
+		grid = grid[:len(grid)-1]
+	}
+
+	headers := make([]string, width)
+	seen := make(map[string]bool, width)
+	for i := range headers {
+		name := ""
+		if i < len(names) {
+			name = strings.Join(strings.Fields(names[i]), " ")
+		}
+		if name == "" {
+			name = fmt.Sprintf("column%d", i)
+		}
+		for base, n := name, 1; seen[name]; n++ {
+			name = fmt.Sprintf("%s_%d", base, n)
+		}
+		seen[name] = true
+		headers[i] = name
+	}
+
+	result := &libreoffice.ParseResult{Headers: headers}
+	for _, cells := range grid {
+		row := make(map[string]interface{}, width)
+		for i, h := range headers {
+			if i < len(cells) && cells[i] != "" {
+				row[h] = cells[i]
+			}
+		}
+		result.Rows = append(result.Rows, row)
+	}
+	return result
+}
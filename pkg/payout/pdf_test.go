@@ -0,0 +1,55 @@
+package payout
+
+import (
+	"reflect"
+	"testing"
+
+	"paperless-document-processor/config"
+)
+
+func TestFormatOf(t *testing.T) {
+	for name, want := range map[string]string{
+		"documents/originals/0001.XLSX": FormatSpreadsheet,
+		"settlement.xls":                FormatSpreadsheet,
+		"orders.csv":                    FormatCSV,
+		"orders.tsv":                    FormatCSV,
+		"Payout Statement.pdf":          FormatPDF,
+		"notes.txt":                     "",
+	} {
+		if got, _ := FormatOf(name); got != want {
+			t.Errorf("FormatOf(%q) = %q, want %q", name, got, want)
+		}
+	}
+}
+
+func TestTableResult(t *testing.T) {
+	grid := [][]string{
+		{"Order ID", "Amount", "", "Amount"},
+		{"101", "250.00", "x"},
+		{"102", "", "y", "5"},
+		{"Total", "250.00"},
+	}
+	footer := true
+	got := tableResult(grid, config.ImportConfig{Footer: &footer})
+
+	wantHeaders := []string{"Order ID", "Amount", "column2", "Amount_1"}
+	if !reflect.DeepEqual(got.Headers, wantHeaders) {
+		t.Errorf("headers = %v, want %v", got.Headers, wantHeaders)
+	}
+	wantRows := []map[string]interface{}{
+		{"Order ID": "101", "Amount": "250.00", "column2": "x"},
+		{"Order ID": "102", "column2": "y", "Amount_1": "5"},
+	}
+	if !reflect.DeepEqual(got.Rows, wantRows) {
+		t.Errorf("rows = %v, want %v", got.Rows, wantRows)
+	}
+
+	noHeader := false
+	got = tableResult(grid[:2], config.ImportConfig{Header: &noHeader})
+	if want := []string{"column0", "column1", "column2", "column3"}; !reflect.DeepEqual(got.Headers, want) {
+		t.Errorf("headerless headers = %v, want %v", got.Headers, want)
+	}
+	if len(got.Rows) != 2 {
+		t.Errorf("headerless rows = %d, want 2", len(got.Rows))
+	}
+}
@@ -0,0 +1,156 @@
+// Package retry runs operations against external services with exponential
+// backoff, retrying only failures that are likely to be transient.
+package retry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"strconv"
+	"syscall"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Policy controls how often and how quickly an operation is retried.  The zero
+// value performs a single attempt with no retries.
+type Policy struct {
+	MaxAttempts    int           // total attempts including the first; <= 1 disables retries
+	InitialBackoff time.Duration // wait before the second attempt
+	MaxBackoff     time.Duration // upper bound for any single wait; 0 means unbounded
+	Multiplier     float64       // backoff growth factor per attempt; < 1 is treated as 2
+	Jitter         float64       // fraction (0..1) of each wait that is randomised
+}
+
+// DefaultPolicy is used for clients that have no explicit configuration.
+var DefaultPolicy = Policy{
+	MaxAttempts:    3,
+	InitialBackoff: time.Second,
+	MaxBackoff:     30 * time.Second,
+	Multiplier:     2,
+	Jitter:         0.2,
+}
+
+// StatusError reports a non-success HTTP response.  Clients return it so that
+// callers can distinguish transient (429, 5xx) from permanent (4xx) failures.
+type StatusError struct {
+	StatusCode int
+	// RetryAfter is the server-requested delay from a Retry-After header, if
+	// any.  It takes precedence over a shorter computed backoff.
+	RetryAfter time.Duration
+	Err        error
+}
+
+func (e *StatusError) Error() string { return e.Err.Error() }
+func (e *StatusError) Unwrap() error { return e.Err }
+
+// NewStatusError builds a StatusError from resp, reading the Retry-After
+// header when present.  err is the message callers will see.
+func NewStatusError(resp *http.Response, err error) *StatusError {
+	se := &StatusError{StatusCode: resp.StatusCode, Err: err}
+	if secs, convErr := strconv.Atoi(resp.Header.Get("Retry-After")); convErr == nil && secs > 0 {
+		se.RetryAfter = time.Duration(secs) * time.Second
+	}
+	return se
+}
+
+// IsRetryable reports whether err looks transient: timeouts, refused or reset
+// connections, HTTP 429/5xx responses and the gRPC Unavailable,
+// ResourceExhausted and DeadlineExceeded codes.  Everything else, including
+// 4xx validation errors, is treated as permanent.
+func IsRetryable(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) {
+		return false
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+
+	var statusErr *StatusError
+	if errors.As(err, &statusErr) {
+		return statusErr.StatusCode == http.StatusTooManyRequests || statusErr.StatusCode >= 500
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return true
+	}
+	if errors.Is(err, syscall.ECONNREFUSED) || errors.Is(err, syscall.ECONNRESET) {
+		return true
+	}
+
+	if st, ok := status.FromError(err); ok {
+		switch st.Code() {
+		case codes.Unavailable, codes.ResourceExhausted, codes.DeadlineExceeded:
+			return true
+		}
+	}
+	return false
+}
+
+// Backoff returns the wait before attempt+1, where attempt is the number of
+// attempts made so far (starting at 1).
+func (p Policy) Backoff(attempt int) time.Duration {
+	mult := p.Multiplier
+	if mult < 1 {
+		mult = 2
+	}
+	d := float64(p.InitialBackoff) * math.Pow(mult, float64(attempt-1))
+	if p.MaxBackoff > 0 && d > float64(p.MaxBackoff) {
+		d = float64(p.MaxBackoff)
+	}
+	if p.Jitter > 0 {
+		d += d * p.Jitter * (rand.Float64()*2 - 1)
+	}
+	if d < 0 {
+		d = 0
+	}
+	return time.Duration(d)
+}
+
+// Do calls fn until it succeeds, returns a permanent error, the policy's
+// attempts are used up or ctx is done.  op names the operation in log output.
+// The error of the last attempt is returned unchanged so IsRetryable still
+// applies to it.
+func Do(ctx context.Context, p Policy, op string, fn func() error) error {
+	maxAttempts := p.MaxAttempts
+	if maxAttempts < 1 {
+		maxAttempts = 1
+	}
+
+	var err error
+	for attempt := 1; ; attempt++ {
+		err = fn()
+		if err == nil || !IsRetryable(err) {
+			return err
+		}
+		if attempt >= maxAttempts {
+			if maxAttempts > 1 {
+				slog.Warn("Retry attempts exhausted", "op", op, "attempts", attempt, "error", err)
+			}
+			return err
+		}
+
+		wait := p.Backoff(attempt)
+		var statusErr *StatusError
+		if errors.As(err, &statusErr) && statusErr.RetryAfter > wait {
+			wait = statusErr.RetryAfter
+		}
+		slog.Warn("Transient error, retrying", "op", op, "attempt", attempt, "max_attempts", maxAttempts, "backoff", wait, "error", err)
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return fmt.Errorf("%s: %w (last error: %v)", op, ctx.Err(), err)
+		case <-timer.C:
+		}
+	}
+}
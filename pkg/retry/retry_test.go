@@ -0,0 +1,116 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"syscall"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestIsRetryable(t *testing.T) {
+	cases := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"nil", nil, false},
+		{"plain error", errors.New("boom"), false},
+		{"429", &StatusError{StatusCode: http.StatusTooManyRequests, Err: errors.New("slow down")}, true},
+		{"503 wrapped", fmt.Errorf("create bill: %w", &StatusError{StatusCode: 503, Err: errors.New("unavailable")}), true},
+		{"400", &StatusError{StatusCode: http.StatusBadRequest, Err: errors.New("invalid")}, false},
+		{"404", &StatusError{StatusCode: http.StatusNotFound, Err: errors.New("missing")}, false},
+		{"deadline", context.DeadlineExceeded, true},
+		{"canceled", context.Canceled, false},
+		{"conn refused", fmt.Errorf("dial: %w", syscall.ECONNREFUSED), true},
+		{"grpc unavailable", status.Error(codes.Unavailable, "down"), true},
+		{"grpc exhausted", status.Error(codes.ResourceExhausted, "quota"), true},
+		{"grpc invalid", status.Error(codes.InvalidArgument, "bad doc"), false},
+	}
+	for _, tc := range cases {
+		if got := IsRetryable(tc.err); got != tc.want {
+			t.Errorf("%s: IsRetryable() = %v, want %v", tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestBackoff_ExponentialAndCapped(t *testing.T) {
+	p := Policy{InitialBackoff: 100 * time.Millisecond, MaxBackoff: time.Second, Multiplier: 2}
+	want := []time.Duration{100 * time.Millisecond, 200 * time.Millisecond, 400 * time.Millisecond, 800 * time.Millisecond, time.Second, time.Second}
+	for i, w := range want {
+		if got := p.Backoff(i + 1); got != w {
+			t.Errorf("Backoff(%d) = %v, want %v", i+1, got, w)
+		}
+	}
+}
+
+func TestBackoff_JitterWithinBounds(t *testing.T) {
+	p := Policy{InitialBackoff: time.Second, Multiplier: 2, Jitter: 0.5}
+	for i := 0; i < 100; i++ {
+		got := p.Backoff(1)
+		if got < 500*time.Millisecond || got > 1500*time.Millisecond {
+			t.Fatalf("Backoff with jitter out of range: %v", got)
+		}
+	}
+}
+
+func TestDo_RetriesTransientThenSucceeds(t *testing.T) {
+	calls := 0
+	err := Do(context.Background(), Policy{MaxAttempts: 3}, "test", func() error {
+		calls++
+		if calls < 3 {
+			return &StatusError{StatusCode: 502, Err: errors.New("bad gateway")}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("expected success, got %v", err)
+	}
+	if calls != 3 {
+		t.Errorf("expected 3 calls, got %d", calls)
+	}
+}
+
+func TestDo_StopsOnPermanentError(t *testing.T) {
+	calls := 0
+	err := Do(context.Background(), Policy{MaxAttempts: 5}, "test", func() error {
+		calls++
+		return &StatusError{StatusCode: 422, Err: errors.New("validation failed")}
+	})
+	if err == nil {
+		t.Fatal("expected error")
+	}
+	if calls != 1 {
+		t.Errorf("expected 1 call for permanent error, got %d", calls)
+	}
+}
+
+func TestDo_ExhaustedReturnsLastRetryableError(t *testing.T) {
+	calls := 0
+	err := Do(context.Background(), Policy{MaxAttempts: 2}, "test", func() error {
+		calls++
+		return &StatusError{StatusCode: 500, Err: errors.New("internal")}
+	})
+	if calls != 2 {
+		t.Errorf("expected 2 calls, got %d", calls)
+	}
+	if !IsRetryable(err) {
+		t.Errorf("expected exhausted error to remain retryable, got %v", err)
+	}
+}
+
+func TestDo_ZeroPolicySingleAttempt(t *testing.T) {
+	calls := 0
+	Do(context.Background(), Policy{}, "test", func() error {
+		calls++
+		return &StatusError{StatusCode: 503, Err: errors.New("unavailable")}
+	})
+	if calls != 1 {
+		t.Errorf("expected a single attempt with zero policy, got %d", calls)
+	}
+}
@@ -4,18 +4,23 @@ import (
 	"bytes"
 	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log/slog"
 	"math/big"
 	"os"
 	"reflect"
+	"sort"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"paperless-document-processor/config"
 	"paperless-document-processor/pkg/accounting"
 	"paperless-document-processor/pkg/excel"
 	"paperless-document-processor/pkg/libreoffice"
+	"paperless-document-processor/pkg/money"
 
 	"github.com/duckdb/duckdb-go/v2"
 	_ "github.com/duckdb/duckdb-go/v2"
@@ -24,6 +29,10 @@ import (
 
 type DB struct {
 	Conn *sql.DB
+
+	// jobsMu serialises job claims so that two workers never pick up the
+	// same queued job.
+	jobsMu sync.Mutex
 }
 
 type ProcessedDocument struct {
@@ -31,7 +40,7 @@ type ProcessedDocument struct {
 	Filename      string
 	Supplier      string
 	Date          string
-	TotalAmount   float64
+	TotalAmount   money.Money
 	RawOCRData    string // JSON string
 	ExtractedText string
 	CreatedAt     time.Time
@@ -56,16 +65,48 @@ func InitDB(filepath string) (*DB, error) {
 		slog.Warn("Failed to install/load excel extension", "error", err)
 	}
 
-	if err := createTables(db); err != nil {
+	native, err := createTables(db)
+	if err != nil {
 		slog.Error("Failed to create tables", "error", err)
 		return nil, fmt.Errorf("failed to create tables: %w", err)
 	}
 
+	if err := createJobsTable(db, native); err != nil {
+		slog.Error("Failed to create jobs table", "error", err)
+		return nil, fmt.Errorf("failed to create jobs table: %w", err)
+	}
+
+	if err := createAccountingRecordsTable(db); err != nil {
+		slog.Error("Failed to create accounting records table", "error", err)
+		return nil, err
+	}
+
+	if err := createPollStateTable(db); err != nil {
+		slog.Error("Failed to create poll state table", "error", err)
+		return nil, err
+	}
+
+	if err := createLineItemsTable(db); err != nil {
+		slog.Error("Failed to create line items table", "error", err)
+		return nil, err
+	}
+
+	if err := createPaymentTermsTable(db); err != nil {
+		slog.Error("Failed to create payment terms table", "error", err)
+		return nil, err
+	}
+
+	if native {
+		migrateAmountColumns(db)
+	}
+
 	slog.Info("Database initialized successfully")
 	return &DB{Conn: db}, nil
 }
 
-func createTables(db *sql.DB) error {
+// createTables creates the processed_documents table and reports whether the
+// database is a native DuckDB file (true) or a SQLite file opened by DuckDB.
+func createTables(db *sql.DB) (bool, error) {
 	// 1. Try to create the sequence (Native DuckDB path)
 	_, err := db.Exec("CREATE SEQUENCE IF NOT EXISTS seq_processed_documents_id;")
 
@@ -80,7 +121,7 @@ func createTables(db *sql.DB) error {
 			filename TEXT,
 			supplier TEXT,
 			date TEXT,
-			total_amount REAL,
+			total_amount DECIMAL(18,2),
 			raw_ocr_data TEXT,
 			extracted_text TEXT,
 			created_at DATETIME DEFAULT CURRENT_TIMESTAMP
@@ -95,24 +136,50 @@ func createTables(db *sql.DB) error {
 			filename TEXT,
 			supplier TEXT,
 			date TEXT,
-			total_amount REAL,
+			total_amount DECIMAL(18,2),
 			raw_ocr_data TEXT,
 			extracted_text TEXT,
 			created_at DATETIME DEFAULT CURRENT_TIMESTAMP
 		);`
 	} else {
 		// Some other error
-		return fmt.Errorf("failed to initialize sequence: %w", err)
+		return false, fmt.Errorf("failed to initialize sequence: %w", err)
 	}
+	native := err == nil
 
 	_, err = db.Exec(query)
 	if err != nil {
-		return fmt.Errorf("failed to create processed_documents table: %w", err)
+		return native, fmt.Errorf("failed to create processed_documents table: %w", err)
 	}
 
 	// Create index
 	_, err = db.Exec(`CREATE INDEX IF NOT EXISTS idx_paperless_id ON processed_documents(paperless_id);`)
-	return err
+	return native, err
+}
+
+// amountColumns are the money columns that older databases created as REAL
+// or DOUBLE.
+var amountColumns = []struct{ table, column string }{
+	{"processed_documents", "total_amount"},
+	{"bill_line_items", "unit_price"},
+	{"bill_line_items", "amount"},
+}
+
+// migrateAmountColumns converts amount columns to DECIMAL(18,2) so they
+// hold paise exactly.  Values already stored keep their (rounded) amount.
+// A failure only means amounts keep being stored as floats.
+func migrateAmountColumns(db *sql.DB) {
+	for _, c := range amountColumns {
+		var dataType string
+		err := db.QueryRow(`SELECT data_type FROM information_schema.columns WHERE table_name = ? AND column_name = ?;`, c.table, c.column).Scan(&dataType)
+		if err != nil || strings.HasPrefix(dataType, "DECIMAL") {
+			continue
+		}
+		query := fmt.Sprintf("ALTER TABLE %s ALTER COLUMN %s SET DATA TYPE DECIMAL(18,2);", c.table, c.column)
+		if _, err := db.Exec(query); err != nil {
+			slog.Warn("Failed to convert amount column to DECIMAL", "table", c.table, "column", c.column, "error", err)
+		}
+	}
 }
 
 func (d *DB) SaveDocument(doc *ProcessedDocument) error {
@@ -191,6 +258,36 @@ func (d *DB) ProcessPlatformExcel(docID int, filePath string, platform string, o
 	return nil
 }
 
+// ProcessPlatformCSV reads a CSV file with DuckDB's read_csv into the
+// platform's import tables.  Sheet, range and footer settings do not apply
+// to CSV files.
+func (d *DB) ProcessPlatformCSV(docID int, filePath string, platform string, options config.PlatformConfig) error {
+	slog.Info("Storing CSV file via DuckDB into platform table", "platform", platform, "path", filePath)
+	safePath := strings.ReplaceAll(filePath, "'", "''")
+
+	for _, importConfig := range options.ImportConfigs {
+		source := fmt.Sprintf("read_csv('%s')", safePath)
+		if opts := importConfig.ToCSVOptionString(); opts != "" {
+			source = fmt.Sprintf("read_csv('%s', %s)", safePath, strings.TrimSuffix(opts, ","))
+		}
+		tableName := importConfig.GetTableName(platform)
+
+		createStmt := fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s AS SELECT %d AS document_id, * FROM %s LIMIT 0;`, tableName, docID, source)
+		slog.Debug("Executing create table statement", "query", createStmt)
+		if _, err := d.Conn.Exec(createStmt); err != nil {
+			return fmt.Errorf("failed to create platform table: %w", err)
+		}
+
+		insertStmt := fmt.Sprintf(`INSERT INTO %s BY NAME SELECT %d AS document_id, * FROM %s;`, tableName, docID, source)
+		slog.Debug("Executing insert statement", "query", insertStmt)
+		if _, err := d.Conn.Exec(insertStmt); err != nil {
+			return fmt.Errorf("failed to insert csv data: %w", err)
+		}
+		slog.Info("Successfully stored CSV data into", "table", tableName)
+	}
+	return nil
+}
+
 func (d *DB) GetRangeEnd(docID int, platform string, option config.ImportConfig) (excel.Range, error) {
 	rangeStart := option.Range
 	rangeStartObj, err := excel.NewRange(rangeStart)
@@ -234,8 +331,37 @@ func (d *DB) GetRangeEnd(docID int, platform string, option config.ImportConfig)
 	return excel.Range{}, nil
 }
 
+var moneyType = reflect.TypeOf(money.Money{})
+
+// moneyDecodeHook converts the DuckDB value of an export expression into a
+// money.Money field.  DOUBLE results are rounded to the nearest paisa from
+// their shortest decimal form, DECIMAL and text results are parsed exactly.
+func moneyDecodeHook(from reflect.Type, to reflect.Type, data interface{}) (interface{}, error) {
+	if to != moneyType || data == nil {
+		return data, nil
+	}
+	var m money.Money
+	v := reflect.ValueOf(data)
+	switch v.Kind() { //nolint:exhaustive
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return money.New(v.Int()*100, ""), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return money.New(int64(v.Uint())*100, ""), nil
+	case reflect.Float32:
+		// Via its decimal text, so float32(0.1) is 0.10 and not 0.1000000015.
+		return money.Parse(strconv.FormatFloat(v.Float(), 'f', -1, 32))
+	case reflect.Float64:
+		return money.FromFloat(v.Float(), ""), nil
+	}
+	if err := m.Scan(data); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
 // bigNumericDecodeHook converts *big.Int and *big.Float returned by the DuckDB
-// driver into the plain Go numeric type expected by the mapstructure target field.
+// driver into the plain Go integer type expected by the mapstructure target
+// field.  Money fields are handled by moneyDecodeHook.
 func bigNumericDecodeHook(from reflect.Type, to reflect.Type, data interface{}) (interface{}, error) {
 	switch v := data.(type) {
 	case *big.Int:
@@ -243,9 +369,6 @@ func bigNumericDecodeHook(from reflect.Type, to reflect.Type, data interface{})
 			return data, nil
 		}
 		switch to.Kind() { //nolint:exhaustive
-		case reflect.Float32, reflect.Float64:
-			f, _ := new(big.Float).SetInt(v).Float64()
-			return f, nil
 		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
 			return v.Int64(), nil
 		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
@@ -256,9 +379,6 @@ func bigNumericDecodeHook(from reflect.Type, to reflect.Type, data interface{})
 			return data, nil
 		}
 		switch to.Kind() { //nolint:exhaustive
-		case reflect.Float32, reflect.Float64:
-			f, _ := v.Float64()
-			return f, nil
 		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
 			i, _ := v.Int64()
 			return i, nil
@@ -270,6 +390,30 @@ func bigNumericDecodeHook(from reflect.Type, to reflect.Type, data interface{})
 	return data, nil
 }
 
+// timeDecodeHook formats the DATE and TIMESTAMP values DuckDB returns for
+// date columns (e.g. MIN(order_date) over read_csv output) as YYYY-MM-DD
+// for string fields such as PeriodStart.
+func timeDecodeHook(from reflect.Type, to reflect.Type, data interface{}) (interface{}, error) {
+	if t, ok := data.(time.Time); ok && to.Kind() == reflect.String {
+		return t.Format("2006-01-02"), nil
+	}
+	return data, nil
+}
+
+// newPayoutDecoder decodes the columns of an export query into result.
+func newPayoutDecoder(result *accounting.PayoutInput) (*mapstructure.Decoder, error) {
+	return mapstructure.NewDecoder(&mapstructure.DecoderConfig{
+		Result:           result,
+		WeaklyTypedInput: true,
+		DecodeHook: mapstructure.ComposeDecodeHookFunc(
+			moneyDecodeHook,
+			bigNumericDecodeHook,
+			timeDecodeHook,
+			mapstructure.StringToBasicTypeHookFunc(),
+		),
+	})
+}
+
 // GetPlatformExcelRows retrieves the previously stored Excel rows from the platform table.
 func (d *DB) GetPlatformExcelRows(docID int, platform string, options config.PlatformConfig) (accounting.PayoutInput, error) {
 	var payoutInput accounting.PayoutInput
@@ -285,28 +429,70 @@ func (d *DB) GetPlatformExcelRows(docID int, platform string, options config.Pla
 		if rows.Err() != nil {
 			return accounting.PayoutInput{}, fmt.Errorf("failed to query platform table: %w", rows.Err())
 		}
-		rows.Scan(&jsonMap)
-		slog.Debug("Retrieved platform table", "rows", jsonMap.Get())
-		dc := &mapstructure.DecoderConfig{
-			Result:           &payoutInput,
-			WeaklyTypedInput: true,
-			DecodeHook: mapstructure.ComposeDecodeHookFunc(
-				bigNumericDecodeHook,
-				mapstructure.StringToBasicTypeHookFunc(),
-			),
+		if err := rows.Scan(&jsonMap); err != nil && !errors.Is(err, sql.ErrNoRows) {
+			return accounting.PayoutInput{}, fmt.Errorf("failed to read export of %s: %w", tableName, err)
 		}
-		decoder, err := mapstructure.NewDecoder(dc)
+		slog.Debug("Retrieved platform table", "rows", jsonMap.Get())
+		decoder, err := newPayoutDecoder(&payoutInput)
 		if err != nil {
 			return accounting.PayoutInput{}, fmt.Errorf("failed to create mapstructure decoder: %w", err)
 		}
 		if err := decoder.Decode(jsonMap.Get()); err != nil {
-			slog.Warn("GetPlatformExcelRows: partial decode error (some fields may be zero)", "table", tableName, "err", err)
+			return accounting.PayoutInput{}, fmt.Errorf("failed to decode export of %s: %w", tableName, err)
 		}
 	}
 	slog.Debug("Constructed payout input", "rows", payoutInput)
 	return payoutInput, nil
 }
 
+// QueryPlatformValue evaluates a single expression over a platform table's
+// rows for docID and returns it as text.  The expression is usually an
+// aggregate; otherwise the first row's value is used.  NULL yields "".
+func (d *DB) QueryPlatformValue(docID int, tableName, expression string) (string, error) {
+	query := fmt.Sprintf("SELECT CAST((%s) AS VARCHAR) FROM %s WHERE document_id = ? LIMIT 1", expression, tableName)
+	slog.Debug("Executing platform value query", "query", query, "docID", docID)
+	var value sql.NullString
+	if err := d.Conn.QueryRow(query, docID).Scan(&value); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return "", nil
+		}
+		return "", fmt.Errorf("failed to query %s: %w", tableName, err)
+	}
+	return value.String, nil
+}
+
+// EvalExpression evaluates a SQL expression against a single row whose
+// columns are the given variables, returning the result as text.  NULL
+// yields "".
+func (d *DB) EvalExpression(vars map[string]interface{}, expression string) (string, error) {
+	names := make([]string, 0, len(vars))
+	for name := range vars {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	cols := make([]string, len(names))
+	args := make([]interface{}, len(names))
+	for i, name := range names {
+		cols[i] = fmt.Sprintf("? AS %q", name)
+		args[i] = vars[name]
+		// Amounts are exact decimals, so sums and differences of them are
+		// too.
+		if m, ok := vars[name].(money.Money); ok {
+			cols[i] = fmt.Sprintf("CAST(? AS DECIMAL(18,2)) AS %q", name)
+			args[i] = m.String()
+		}
+	}
+	query := fmt.Sprintf("SELECT CAST((%s) AS VARCHAR) FROM (SELECT %s)", expression, strings.Join(cols, ", "))
+	slog.Debug("Evaluating expression", "query", query)
+
+	var value sql.NullString
+	if err := d.Conn.QueryRow(query, args...).Scan(&value); err != nil {
+		return "", fmt.Errorf("failed to evaluate %q: %w", expression, err)
+	}
+	return value.String, nil
+}
+
 // marshalOrderedRows encodes rows to JSON with object keys written in the order
 // given by headers.  This ensures that read_json_auto creates DuckDB table
 // columns in the same sequence as the original xlsx spreadsheet, enabling
@@ -0,0 +1,73 @@
+package storage
+
+import (
+	"math/big"
+	"testing"
+	"time"
+
+	"paperless-document-processor/pkg/accounting"
+	"paperless-document-processor/pkg/money"
+)
+
+func TestDecodePayoutAmounts(t *testing.T) {
+	row := map[string]interface{}{
+		"GrossSalesAmt":         3400.4999999999995, // DOUBLE sum
+		"RestaurantDiscountAmt": float32(0.1),
+		"PlatformCommissionAmt": "1,23,456.78", // DECIMAL or text
+		"TaxesTcsTdsAmt":        big.NewInt(12),
+		"MarketingAdsAmt":       int32(-5),
+		"FinalPayoutAmt":        big.NewFloat(0.285),
+		"TotalOrders":           big.NewInt(42),
+	}
+	var p accounting.PayoutInput
+	dec, err := newPayoutDecoder(&p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := dec.Decode(row); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+
+	amounts := map[string]money.Money{
+		"GrossSalesAmt":         p.GrossSalesAmt,
+		"RestaurantDiscountAmt": p.RestaurantDiscountAmt,
+		"PlatformCommissionAmt": p.PlatformCommissionAmt,
+		"TaxesTcsTdsAmt":        p.TaxesTcsTdsAmt,
+		"MarketingAdsAmt":       p.MarketingAdsAmt,
+		"FinalPayoutAmt":        p.FinalPayoutAmt,
+	}
+	for field, minor := range map[string]int64{
+		"GrossSalesAmt":         340050,
+		"RestaurantDiscountAmt": 10,
+		"PlatformCommissionAmt": 12345678,
+		"TaxesTcsTdsAmt":        1200,
+		"MarketingAdsAmt":       -500,
+		"FinalPayoutAmt":        29,
+	} {
+		if got := amounts[field].Minor; got != minor {
+			t.Errorf("%s = %d paise, want %d", field, got, minor)
+		}
+	}
+	if p.TotalOrders != 42 {
+		t.Errorf("TotalOrders = %d, want 42", p.TotalOrders)
+	}
+}
+
+func TestDecodePayoutDates(t *testing.T) {
+	row := map[string]interface{}{
+		"PeriodStart":    time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC),   // DATE
+		"PeriodEnd":      time.Date(2025, 3, 7, 23, 30, 0, 0, time.UTC), // TIMESTAMP
+		"SettlementDate": "12/03/2025",
+	}
+	var p accounting.PayoutInput
+	dec, err := newPayoutDecoder(&p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := dec.Decode(row); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if p.PeriodStart != "2025-03-01" || p.PeriodEnd != "2025-03-07" || p.SettlementDate != "12/03/2025" {
+		t.Errorf("dates = %q, %q, %q", p.PeriodStart, p.PeriodEnd, p.SettlementDate)
+	}
+}
@@ -0,0 +1,339 @@
+package storage
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+)
+
+// JobKind identifies the pipeline a job is dispatched to.
+type JobKind string
+
+const (
+	JobKindBill          JobKind = "bill"
+	JobKindPayout        JobKind = "payout"
+	JobKindBankStatement JobKind = "bank_statement"
+)
+
+// JobState is the lifecycle state of a queued job.
+type JobState string
+
+const (
+	JobStateQueued    JobState = "queued"
+	JobStateRunning   JobState = "running"
+	JobStateSucceeded JobState = "succeeded"
+	JobStateFailed    JobState = "failed"
+	// JobStateDeadLetter marks a job whose last error was transient but whose
+	// retry attempts ran out; it is safe to resubmit once the dependency
+	// recovers.
+	JobStateDeadLetter JobState = "dead_letter"
+)
+
+// JobStep names the pipeline stage a running job is currently in.  The last
+// step is kept after the job finishes so failures show where they happened.
+type JobStep string
+
+const (
+	JobStepDownload        JobStep = "download"
+	JobStepDocAI           JobStep = "docai"
+	JobStepImport          JobStep = "import"
+	JobStepChecks          JobStep = "checks"
+	JobStepDBSave          JobStep = "db_save"
+	JobStepAccounting      JobStep = "accounting"
+	JobStepPaperlessUpdate JobStep = "paperless_update"
+)
+
+// Job is a single durable processing request.  Payload holds the JSON-encoded
+// webhook request so the worker can rebuild it after a restart.
+type Job struct {
+	ID          int64      `json:"id"`
+	Kind        JobKind    `json:"kind"`
+	PaperlessID int        `json:"paperless_id"`
+	Payload     string     `json:"-"`
+	State       JobState   `json:"state"`
+	Step        JobStep    `json:"step,omitempty"`
+	Attempts    int        `json:"attempts"`
+	Error       string     `json:"error,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+	StartedAt   *time.Time `json:"started_at,omitempty"`
+	FinishedAt  *time.Time `json:"finished_at,omitempty"`
+}
+
+// JobFilter narrows ListJobs.  Zero values match everything; Limit defaults
+// to 100.
+type JobFilter struct {
+	Kind        JobKind
+	State       JobState
+	PaperlessID int
+	Limit       int
+}
+
+const jobColumns = `id, kind, paperless_id, payload, state, step, attempts, error, created_at, updated_at, started_at, finished_at`
+
+func createJobsTable(db *sql.DB, native bool) error {
+	idColumn := "id INTEGER PRIMARY KEY AUTOINCREMENT"
+	if native {
+		if _, err := db.Exec("CREATE SEQUENCE IF NOT EXISTS seq_jobs_id;"); err != nil {
+			return fmt.Errorf("failed to create jobs sequence: %w", err)
+		}
+		idColumn = "id INTEGER PRIMARY KEY DEFAULT nextval('seq_jobs_id')"
+	}
+
+	query := fmt.Sprintf(`
+	CREATE TABLE IF NOT EXISTS jobs (
+		%s,
+		kind TEXT NOT NULL,
+		paperless_id INTEGER NOT NULL,
+		payload TEXT,
+		state TEXT NOT NULL,
+		step TEXT,
+		attempts INTEGER NOT NULL DEFAULT 0,
+		error TEXT,
+		created_at TIMESTAMP NOT NULL,
+		updated_at TIMESTAMP NOT NULL,
+		started_at TIMESTAMP,
+		finished_at TIMESTAMP
+	);`, idColumn)
+	if _, err := db.Exec(query); err != nil {
+		return fmt.Errorf("failed to create jobs table: %w", err)
+	}
+	// Tables created before jobs reported their step lack the column.
+	if _, err := db.Exec(`ALTER TABLE jobs ADD COLUMN IF NOT EXISTS step TEXT;`); err != nil {
+		return fmt.Errorf("failed to add jobs step column: %w", err)
+	}
+
+	if _, err := db.Exec(`CREATE INDEX IF NOT EXISTS idx_jobs_state ON jobs(state);`); err != nil {
+		return fmt.Errorf("failed to create jobs state index: %w", err)
+	}
+	_, err := db.Exec(`CREATE INDEX IF NOT EXISTS idx_jobs_paperless_id ON jobs(paperless_id);`)
+	return err
+}
+
+// EnqueueJob inserts a new job in the queued state and returns its ID.
+func (d *DB) EnqueueJob(kind JobKind, paperlessID int, payload string) (int64, error) {
+	slog.Debug("Enqueueing job", "kind", kind, "paperless_id", paperlessID)
+	now := time.Now().UTC()
+	query := `
+	INSERT INTO jobs (kind, paperless_id, payload, state, step, attempts, error, created_at, updated_at)
+	VALUES (?, ?, ?, ?, '', 0, '', ?, ?)
+	RETURNING id
+	`
+	var id int64
+	if err := d.Conn.QueryRow(query, string(kind), paperlessID, payload, string(JobStateQueued), now, now).Scan(&id); err != nil {
+		return 0, fmt.Errorf("failed to enqueue job: %w", err)
+	}
+	return id, nil
+}
+
+// Errors returned by EnqueueReprocessJob when the document cannot be queued.
+var (
+	ErrJobActive         = errors.New("document has a queued or running job")
+	ErrDocumentProcessed = errors.New("document already processed")
+)
+
+// EnqueueReprocessJob queues a job that processes a document again.  It
+// fails with ErrJobActive while the document has a queued or running job and,
+// unless purge is set, with ErrDocumentProcessed once the document has been
+// processed.  With purge the document's stored rows are deleted first; see
+// purgeDocument.  The checks, purge and insert run in one transaction under
+// the claim lock, so two concurrent requests cannot both queue the document.
+func (d *DB) EnqueueReprocessJob(kind JobKind, paperlessID int, payload string, purge bool, tables []string) (int64, error) {
+	d.jobsMu.Lock()
+	defer d.jobsMu.Unlock()
+
+	tx, err := d.Conn.Begin()
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin reprocess: %w", err)
+	}
+	defer tx.Rollback()
+
+	var active, processed int
+	query := `SELECT COUNT(1) FROM jobs WHERE paperless_id = ? AND state IN (?, ?);`
+	if err := tx.QueryRow(query, paperlessID, string(JobStateQueued), string(JobStateRunning)).Scan(&active); err != nil {
+		return 0, fmt.Errorf("failed to check active jobs: %w", err)
+	}
+	if active > 0 {
+		return 0, ErrJobActive
+	}
+	if err := tx.QueryRow(`SELECT COUNT(1) FROM processed_documents WHERE paperless_id = ?;`, paperlessID).Scan(&processed); err != nil {
+		return 0, fmt.Errorf("failed to check document: %w", err)
+	}
+	if processed > 0 && !purge {
+		return 0, ErrDocumentProcessed
+	}
+
+	if purge {
+		slog.Info("Purging stored rows for document", "paperless_id", paperlessID, "tables", tables)
+		if err := purgeDocument(tx, paperlessID, tables); err != nil {
+			return 0, err
+		}
+	}
+
+	slog.Debug("Enqueueing job", "kind", kind, "paperless_id", paperlessID)
+	now := time.Now().UTC()
+	insert := `
+	INSERT INTO jobs (kind, paperless_id, payload, state, step, attempts, error, created_at, updated_at)
+	VALUES (?, ?, ?, ?, '', 0, '', ?, ?)
+	RETURNING id
+	`
+	var id int64
+	if err := tx.QueryRow(insert, string(kind), paperlessID, payload, string(JobStateQueued), now, now).Scan(&id); err != nil {
+		return 0, fmt.Errorf("failed to enqueue job: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit reprocess: %w", err)
+	}
+	return id, nil
+}
+
+// ClaimNextJob moves the oldest queued job to the running state and returns
+// it.  It returns nil, nil when the queue is empty.
+func (d *DB) ClaimNextJob() (*Job, error) {
+	d.jobsMu.Lock()
+	defer d.jobsMu.Unlock()
+
+	query := fmt.Sprintf(`SELECT %s FROM jobs WHERE state = ? ORDER BY id LIMIT 1;`, jobColumns)
+	job, err := scanJob(d.Conn.QueryRow(query, string(JobStateQueued)))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to select queued job: %w", err)
+	}
+
+	now := time.Now().UTC()
+	update := `UPDATE jobs SET state = ?, step = '', attempts = attempts + 1, started_at = ?, updated_at = ? WHERE id = ?;`
+	if _, err := d.Conn.Exec(update, string(JobStateRunning), now, now, job.ID); err != nil {
+		return nil, fmt.Errorf("failed to claim job %d: %w", job.ID, err)
+	}
+
+	job.State = JobStateRunning
+	job.Step = ""
+	job.Attempts++
+	job.StartedAt = &now
+	job.UpdatedAt = now
+	return job, nil
+}
+
+// SetJobStep records the pipeline stage a running job has entered.
+func (d *DB) SetJobStep(id int64, step JobStep) error {
+	query := `UPDATE jobs SET step = ?, updated_at = ? WHERE id = ?;`
+	if _, err := d.Conn.Exec(query, string(step), time.Now().UTC(), id); err != nil {
+		return fmt.Errorf("failed to set step for job %d: %w", id, err)
+	}
+	return nil
+}
+
+// FinishJob records the terminal state of a job.  errText is stored as-is and
+// should be empty for successful jobs.
+func (d *DB) FinishJob(id int64, state JobState, errText string) error {
+	now := time.Now().UTC()
+	query := `UPDATE jobs SET state = ?, error = ?, finished_at = ?, updated_at = ? WHERE id = ?;`
+	if _, err := d.Conn.Exec(query, string(state), errText, now, now, id); err != nil {
+		return fmt.Errorf("failed to finish job %d: %w", id, err)
+	}
+	return nil
+}
+
+// RequeueInterruptedJobs returns jobs left in the running state by a previous
+// process (crash or restart) to the queue so they are picked up again.
+func (d *DB) RequeueInterruptedJobs() (int64, error) {
+	query := `UPDATE jobs SET state = ?, updated_at = ? WHERE state = ?;`
+	res, err := d.Conn.Exec(query, string(JobStateQueued), time.Now().UTC(), string(JobStateRunning))
+	if err != nil {
+		return 0, fmt.Errorf("failed to requeue interrupted jobs: %w", err)
+	}
+	return res.RowsAffected()
+}
+
+// GetJob returns the job with the given ID, or nil if it does not exist.
+func (d *DB) GetJob(id int64) (*Job, error) {
+	query := fmt.Sprintf(`SELECT %s FROM jobs WHERE id = ?;`, jobColumns)
+	job, err := scanJob(d.Conn.QueryRow(query, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get job %d: %w", id, err)
+	}
+	return job, nil
+}
+
+// ListJobs returns jobs matching filter, newest first.
+func (d *DB) ListJobs(filter JobFilter) ([]Job, error) {
+	var (
+		conds []string
+		args  []interface{}
+	)
+	if filter.Kind != "" {
+		conds = append(conds, "kind = ?")
+		args = append(args, string(filter.Kind))
+	}
+	if filter.State != "" {
+		conds = append(conds, "state = ?")
+		args = append(args, string(filter.State))
+	}
+	if filter.PaperlessID != 0 {
+		conds = append(conds, "paperless_id = ?")
+		args = append(args, filter.PaperlessID)
+	}
+	limit := filter.Limit
+	if limit <= 0 {
+		limit = 100
+	}
+
+	query := fmt.Sprintf(`SELECT %s FROM jobs`, jobColumns)
+	if len(conds) > 0 {
+		query += " WHERE " + strings.Join(conds, " AND ")
+	}
+	query += fmt.Sprintf(" ORDER BY id DESC LIMIT %d;", limit)
+
+	slog.Debug("Listing jobs", "query", query, "args", args)
+	rows, err := d.Conn.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list jobs: %w", err)
+	}
+	defer rows.Close()
+
+	jobs := []Job{}
+	for rows.Next() {
+		job, err := scanJob(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan job: %w", err)
+		}
+		jobs = append(jobs, *job)
+	}
+	return jobs, rows.Err()
+}
+
+func scanJob(row interface{ Scan(...any) error }) (*Job, error) {
+	var (
+		job        Job
+		kind       string
+		state      string
+		payload    sql.NullString
+		step       sql.NullString
+		errText    sql.NullString
+		startedAt  sql.NullTime
+		finishedAt sql.NullTime
+	)
+	if err := row.Scan(&job.ID, &kind, &job.PaperlessID, &payload, &state, &step, &job.Attempts, &errText, &job.CreatedAt, &job.UpdatedAt, &startedAt, &finishedAt); err != nil {
+		return nil, err
+	}
+	job.Kind = JobKind(kind)
+	job.State = JobState(state)
+	job.Payload = payload.String
+	job.Step = JobStep(step.String)
+	job.Error = errText.String
+	if startedAt.Valid {
+		job.StartedAt = &startedAt.Time
+	}
+	if finishedAt.Valid {
+		job.FinishedAt = &finishedAt.Time
+	}
+	return &job, nil
+}
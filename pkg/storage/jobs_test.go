@@ -0,0 +1,289 @@
+package storage
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"sync"
+	"testing"
+)
+
+// testDBs opens a fresh database for each schema InitDB supports: a native
+// in-memory DuckDB and a SQLite file opened by DuckDB.  A backend that cannot
+// be opened here (no DuckDB library, no sqlite extension) is skipped.
+func testDBs(t *testing.T) map[string]*DB {
+	t.Helper()
+	dbs := make(map[string]*DB)
+
+	if db, err := InitDB(""); err != nil {
+		t.Logf("skipping native DuckDB: %v", err)
+	} else {
+		dbs["duckdb"] = db
+	}
+
+	path := filepath.Join(t.TempDir(), "jobs.sqlite")
+	if err := createSQLiteFile(path); err != nil {
+		t.Logf("skipping SQLite fallback: %v", err)
+	} else if db, err := InitDB(path); err != nil {
+		t.Logf("skipping SQLite fallback: %v", err)
+	} else {
+		dbs["sqlite"] = db
+	}
+
+	if len(dbs) == 0 {
+		t.Skip("no database backend available")
+	}
+	t.Cleanup(func() {
+		for _, db := range dbs {
+			db.Close()
+		}
+	})
+	return dbs
+}
+
+// createSQLiteFile creates an empty SQLite database through DuckDB's sqlite
+// extension.
+func createSQLiteFile(path string) error {
+	conn, err := sql.Open("duckdb", "")
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	_, err = conn.Exec(fmt.Sprintf(`INSTALL sqlite; LOAD sqlite; ATTACH '%s' AS scratch (TYPE sqlite); DETACH scratch;`, path))
+	return err
+}
+
+func TestEnqueueAndClaimJobs(t *testing.T) {
+	for name, db := range testDBs(t) {
+		first, err := db.EnqueueJob(JobKindBill, 11, `{"doc_url":"a"}`)
+		if err != nil {
+			t.Fatalf("%s: enqueue: %v", name, err)
+		}
+		second, err := db.EnqueueJob(JobKindPayout, 12, `{"doc_url":"b"}`)
+		if err != nil {
+			t.Fatalf("%s: enqueue: %v", name, err)
+		}
+
+		for _, want := range []struct {
+			id          int64
+			kind        JobKind
+			paperlessID int
+			payload     string
+		}{
+			{first, JobKindBill, 11, `{"doc_url":"a"}`},
+			{second, JobKindPayout, 12, `{"doc_url":"b"}`},
+		} {
+			job, err := db.ClaimNextJob()
+			if err != nil || job == nil {
+				t.Fatalf("%s: ClaimNextJob = %v, %v", name, job, err)
+			}
+			if job.ID != want.id || job.Kind != want.kind || job.PaperlessID != want.paperlessID || job.Payload != want.payload {
+				t.Errorf("%s: claimed %+v, want job %d", name, job, want.id)
+			}
+			if job.State != JobStateRunning || job.Attempts != 1 || job.StartedAt == nil {
+				t.Errorf("%s: claimed job %d is %s with %d attempts", name, job.ID, job.State, job.Attempts)
+			}
+			stored, err := db.GetJob(job.ID)
+			if err != nil || stored == nil || stored.State != JobStateRunning || stored.Attempts != 1 {
+				t.Errorf("%s: stored job %d = %+v, %v", name, job.ID, stored, err)
+			}
+		}
+
+		if job, err := db.ClaimNextJob(); err != nil || job != nil {
+			t.Errorf("%s: ClaimNextJob on an empty queue = %+v, %v; want nil", name, job, err)
+		}
+	}
+}
+
+func TestClaimNextJobNoDoubleClaim(t *testing.T) {
+	const jobs, workers = 20, 6
+	for name, db := range testDBs(t) {
+		want := make([]int64, 0, jobs)
+		for i := 0; i < jobs; i++ {
+			id, err := db.EnqueueJob(JobKindBill, 100+i, "{}")
+			if err != nil {
+				t.Fatalf("%s: enqueue: %v", name, err)
+			}
+			want = append(want, id)
+		}
+
+		var (
+			mu      sync.Mutex
+			claimed []int64
+			wg      sync.WaitGroup
+		)
+		for w := 0; w < workers; w++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for {
+					job, err := db.ClaimNextJob()
+					if err != nil {
+						t.Errorf("%s: ClaimNextJob: %v", name, err)
+						return
+					}
+					if job == nil {
+						return
+					}
+					mu.Lock()
+					claimed = append(claimed, job.ID)
+					mu.Unlock()
+				}
+			}()
+		}
+		wg.Wait()
+
+		sort.Slice(claimed, func(i, j int) bool { return claimed[i] < claimed[j] })
+		if fmt.Sprint(claimed) != fmt.Sprint(want) {
+			t.Errorf("%s: claimed %v, want each of %v exactly once", name, claimed, want)
+		}
+	}
+}
+
+func TestRequeueInterruptedJobs(t *testing.T) {
+	for name, db := range testDBs(t) {
+		running, _ := db.EnqueueJob(JobKindBill, 1, "{}")
+		finished, _ := db.EnqueueJob(JobKindBill, 2, "{}")
+		queued, _ := db.EnqueueJob(JobKindBill, 3, "{}")
+		for range 2 {
+			if _, err := db.ClaimNextJob(); err != nil {
+				t.Fatalf("%s: claim: %v", name, err)
+			}
+		}
+		if err := db.FinishJob(finished, JobStateSucceeded, ""); err != nil {
+			t.Fatalf("%s: finish: %v", name, err)
+		}
+
+		n, err := db.RequeueInterruptedJobs()
+		if err != nil || n != 1 {
+			t.Fatalf("%s: RequeueInterruptedJobs = %d, %v; want 1", name, n, err)
+		}
+		for id, state := range map[int64]JobState{running: JobStateQueued, finished: JobStateSucceeded, queued: JobStateQueued} {
+			if job, err := db.GetJob(id); err != nil || job.State != state {
+				t.Errorf("%s: job %d = %+v, %v; want %s", name, id, job, err, state)
+			}
+		}
+
+		// The interrupted job is picked up again before later ones.
+		job, err := db.ClaimNextJob()
+		if err != nil || job == nil || job.ID != running || job.Attempts != 2 {
+			t.Errorf("%s: reclaimed %+v, %v; want job %d on its second attempt", name, job, err, running)
+		}
+	}
+}
+
+func TestFinishJob(t *testing.T) {
+	cases := []struct {
+		state   JobState
+		errText string
+	}{
+		{JobStateSucceeded, ""},
+		{JobStateFailed, "no valid amount"},
+		{JobStateDeadLetter, "status 503"},
+	}
+	for name, db := range testDBs(t) {
+		for i, tc := range cases {
+			id, err := db.EnqueueJob(JobKindBankStatement, 50+i, "{}")
+			if err != nil {
+				t.Fatalf("%s: enqueue: %v", name, err)
+			}
+			if _, err := db.ClaimNextJob(); err != nil {
+				t.Fatalf("%s: claim: %v", name, err)
+			}
+			if err := db.SetJobStep(id, JobStepAccounting); err != nil {
+				t.Fatalf("%s: set step: %v", name, err)
+			}
+			if err := db.FinishJob(id, tc.state, tc.errText); err != nil {
+				t.Fatalf("%s: finish: %v", name, err)
+			}
+
+			job, err := db.GetJob(id)
+			if err != nil || job == nil {
+				t.Fatalf("%s: GetJob(%d) = %v, %v", name, id, job, err)
+			}
+			if job.State != tc.state || job.Error != tc.errText || job.FinishedAt == nil {
+				t.Errorf("%s: finished job = %+v, want %s with error %q", name, job, tc.state, tc.errText)
+			}
+			// The last step is kept so failures show where they happened.
+			if job.Step != JobStepAccounting {
+				t.Errorf("%s: step = %q, want %q", name, job.Step, JobStepAccounting)
+			}
+		}
+
+		dead, err := db.ListJobs(JobFilter{State: JobStateDeadLetter})
+		if err != nil || len(dead) != 1 || dead[0].PaperlessID != 52 {
+			t.Errorf("%s: dead-lettered jobs = %+v, %v", name, dead, err)
+		}
+	}
+}
+
+func TestEnqueueReprocessJob(t *testing.T) {
+	for name, db := range testDBs(t) {
+		first, err := db.EnqueueJob(JobKindBill, 7, "{}")
+		if err != nil {
+			t.Fatalf("%s: enqueue: %v", name, err)
+		}
+		if _, err := db.EnqueueReprocessJob(JobKindBill, 7, "{}", true, nil); !errors.Is(err, ErrJobActive) {
+			t.Errorf("%s: reprocess with a queued job: %v, want ErrJobActive", name, err)
+		}
+		if _, err := db.ClaimNextJob(); err != nil {
+			t.Fatalf("%s: claim: %v", name, err)
+		}
+		if _, err := db.EnqueueReprocessJob(JobKindBill, 7, "{}", true, nil); !errors.Is(err, ErrJobActive) {
+			t.Errorf("%s: reprocess with a running job: %v, want ErrJobActive", name, err)
+		}
+		if err := db.FinishJob(first, JobStateSucceeded, ""); err != nil {
+			t.Fatalf("%s: finish: %v", name, err)
+		}
+		if err := db.SaveDocument(&ProcessedDocument{PaperlessID: 7, Filename: "bill.pdf"}); err != nil {
+			t.Fatalf("%s: save document: %v", name, err)
+		}
+
+		if _, err := db.EnqueueReprocessJob(JobKindBill, 7, "{}", false, nil); !errors.Is(err, ErrDocumentProcessed) {
+			t.Errorf("%s: reprocess without purge: %v, want ErrDocumentProcessed", name, err)
+		}
+		id, err := db.EnqueueReprocessJob(JobKindBill, 7, `{"doc_url":"a"}`, true, nil)
+		if err != nil {
+			t.Fatalf("%s: reprocess with purge: %v", name, err)
+		}
+		if processed, err := db.IsDocumentProcessed(7); err != nil || processed {
+			t.Errorf("%s: document still processed after purge: %v, %v", name, processed, err)
+		}
+		if job, err := db.GetJob(id); err != nil || job == nil || job.State != JobStateQueued || job.Payload != `{"doc_url":"a"}` {
+			t.Errorf("%s: reprocess job = %+v, %v", name, job, err)
+		}
+	}
+}
+
+func TestEnqueueReprocessJobConcurrent(t *testing.T) {
+	const requests = 8
+	for name, db := range testDBs(t) {
+		var (
+			wg     sync.WaitGroup
+			mu     sync.Mutex
+			queued int
+		)
+		for range requests {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				_, err := db.EnqueueReprocessJob(JobKindPayout, 9, "{}", true, nil)
+				if err != nil && !errors.Is(err, ErrJobActive) {
+					t.Errorf("%s: reprocess: %v", name, err)
+					return
+				}
+				if err == nil {
+					mu.Lock()
+					queued++
+					mu.Unlock()
+				}
+			}()
+		}
+		wg.Wait()
+		if queued != 1 {
+			t.Errorf("%s: %d of %d concurrent reprocess requests queued a job, want 1", name, queued, requests)
+		}
+	}
+}
@@ -0,0 +1,68 @@
+package storage
+
+import (
+	"database/sql"
+	"fmt"
+	"log/slog"
+
+	"paperless-document-processor/pkg/money"
+)
+
+// LineItem is one line of a bill as extracted from the invoice.  Zero
+// means the invoice did not show the value.
+type LineItem struct {
+	LineNo      int
+	Description string
+	ProductCode string
+	Quantity    float64
+	Unit        string
+	UnitPrice   money.Money
+	Amount      money.Money
+}
+
+func createLineItemsTable(db *sql.DB) error {
+	query := `
+	CREATE TABLE IF NOT EXISTS bill_line_items (
+		paperless_id INTEGER NOT NULL,
+		line_no INTEGER NOT NULL,
+		description TEXT,
+		product_code TEXT,
+		quantity DOUBLE,
+		unit TEXT,
+		unit_price DECIMAL(18,2),
+		amount DECIMAL(18,2),
+		PRIMARY KEY (paperless_id, line_no)
+	);`
+	if _, err := db.Exec(query); err != nil {
+		return fmt.Errorf("failed to create bill_line_items table: %w", err)
+	}
+	return nil
+}
+
+// SaveLineItems replaces the line items stored for a Paperless document.
+func (d *DB) SaveLineItems(paperlessID int, items []LineItem) error {
+	slog.Debug("Saving bill line items", "paperless_id", paperlessID, "count", len(items))
+	tx, err := d.Conn.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin line item save: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`DELETE FROM bill_line_items WHERE paperless_id = ?;`, paperlessID); err != nil {
+		return fmt.Errorf("failed to clear line items: %w", err)
+	}
+	query := `
+	INSERT INTO bill_line_items (paperless_id, line_no, description, product_code, quantity, unit, unit_price, amount)
+	VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+	`
+	for _, item := range items {
+		if _, err := tx.Exec(query, paperlessID, item.LineNo, item.Description, item.ProductCode, item.Quantity, item.Unit, item.UnitPrice, item.Amount); err != nil {
+			return fmt.Errorf("failed to insert line item %d: %w", item.LineNo, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit line items: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,50 @@
+package storage
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+)
+
+func createPollStateTable(db *sql.DB) error {
+	query := `
+	CREATE TABLE IF NOT EXISTS poll_state (
+		kind TEXT PRIMARY KEY,
+		high_water TIMESTAMP NOT NULL,
+		updated_at TIMESTAMP NOT NULL
+	);`
+	if _, err := db.Exec(query); err != nil {
+		return fmt.Errorf("failed to create poll_state table: %w", err)
+	}
+	return nil
+}
+
+// GetPollMark returns the "modified" time of the newest document the poller
+// has seen for kind, and false if it has never polled that kind.
+func (d *DB) GetPollMark(kind JobKind) (time.Time, bool, error) {
+	var mark time.Time
+	err := d.Conn.QueryRow(`SELECT high_water FROM poll_state WHERE kind = ?;`, string(kind)).Scan(&mark)
+	if errors.Is(err, sql.ErrNoRows) {
+		return time.Time{}, false, nil
+	}
+	if err != nil {
+		return time.Time{}, false, fmt.Errorf("failed to get poll mark: %w", err)
+	}
+	return mark.UTC(), true, nil
+}
+
+// SetPollMark stores the poller's high-water mark for kind.
+func (d *DB) SetPollMark(kind JobKind, mark time.Time) error {
+	slog.Debug("Saving poll mark", "kind", kind, "high_water", mark)
+	query := `
+	INSERT INTO poll_state (kind, high_water, updated_at)
+	VALUES (?, ?, ?)
+	ON CONFLICT (kind) DO UPDATE SET high_water = excluded.high_water, updated_at = excluded.updated_at
+	`
+	if _, err := d.Conn.Exec(query, string(kind), mark.UTC(), time.Now().UTC()); err != nil {
+		return fmt.Errorf("failed to save poll mark: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,94 @@
+package storage
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+)
+
+// Accounting record kinds, one per downstream object a pipeline creates.
+const (
+	AccountingRecordBill   = "bill"
+	AccountingRecordPayout = "payout"
+)
+
+func createAccountingRecordsTable(db *sql.DB) error {
+	query := `
+	CREATE TABLE IF NOT EXISTS accounting_records (
+		paperless_id INTEGER NOT NULL,
+		kind TEXT NOT NULL,
+		accounting_id INTEGER NOT NULL,
+		created_at TIMESTAMP NOT NULL,
+		updated_at TIMESTAMP NOT NULL,
+		PRIMARY KEY (paperless_id, kind)
+	);`
+	if _, err := db.Exec(query); err != nil {
+		return fmt.Errorf("failed to create accounting_records table: %w", err)
+	}
+	return nil
+}
+
+// SaveAccountingRecord remembers which accounting object was created for a
+// Paperless document so that a later reprocess can update it in place.
+func (d *DB) SaveAccountingRecord(paperlessID int, kind string, accountingID int) error {
+	slog.Debug("Saving accounting record", "paperless_id", paperlessID, "kind", kind, "accounting_id", accountingID)
+	now := time.Now().UTC()
+	query := `
+	INSERT INTO accounting_records (paperless_id, kind, accounting_id, created_at, updated_at)
+	VALUES (?, ?, ?, ?, ?)
+	ON CONFLICT (paperless_id, kind) DO UPDATE SET accounting_id = excluded.accounting_id, updated_at = excluded.updated_at
+	`
+	if _, err := d.Conn.Exec(query, paperlessID, kind, accountingID, now, now); err != nil {
+		return fmt.Errorf("failed to save accounting record: %w", err)
+	}
+	return nil
+}
+
+// GetAccountingRecord returns the accounting object ID previously created for
+// a document, and false if there is none.
+func (d *DB) GetAccountingRecord(paperlessID int, kind string) (int, bool, error) {
+	query := `SELECT accounting_id FROM accounting_records WHERE paperless_id = ? AND kind = ?;`
+	var id int
+	err := d.Conn.QueryRow(query, paperlessID, kind).Scan(&id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, false, nil
+	}
+	if err != nil {
+		return 0, false, fmt.Errorf("failed to get accounting record: %w", err)
+	}
+	return id, true, nil
+}
+
+// purgeDocument deletes everything stored locally for a document so it can
+// be processed again: its processed_documents and bill_line_items rows and
+// its rows in each of the given platform tables.  Tables that do not exist
+// yet are skipped.  Accounting records are kept so a reprocess can update
+// rather than duplicate.
+func purgeDocument(tx *sql.Tx, paperlessID int, tables []string) error {
+	if _, err := tx.Exec(`DELETE FROM processed_documents WHERE paperless_id = ?;`, paperlessID); err != nil {
+		return fmt.Errorf("failed to purge processed_documents: %w", err)
+	}
+	if _, err := tx.Exec(`DELETE FROM bill_line_items WHERE paperless_id = ?;`, paperlessID); err != nil {
+		return fmt.Errorf("failed to purge bill_line_items: %w", err)
+	}
+
+	for _, table := range tables {
+		var count int
+		if err := tx.QueryRow(`SELECT COUNT(1) FROM information_schema.tables WHERE lower(table_name) = ?;`, strings.ToLower(table)).Scan(&count); err != nil {
+			return fmt.Errorf("failed to check table %s: %w", table, err)
+		}
+		if count == 0 {
+			continue
+		}
+		res, err := tx.Exec(fmt.Sprintf(`DELETE FROM %s WHERE document_id = ?;`, table), paperlessID)
+		if err != nil {
+			return fmt.Errorf("failed to purge %s: %w", table, err)
+		}
+		n, _ := res.RowsAffected()
+		slog.Debug("Purged platform table rows", "table", table, "paperless_id", paperlessID, "rows", n)
+	}
+	return nil
+}
@@ -0,0 +1,97 @@
+package storage
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+)
+
+// PaymentTerms are the payment terms agreed with a vendor, used for the due
+// date of its bills when the invoice does not print one.
+type PaymentTerms struct {
+	// Vendor is the vendor name, lower-cased with whitespace collapsed.
+	Vendor    string    `json:"vendor"`
+	Terms     string    `json:"terms"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+func createPaymentTermsTable(db *sql.DB) error {
+	query := `
+	CREATE TABLE IF NOT EXISTS vendor_payment_terms (
+		vendor TEXT PRIMARY KEY,
+		terms TEXT NOT NULL,
+		updated_at TIMESTAMP NOT NULL
+	);`
+	if _, err := db.Exec(query); err != nil {
+		return fmt.Errorf("failed to create vendor_payment_terms table: %w", err)
+	}
+	return nil
+}
+
+// VendorKey is the form vendor names are stored and looked up in, so
+// "ACME  Traders" and "Acme Traders" share their terms.
+func VendorKey(vendor string) string {
+	return strings.ToLower(strings.Join(strings.Fields(vendor), " "))
+}
+
+// SetPaymentTerms stores a vendor's payment terms, replacing earlier ones.
+func (d *DB) SetPaymentTerms(vendor, terms string) (PaymentTerms, error) {
+	pt := PaymentTerms{Vendor: VendorKey(vendor), Terms: terms, UpdatedAt: time.Now().UTC()}
+	slog.Debug("Saving payment terms", "vendor", pt.Vendor, "terms", terms)
+	query := `
+	INSERT INTO vendor_payment_terms (vendor, terms, updated_at)
+	VALUES (?, ?, ?)
+	ON CONFLICT (vendor) DO UPDATE SET terms = excluded.terms, updated_at = excluded.updated_at
+	`
+	if _, err := d.Conn.Exec(query, pt.Vendor, pt.Terms, pt.UpdatedAt); err != nil {
+		return PaymentTerms{}, fmt.Errorf("failed to save payment terms: %w", err)
+	}
+	return pt, nil
+}
+
+// GetPaymentTerms returns a vendor's payment terms, and false if none are
+// stored.
+func (d *DB) GetPaymentTerms(vendor string) (string, bool, error) {
+	var terms string
+	err := d.Conn.QueryRow(`SELECT terms FROM vendor_payment_terms WHERE vendor = ?;`, VendorKey(vendor)).Scan(&terms)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", false, nil
+	}
+	if err != nil {
+		return "", false, fmt.Errorf("failed to get payment terms: %w", err)
+	}
+	return terms, true, nil
+}
+
+// ListPaymentTerms returns the terms of every vendor, by vendor name.
+func (d *DB) ListPaymentTerms() ([]PaymentTerms, error) {
+	rows, err := d.Conn.Query(`SELECT vendor, terms, updated_at FROM vendor_payment_terms ORDER BY vendor;`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list payment terms: %w", err)
+	}
+	defer rows.Close()
+
+	list := []PaymentTerms{}
+	for rows.Next() {
+		var pt PaymentTerms
+		if err := rows.Scan(&pt.Vendor, &pt.Terms, &pt.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan payment terms: %w", err)
+		}
+		list = append(list, pt)
+	}
+	return list, rows.Err()
+}
+
+// DeletePaymentTerms removes a vendor's payment terms and reports whether
+// there were any.
+func (d *DB) DeletePaymentTerms(vendor string) (bool, error) {
+	res, err := d.Conn.Exec(`DELETE FROM vendor_payment_terms WHERE vendor = ?;`, VendorKey(vendor))
+	if err != nil {
+		return false, fmt.Errorf("failed to delete payment terms: %w", err)
+	}
+	n, _ := res.RowsAffected()
+	return n > 0, nil
+}
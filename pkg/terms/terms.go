@@ -0,0 +1,73 @@
+// Package terms reads a vendor's payment terms ("net 30", "end of month",
+// "on receipt") and computes the due date of a bill from them.
+package terms
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Terms are payment terms in canonical form: "net_<days>", "end_of_month"
+// or "on_receipt".
+type Terms string
+
+const (
+	OnReceipt  Terms = "on_receipt"
+	EndOfMonth Terms = "end_of_month"
+)
+
+// Net returns net terms of the given number of days, e.g. Net(30) is
+// "net_30".
+func Net(days int) Terms {
+	return Terms(fmt.Sprintf("net_%d", days))
+}
+
+// maxNetDays bounds net terms; anything longer is a typo.
+const maxNetDays = 365
+
+var netRe = regexp.MustCompile(`^(?:net\s*(\d{1,3})|(\d{1,3})\s*days?(?:\s*net)?)$`)
+
+// Parse reads payment terms as written on invoices or entered by hand:
+// "net 30", "NET30", "net_15", "30 days", "eom", "end of month", "on
+// receipt", "due on receipt" and "immediate".
+func Parse(s string) (Terms, error) {
+	v := strings.ToLower(strings.TrimSpace(s))
+	v = strings.NewReplacer("_", " ", "-", " ").Replace(v)
+	v = strings.Join(strings.Fields(v), " ")
+	switch v {
+	case "on receipt", "due on receipt", "upon receipt", "immediate", "immediately", "cod", "net 0", "0 days":
+		return OnReceipt, nil
+	case "eom", "end of month", "net eom", "due end of month":
+		return EndOfMonth, nil
+	}
+	m := netRe.FindStringSubmatch(v)
+	if m == nil {
+		return "", fmt.Errorf("unrecognised payment terms %q", s)
+	}
+	days, _ := strconv.Atoi(m[1] + m[2])
+	if days > maxNetDays {
+		return "", fmt.Errorf("payment terms %q exceed %d days", s, maxNetDays)
+	}
+	if days == 0 {
+		return OnReceipt, nil
+	}
+	return Net(days), nil
+}
+
+// Due returns the due date of a bill issued on the given date.
+func (t Terms) Due(issued time.Time) time.Time {
+	switch t {
+	case OnReceipt:
+		return issued
+	case EndOfMonth:
+		// Day 0 of the next month is the last day of this one.
+		return time.Date(issued.Year(), issued.Month()+1, 0, 0, 0, 0, 0, issued.Location())
+	}
+	if days, err := strconv.Atoi(strings.TrimPrefix(string(t), "net_")); err == nil {
+		return issued.AddDate(0, 0, days)
+	}
+	return issued
+}
@@ -0,0 +1,65 @@
+package terms
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		in   string
+		want Terms
+	}{
+		{"net 30", Net(30)},
+		{"NET15", Net(15)},
+		{"net_7", Net(7)},
+		{"Net-45", Net(45)},
+		{"30 days", Net(30)},
+		{"eom", EndOfMonth},
+		{"End of Month", EndOfMonth},
+		{"end_of_month", EndOfMonth},
+		{"on_receipt", OnReceipt},
+		{"Due on receipt", OnReceipt},
+		{"net 0", OnReceipt},
+	}
+	for _, tt := range tests {
+		got, err := Parse(tt.in)
+		if err != nil {
+			t.Errorf("Parse(%q): %v", tt.in, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("Parse(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+
+	for _, in := range []string{"", "soon", "net", "net 1000", "2025-12-03"} {
+		if got, err := Parse(in); err == nil {
+			t.Errorf("Parse(%q) = %q, want error", in, got)
+		}
+	}
+}
+
+func TestDue(t *testing.T) {
+	issued := time.Date(2025, 1, 20, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		terms Terms
+		want  string
+	}{
+		{Net(7), "2025-01-27"},
+		{Net(15), "2025-02-04"},
+		{Net(30), "2025-02-19"},
+		{EndOfMonth, "2025-01-31"},
+		{OnReceipt, "2025-01-20"},
+	}
+	for _, tt := range tests {
+		if got := tt.terms.Due(issued).Format("2006-01-02"); got != tt.want {
+			t.Errorf("%s.Due(2025-01-20) = %s, want %s", tt.terms, got, tt.want)
+		}
+	}
+
+	feb := time.Date(2024, 2, 10, 0, 0, 0, 0, time.UTC)
+	if got := EndOfMonth.Due(feb).Format("2006-01-02"); got != "2024-02-29" {
+		t.Errorf("end of month in a leap February = %s", got)
+	}
+}
@@ -0,0 +1,133 @@
+package tika
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+)
+
+// ParseTables extracts the tables of a document as cell text grids, one
+// [][]string per table in document order.  See Tables.
+func (c *Client) ParseTables(content []byte) ([][][]string, error) {
+	xhtml, err := c.Parse(content)
+	if err != nil {
+		return nil, err
+	}
+	return Tables(xhtml)
+}
+
+// columnGap separates the columns of a text line: a tab or two or more
+// spaces, which is how Tika lays out the columns of a PDF table.
+var columnGap = regexp.MustCompile(`\t+|\s{2,}`)
+
+// Tables reads the <table> elements of Tika's XHTML output.  Tika only emits
+// tables for formats that have them (spreadsheets, Word, HTML); a PDF comes
+// back as text, so when there are none every run of two or more consecutive
+// lines that split into several columns is taken as a table instead.
+func Tables(xhtml string) ([][][]string, error) {
+	dec := xml.NewDecoder(strings.NewReader(xhtml))
+	dec.Strict = false
+	dec.AutoClose = xml.HTMLAutoClose
+	dec.Entity = xml.HTMLEntity
+
+	var (
+		tables [][][]string
+		table  [][]string
+		row    []string
+		cell   strings.Builder
+		text   strings.Builder
+		depth  int // nesting of <table>
+		inCell bool
+	)
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse Tika XHTML: %w", err)
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			switch t.Name.Local {
+			case "table":
+				depth++
+				if depth == 1 {
+					table = nil
+				}
+			case "tr":
+				row = nil
+			case "td", "th":
+				inCell = true
+				cell.Reset()
+			case "p", "div", "br":
+				text.WriteByte('\n')
+			}
+		case xml.EndElement:
+			switch t.Name.Local {
+			case "td", "th":
+				if inCell {
+					row = append(row, strings.Join(strings.Fields(cell.String()), " "))
+					inCell = false
+				}
+			case "tr":
+				if hasText(row) {
+					table = append(table, row)
+				}
+				row = nil
+			case "table":
+				depth--
+				if depth == 0 && len(table) > 0 {
+					tables = append(tables, table)
+				}
+			case "p", "div":
+				text.WriteByte('\n')
+			}
+		case xml.CharData:
+			switch {
+			case inCell:
+				cell.Write(t)
+			case depth == 0:
+				text.Write(t)
+			}
+		}
+	}
+	if len(tables) > 0 {
+		return tables, nil
+	}
+	return textTables(text.String()), nil
+}
+
+// textTables finds the column-aligned runs of lines in plain text.
+func textTables(text string) [][][]string {
+	var tables [][][]string
+	var run [][]string
+	flush := func() {
+		if len(run) >= 2 {
+			tables = append(tables, run)
+		}
+		run = nil
+	}
+	for _, line := range strings.Split(text, "\n") {
+		line = strings.TrimSpace(line)
+		cells := columnGap.Split(line, -1)
+		if len(cells) < 2 {
+			flush()
+			continue
+		}
+		run = append(run, cells)
+	}
+	flush()
+	return tables
+}
+
+func hasText(cells []string) bool {
+	for _, c := range cells {
+		if c != "" {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,70 @@
+package tika
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func TestTablesFromXHTML(t *testing.T) {
+	xhtml := `<?xml version="1.0" encoding="UTF-8"?><html xmlns="http://www.w3.org/1999/xhtml"><head><meta name="x" content="y"/></head>
+<body><div class="page"><h1>Sheet1</h1>
+<table><tbody>
+<tr><td>Order ID</td><td>Net&nbsp;Payout</td></tr>
+<tr><td>101</td><td> 1,250.00
+</td></tr>
+<tr><td></td><td></td></tr>
+</tbody></table></div></body></html>`
+
+	got, err := Tables(xhtml)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := [][][]string{{{"Order ID", "Net Payout"}, {"101", "1,250.00"}}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestTablesFromPDFText(t *testing.T) {
+	xhtml := `<html xmlns="http://www.w3.org/1999/xhtml"><body><div class="page">
+<p>Weekly Payout Statement</p>
+<p>Order ID    Date        Amount
+101         01-03-2026  250.00
+102         02-03-2026  99.50</p>
+<p>Thank you</p>
+<p>UTR	AXIS123456</p>
+</div></body></html>`
+
+	got, err := Tables(xhtml)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := [][][]string{{
+		{"Order ID", "Date", "Amount"},
+		{"101", "01-03-2026", "250.00"},
+		{"102", "02-03-2026", "99.50"},
+	}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestParseTables(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPut || r.URL.Path != "/rmeta/xhtml" {
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+		}
+		w.Write([]byte(`[{"X-TIKA:content": "<html><body><table><tr><th>A</th><th>B</th></tr><tr><td>1</td><td>2</td></tr></table></body></html>"}]`))
+	}))
+	defer srv.Close()
+
+	got, err := NewClient(srv.URL).ParseTables([]byte("%PDF"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := [][][]string{{{"A", "B"}, {"1", "2"}}}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}